@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"log"
+	"net/netip"
 	"time"
 
 	"github.com/caarlos0/env/v10"
@@ -31,6 +33,20 @@ type Config struct {
 	JWTAudience          string        `env:"JWT_AUDIENCE" envDefault:"frontend"`
 	NATSAuthVerify       string        `env:"NATS_SUBJECT_AUTH_VERIFY" envDefault:"auth.verifyJWT"`
 
+	AuthTokenVerifier      string        `env:"AUTH_TOKEN_VERIFIER" envDefault:"nats"`
+	JWTJWKSURL             string        `env:"JWT_JWKS_URL"`
+	JWTJWKSRefreshInterval time.Duration `env:"JWT_JWKS_REFRESH_INTERVAL" envDefault:"10m"`
+	JWTClockSkew           time.Duration `env:"JWT_CLOCK_SKEW" envDefault:"30s"`
+
+	AuthCacheSize  int           `env:"AUTH_CACHE_SIZE" envDefault:"10000"`
+	AuthCacheTTL   time.Duration `env:"AUTH_CACHE_TTL" envDefault:"1m"`
+	NATSAuthRevoke string        `env:"NATS_SUBJECT_AUTH_REVOKE" envDefault:"auth.revoke"`
+
+	GatewayHeaderSecret string         `env:"GATEWAY_HEADER_SECRET"`
+	GatewayReplayWindow time.Duration  `env:"GATEWAY_REPLAY_WINDOW" envDefault:"1m"`
+	GatewayTrustedCIDRs []netip.Prefix `env:"GATEWAY_TRUSTED_CIDRS" envSeparator:","`
+	TrustedProxyCIDRs   []netip.Prefix `env:"TRUSTED_PROXY_CIDRS" envSeparator:","`
+
 	GoogleClientID     string `env:"GOOGLE_CLIENT_ID"`
 	GoogleClientSecret string `env:"GOOGLE_CLIENT_SECRET"`
 	GoogleRedirectURL  string `env:"GOOGLE_REDIRECT_URL"`
@@ -49,9 +65,50 @@ type Config struct {
 
 	NATSURL        string `env:"NATS_URL" envDefault:"nats://localhost:4222"`
 	NATSUserCreate string `env:"NATS_SUBJECT_USER_CREATE" envDefault:"user.create-user"`
+	NATSUserEvents string `env:"NATS_SUBJECT_USER_EVENTS" envDefault:"user.events"`
+
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"1s"`
+	OutboxBatchSize    int           `env:"OUTBOX_BATCH_SIZE" envDefault:"100"`
+	OutboxMaxAttempts  int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"20"`
+	OutboxRetryBackoff time.Duration `env:"OUTBOX_RETRY_BACKOFF" envDefault:"1s"`
+	OutboxRetention    time.Duration `env:"OUTBOX_RETENTION" envDefault:"168h"`
+
+	SuspensionSweepInterval time.Duration `env:"SUSPENSION_SWEEP_INTERVAL" envDefault:"1m"`
+	AccountDeletionGrace    time.Duration `env:"ACCOUNT_DELETION_GRACE" envDefault:"720h"`
+	AccountPurgeInterval    time.Duration `env:"ACCOUNT_PURGE_INTERVAL" envDefault:"1h"`
+
+	ExportFileKind     string        `env:"EXPORT_FILE_KIND" envDefault:"USER_EXPORT"`
+	ExportPollInterval time.Duration `env:"EXPORT_POLL_INTERVAL" envDefault:"5s"`
+	ExportURLTTL       time.Duration `env:"EXPORT_URL_TTL" envDefault:"15m"`
+
+	BulkPollInterval time.Duration `env:"BULK_POLL_INTERVAL" envDefault:"2s"`
+	CursorSigningKey string        `env:"CURSOR_SIGNING_KEY"`
+
+	RoleAssignmentPollInterval time.Duration `env:"ROLE_ASSIGNMENT_POLL_INTERVAL" envDefault:"5s"`
+	RoleAssignmentMaxAttempts  int           `env:"ROLE_ASSIGNMENT_MAX_ATTEMPTS" envDefault:"20"`
+	RoleAssignmentRetryBackoff time.Duration `env:"ROLE_ASSIGNMENT_RETRY_BACKOFF" envDefault:"5s"`
 
 	CORSAllowOrigins string `env:"CORS_ALLOW_ORIGINS" envDefault:"*"`
 	RateLimitPerMin  int    `env:"RATE_LIMIT_PER_MIN" envDefault:"120"`
+
+	RateLimitAdminPerMin     int    `env:"RATE_LIMIT_ADMIN_PER_MIN" envDefault:"600"`
+	RateLimitServicePerMin   int    `env:"RATE_LIMIT_SERVICE_PER_MIN" envDefault:"1200"`
+	RateLimitIPPerMin        int    `env:"RATE_LIMIT_IP_PER_MIN" envDefault:"1200"`
+	RateLimitExpensivePerMin int    `env:"RATE_LIMIT_EXPENSIVE_PER_MIN" envDefault:"10"`
+	RateLimitStore           string `env:"RATE_LIMIT_STORE" envDefault:"memory"`
+
+	TracesExporter    string  `env:"OTEL_TRACES_EXPORTER" envDefault:"none"`
+	OTLPEndpoint      string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracesSampleRatio float64 `env:"OTEL_TRACES_SAMPLER_ARG" envDefault:"1"`
+
+	AccessLogSampleSuccess uint32 `env:"ACCESS_LOG_SAMPLE_SUCCESS" envDefault:"1"`
+
+	ReadinessCacheTTL     time.Duration `env:"READINESS_CACHE_TTL" envDefault:"2s"`
+	ReadinessCheckTimeout time.Duration `env:"READINESS_CHECK_TIMEOUT" envDefault:"2s"`
+	ShutdownDrainDelay    time.Duration `env:"SHUTDOWN_DRAIN_DELAY" envDefault:"5s"`
+
+	IdempotencyTTL           time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+	IdempotencySweepInterval time.Duration `env:"IDEMPOTENCY_SWEEP_INTERVAL" envDefault:"1h"`
 }
 
 func Load() (*Config, error) {
@@ -63,6 +120,18 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// Validate checks the settings the HTTP service cannot run without. It is
+// not part of Load, since the migrate and audit-verify commands only need the
+// database settings.
+func (c *Config) Validate() error {
+	if c.CursorSigningKey == "" {
+		// Every replica must sign cursors with the same key, or a cursor
+		// fails as soon as the next page is served by another one.
+		return errors.New("CURSOR_SIGNING_KEY is required")
+	}
+	return nil
+}
+
 func MustLoad() *Config {
 	cfg, err := Load()
 	if err != nil {
@@ -10,6 +10,10 @@ import (
 	"net/http"
 	"path"
 	"time"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/metrics"
+	"github.com/example/user-service/pkg/tracing"
 )
 
 type Client interface {
@@ -47,11 +51,11 @@ type signedURLResponse struct {
 func NewHTTPClient(baseURL string, timeout time.Duration) Client {
 	return &httpClient{
 		baseURL: baseURL,
-		client:  &http.Client{Timeout: timeout},
+		client:  &http.Client{Timeout: timeout, Transport: tracing.Transport("filestorage", nil)},
 	}
 }
 
-func (c *httpClient) Upload(ctx context.Context, req UploadRequest) (*UploadResponse, error) {
+func (c *httpClient) Upload(ctx context.Context, req UploadRequest) (_ *UploadResponse, err error) {
 	if req.OwnerID == "" {
 		return nil, fmt.Errorf("owner_id is required")
 	}
@@ -80,6 +84,7 @@ func (c *httpClient) Upload(ctx context.Context, req UploadRequest) (*UploadResp
 		return nil, err
 	}
 
+	defer observe("upload", time.Now(), &err)
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/files/upload", c.baseURL), body)
 	if err != nil {
 		return nil, err
@@ -109,7 +114,8 @@ func (c *httpClient) Upload(ctx context.Context, req UploadRequest) (*UploadResp
 	return &UploadResponse{ID: resp.ID}, nil
 }
 
-func (c *httpClient) SignedURL(ctx context.Context, id string, expiresMinutes int64) (string, error) {
+func (c *httpClient) SignedURL(ctx context.Context, id string, expiresMinutes int64) (_ string, err error) {
+	defer observe("signed_url", time.Now(), &err)
 	if expiresMinutes <= 0 {
 		expiresMinutes = 15
 	}
@@ -146,3 +152,12 @@ func (c *httpClient) SignedURL(ctx context.Context, id string, expiresMinutes in
 func (c *httpClient) DownloadURL(id string) string {
 	return fmt.Sprintf("%s/files/%s/download", c.baseURL, id)
 }
+
+// observe records the call and marks its failure as the file storage being
+// unavailable.
+func observe(operation string, start time.Time, err *error) {
+	metrics.ObserveClient("filestorage", operation, *err, time.Since(start))
+	if *err != nil {
+		*err = domain.Unavailable("filestorage", *err)
+	}
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/usecase"
+	res "github.com/example/user-service/pkg/http"
+)
+
+// AuditHandler serves the admin audit log.
+type AuditHandler struct {
+	service service.AuditService
+}
+
+func NewAuditHandler(s service.AuditService) *AuditHandler {
+	return &AuditHandler{service: s}
+}
+
+func (h *AuditHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("", h.ListAudit)
+}
+
+// ListAudit returns audit entries newest first, filtered by actor_id,
+// target_user_id, action and a created_from/created_to range (RFC3339).
+// Pagination uses page/per like the user list.
+func (h *AuditHandler) ListAudit(c echo.Context) error {
+	errs := &domain.ValidationError{}
+	page := 1
+	if raw := strings.TrimSpace(c.QueryParam("page")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			errs.Add("page", "invalid page")
+		} else {
+			page = value
+		}
+	}
+	per := parsePerPage(c, errs)
+	query := domain.AuditQuery{
+		ActorID:      strings.TrimSpace(c.QueryParam("actor_id")),
+		TargetUserID: strings.TrimSpace(c.QueryParam("target_user_id")),
+		Action:       domain.AuditAction(strings.ToLower(strings.TrimSpace(c.QueryParam("action")))),
+		Offset:       (page - 1) * per,
+		Limit:        per,
+	}
+	if query.Action != "" && !query.Action.IsValid() {
+		errs.Add("action", "invalid action")
+	}
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{{"created_from", &query.From}, {"created_to", &query.To}} {
+		if raw := strings.TrimSpace(c.QueryParam(bound.name)); raw != "" {
+			value, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				errs.Add(bound.name, "invalid "+bound.name)
+				continue
+			}
+			*bound.target = &value
+		}
+	}
+	if err := errs.OrNil(); err != nil {
+		return err
+	}
+
+	entries, totalCount, err := h.service.List(c.Request().Context(), query)
+	if err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = []domain.AuditEntry{}
+	}
+	return res.JSON(c, http.StatusOK, map[string]interface{}{
+		"totalCount": totalCount,
+		"entries":    entries,
+	})
+}
@@ -1,29 +1,46 @@
 package v1
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 
 	"github.com/example/user-service/internal/adapters/filestorage"
 	"github.com/example/user-service/internal/adapters/http/middleware"
 	"github.com/example/user-service/internal/domain"
 	"github.com/example/user-service/internal/usecase"
+	"github.com/example/user-service/pkg/cursor"
 	res "github.com/example/user-service/pkg/http"
 )
 
 type Handler struct {
-	service service.UserManageService
-	storage filestorage.Client
+	service  service.UserManageService
+	deletion service.AccountDeletionService
+	bulk     service.BulkService
+	imports  service.UserImportService
+	storage  filestorage.Client
+	cursors  *cursor.Signer
 }
 
-func NewHandler(s service.UserManageService, storage filestorage.Client) *Handler {
-	return &Handler{service: s, storage: storage}
+// NewHandler builds the admin handler. A nil cursors signer gets a random
+// key, so cursor tokens are only valid for this process.
+func NewHandler(s service.UserManageService, deletion service.AccountDeletionService, bulk service.BulkService, imports service.UserImportService, storage filestorage.Client, cursors *cursor.Signer) *Handler {
+	if cursors == nil {
+		cursors = cursor.NewRandomSigner()
+	}
+	return &Handler{service: s, deletion: deletion, bulk: bulk, imports: imports, storage: storage, cursors: cursors}
 }
 
 type createManageUserRequest struct {
@@ -48,20 +65,58 @@ type changeRoleRequest struct {
 
 type changeStatusRequest struct {
 	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type suspendRequest struct {
+	Until      *time.Time `json:"until"`
+	Duration   string     `json:"duration"`
+	ReasonCode string     `json:"reason_code"`
+	Note       string     `json:"note"`
+}
+
+type bulkRequest struct {
+	Action  string             `json:"action"`
+	UserIDs []string           `json:"user_ids"`
+	Filter  *bulkFilterRequest `json:"filter"`
+	Status  string             `json:"status"`
+	Reason  string             `json:"reason"`
+	Role    string             `json:"role"`
+	DryRun  bool               `json:"dry_run"`
+}
+
+type bulkFilterRequest struct {
+	Status      []string   `json:"status"`
+	IsActive    *bool      `json:"is_active"`
+	CreatedFrom *time.Time `json:"created_from"`
+	CreatedTo   *time.Time `json:"created_to"`
+	Provider    string     `json:"provider"`
+	Q           string     `json:"q"`
+}
+
+type liftSuspensionRequest struct {
+	Reason string `json:"reason"`
 }
 
 type userResponse struct {
-	ID           string            `json:"id"`
-	Email        string            `json:"email"`
-	Status       domain.UserStatus `json:"status"`
-	IsActive     bool              `json:"is_active"`
-	DisplayName  *string           `json:"display_name,omitempty"`
-	AvatarFileID *string           `json:"avatar_file_id,omitempty"`
-	AvatarURL    *string           `json:"avatar_url,omitempty"`
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
+	ID               string                   `json:"id"`
+	Email            string                   `json:"email"`
+	Status           domain.UserStatus        `json:"status"`
+	IsActive         bool                     `json:"is_active"`
+	DisplayName      *string                  `json:"display_name,omitempty"`
+	AvatarFileID     *string                  `json:"avatar_file_id,omitempty"`
+	AvatarURL        *string                  `json:"avatar_url,omitempty"`
+	SuspendedUntil   *time.Time               `json:"suspended_until,omitempty"`
+	SuspensionReason *domain.SuspensionReason `json:"suspension_reason,omitempty"`
+	DeletedAt        *time.Time               `json:"deleted_at,omitempty"`
+	PurgeAfter       *time.Time               `json:"purge_after,omitempty"`
+	CreatedAt        time.Time                `json:"created_at"`
+	UpdatedAt        time.Time                `json:"updated_at"`
 }
 
+// PermissionExportPII lets an export include unmasked emails.
+const PermissionExportPII = "users.export.pii"
+
 const (
 	defaultPerPage = 50
 	minPerPage     = 10
@@ -72,34 +127,46 @@ func (h *Handler) RegisterRoutes(g *echo.Group) {
 	g.GET("", h.ListUsers)
 	g.GET("/:id", h.GetUser)
 	g.POST("", h.CreateUser)
+	g.POST("/bulk", h.SubmitBulk)
+	g.GET("/bulk/:job_id", h.GetBulk)
+	g.POST("/import", h.ImportUsers)
 	g.PATCH("/:id", h.UpdateUser)
 	g.PATCH("/:id/status", h.ChangeStatus)
+	g.GET("/:id/status-history", h.StatusHistory)
+	g.POST("/:id/suspend", h.Suspend)
+	g.DELETE("/:id/suspend", h.LiftSuspension)
 	g.PATCH("/:id/role", h.ChangeRole)
+	g.DELETE("/:id", h.DeleteUser)
+	g.POST("/:id/restore", h.RestoreUser)
 }
 
+// ListUsers serves two pagination modes. Passing a cursor parameter (empty
+// for the first page) selects keyset pagination; page/per is the legacy
+// offset mode and always returns an exact totalCount.
 func (h *Handler) ListUsers(c echo.Context) error {
+	if c.QueryParams().Has("cursor") {
+		return h.listUsersByCursor(c)
+	}
+	errs := &domain.ValidationError{}
 	page := 1
 	if raw := strings.TrimSpace(c.QueryParam("page")); raw != "" {
 		value, err := strconv.Atoi(raw)
 		if err != nil || value < 1 {
-			return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid page", middleware.RequestIDFromCtx(c), nil)
+			errs.Add("page", "invalid page")
+		} else {
+			page = value
 		}
-		page = value
 	}
-
-	per := defaultPerPage
-	if raw := strings.TrimSpace(c.QueryParam("per")); raw != "" {
-		value, err := strconv.Atoi(raw)
-		if err != nil || value < minPerPage || value > maxPerPage {
-			return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid per", middleware.RequestIDFromCtx(c), nil)
-		}
-		per = value
+	per := parsePerPage(c, errs)
+	query := parseUserQuery(c, errs)
+	if err := errs.OrNil(); err != nil {
+		return err
 	}
-
-	offset := (page - 1) * per
-	users, totalCount, err := h.service.ListUsers(c.Request().Context(), offset, per)
+	query.Offset = (page - 1) * per
+	query.Limit = per
+	users, totalCount, err := h.service.ListUsers(c.Request().Context(), query)
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "list_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
 	items := make([]*userResponse, 0, len(users))
 	for idx := range users {
@@ -111,26 +178,170 @@ func (h *Handler) ListUsers(c echo.Context) error {
 	})
 }
 
+func (h *Handler) listUsersByCursor(c echo.Context) error {
+	errs := &domain.ValidationError{}
+	per := parsePerPage(c, errs)
+	query := parseUserQuery(c, errs)
+	if query.SortBy != domain.UserSortCreatedAt {
+		errs.Add("sort", "cursor pagination only supports sort=created_at")
+	}
+	if token := strings.TrimSpace(c.QueryParam("cursor")); token != "" {
+		position := new(listCursor)
+		if err := h.cursors.Decode(token, position); err != nil || position.Desc != query.SortDesc {
+			errs.Add("cursor", "invalid cursor")
+		} else {
+			query.Cursor = &position.UserCursor
+		}
+	}
+	switch mode := domain.UserCountMode(strings.ToLower(strings.TrimSpace(c.QueryParam("count")))); mode {
+	case domain.UserCountNone, domain.UserCountExact, domain.UserCountEstimated:
+		query.Count = mode
+	default:
+		errs.Add("count", "invalid count")
+	}
+	if err := errs.OrNil(); err != nil {
+		return err
+	}
+	query.Limit = per
+
+	page, err := h.service.ListUsersByCursor(c.Request().Context(), query)
+	if err != nil {
+		return err
+	}
+	items := make([]*userResponse, 0, len(page.Users))
+	for idx := range page.Users {
+		items = append(items, h.newUserResponse(&page.Users[idx]))
+	}
+	body := map[string]interface{}{"users": items}
+	if page.TotalCount != nil {
+		body["totalCount"] = *page.TotalCount
+	}
+	for key, position := range map[string]*domain.UserCursor{"next_cursor": page.Next, "prev_cursor": page.Prev} {
+		if position == nil {
+			continue
+		}
+		token, err := h.cursors.Encode(listCursor{UserCursor: *position, Desc: query.SortDesc})
+		if err != nil {
+			return err
+		}
+		body[key] = token
+	}
+	return res.JSON(c, http.StatusOK, body)
+}
+
+// listCursor is the signed cursor payload. It records the sort direction so
+// a token cannot be replayed against the opposite order.
+type listCursor struct {
+	domain.UserCursor
+	Desc bool `json:"d,omitempty"`
+}
+
+// parsePerPage reads the page size, recording an invalid one in errs.
+func parsePerPage(c echo.Context, errs *domain.ValidationError) int {
+	raw := strings.TrimSpace(c.QueryParam("per"))
+	if raw == "" {
+		return defaultPerPage
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < minPerPage || value > maxPerPage {
+		errs.Add("per", "invalid per")
+		return defaultPerPage
+	}
+	return value
+}
+
+// parseUserQuery reads the list filters and sort order:
+// status (comma-separated), is_active, created_from/created_to (RFC3339),
+// provider, q (email or display name substring), sort and order (asc|desc).
+// Parameters that do not parse and filters rejected by UserQuery.Validate
+// are recorded in errs.
+func parseUserQuery(c echo.Context, errs *domain.ValidationError) domain.UserQuery {
+	query := domain.UserQuery{SortBy: domain.UserSortCreatedAt, SortDesc: true}
+	if raw := strings.TrimSpace(c.QueryParam("status")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			query.Statuses = append(query.Statuses, domain.UserStatus(strings.ToUpper(strings.TrimSpace(part))))
+		}
+	}
+	if raw := strings.TrimSpace(c.QueryParam("is_active")); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs.Add("is_active", "invalid is_active")
+		} else {
+			query.IsActive = &value
+		}
+	}
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{{"created_from", &query.CreatedFrom}, {"created_to", &query.CreatedTo}} {
+		if raw := strings.TrimSpace(c.QueryParam(bound.name)); raw != "" {
+			value, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				errs.Add(bound.name, "invalid "+bound.name)
+				continue
+			}
+			*bound.target = &value
+		}
+	}
+	if raw := strings.TrimSpace(c.QueryParam("provider")); raw != "" {
+		query.Provider = domain.IdentityProvider(strings.ToLower(raw))
+	}
+	query.Search = strings.TrimSpace(c.QueryParam("q"))
+	if raw := strings.TrimSpace(c.QueryParam("sort")); raw != "" {
+		query.SortBy = domain.UserSortField(strings.ToLower(raw))
+		// Explicit sorts default to ascending unless order says otherwise.
+		query.SortDesc = false
+	}
+	switch strings.ToLower(strings.TrimSpace(c.QueryParam("order"))) {
+	case "":
+	case "asc":
+		query.SortDesc = false
+	case "desc":
+		query.SortDesc = true
+	default:
+		errs.Add("order", "invalid order")
+	}
+	// Pagination is filled in by the caller; validate the filters only.
+	filters := query
+	filters.Limit = 1
+	errs.Merge(filters.Validate())
+	return query
+}
+
 func (h *Handler) GetUser(c echo.Context) error {
 	userID := c.Param("id")
 	user, err := h.service.GetUser(c.Request().Context(), userID)
 	if err != nil {
-		status := http.StatusBadRequest
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			status = http.StatusNotFound
-		}
-		return res.ErrorJSON(c, status, "get_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
+	c.Response().Header().Set(middleware.HeaderETag, user.ETag())
 	return res.JSON(c, http.StatusOK, h.newUserResponse(user))
 }
 
 func (h *Handler) CreateUser(c echo.Context) error {
 	req := new(createManageUserRequest)
 	if err := c.Bind(req); err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid payload", middleware.RequestIDFromCtx(c), nil)
+		return middleware.ErrInvalidPayload
+	}
+	return h.createUser(c, req)
+}
+
+// CreateUserForService is CreateUser for service clients, which may not grant
+// the roles that open the admin API.
+func (h *Handler) CreateUserForService(c echo.Context) error {
+	req := new(createManageUserRequest)
+	if err := c.Bind(req); err != nil {
+		return middleware.ErrInvalidPayload
 	}
+	if domain.IsPrivilegedRole(req.Role) {
+		return domain.Forbidden("role_not_allowed", "service clients may not grant the "+strings.ToLower(strings.TrimSpace(req.Role))+" role")
+	}
+	return h.createUser(c, req)
+}
+
+func (h *Handler) createUser(c echo.Context, req *createManageUserRequest) error {
 	status := domain.UserStatus(strings.ToUpper(strings.TrimSpace(req.Status)))
-	user, err := h.service.CreateUser(c.Request().Context(), service.CreateUserRequest{
+	user, err := h.service.CreateUser(auditContext(c), service.CreateUserRequest{
 		Email:        req.Email,
 		Password:     req.Password,
 		DisplayName:  req.DisplayName,
@@ -139,67 +350,479 @@ func (h *Handler) CreateUser(c echo.Context) error {
 		Status:       status,
 	})
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "create_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
 	return res.JSON(c, http.StatusCreated, h.newUserResponse(user))
 }
 
 func (h *Handler) UpdateUser(c echo.Context) error {
+	return h.updateUser(c, h.service.UpdateUser)
+}
+
+// UpdateUserForService is UpdateUser for service clients, which may neither
+// change credentials nor touch users with a privileged role.
+func (h *Handler) UpdateUserForService(c echo.Context) error {
+	return h.updateUser(c, h.service.UpdateUserForService)
+}
+
+func (h *Handler) updateUser(c echo.Context, update func(context.Context, string, service.UpdateUserRequest) (*domain.User, error)) error {
 	req := new(updateManageUserRequest)
 	if err := c.Bind(req); err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid payload", middleware.RequestIDFromCtx(c), nil)
+		return middleware.ErrInvalidPayload
 	}
-	userID := c.Param("id")
-	user, err := h.service.UpdateUser(c.Request().Context(), userID, service.UpdateUserRequest{
+	user, err := update(auditContext(c), c.Param("id"), service.UpdateUserRequest{
 		Email:        req.Email,
 		Password:     req.Password,
 		DisplayName:  req.DisplayName,
 		AvatarFileID: req.AvatarFileID,
+		IfMatch:      c.Request().Header.Get(middleware.HeaderIfMatch),
 	})
 	if err != nil {
-		status := http.StatusBadRequest
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			status = http.StatusNotFound
-		}
-		return res.ErrorJSON(c, status, "update_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
+	c.Response().Header().Set(middleware.HeaderETag, user.ETag())
 	return res.JSON(c, http.StatusOK, h.newUserResponse(user))
 }
 
 func (h *Handler) ChangeStatus(c echo.Context) error {
 	req := new(changeStatusRequest)
 	if err := c.Bind(req); err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid payload", middleware.RequestIDFromCtx(c), nil)
+		return middleware.ErrInvalidPayload
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return domain.Invalid("reason", "reason is required")
 	}
 	userID := c.Param("id")
-	status := domain.UserStatus(strings.ToUpper(strings.TrimSpace(req.Status)))
-	user, err := h.service.ChangeStatus(c.Request().Context(), userID, status)
+	actorID, _ := c.Get("user_id").(string)
+	user, err := h.service.ChangeStatus(auditContext(c), userID, service.ChangeStatusRequest{
+		Status:  domain.UserStatus(strings.ToUpper(strings.TrimSpace(req.Status))),
+		Reason:  req.Reason,
+		ActorID: actorID,
+		IfMatch: c.Request().Header.Get(middleware.HeaderIfMatch),
+	})
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(middleware.HeaderETag, user.ETag())
+	return res.JSON(c, http.StatusOK, h.newUserResponse(user))
+}
+
+func (h *Handler) StatusHistory(c echo.Context) error {
+	userID := c.Param("id")
+	history, err := h.service.StatusHistory(c.Request().Context(), userID)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			statusCode = http.StatusNotFound
+		return err
+	}
+	if history == nil {
+		history = []domain.UserStatusHistory{}
+	}
+	return res.JSON(c, http.StatusOK, map[string]interface{}{"history": history})
+}
+
+func (h *Handler) Suspend(c echo.Context) error {
+	req := new(suspendRequest)
+	if err := c.Bind(req); err != nil {
+		return middleware.ErrInvalidPayload
+	}
+	var until time.Time
+	switch {
+	case req.Until != nil:
+		until = *req.Until
+	case strings.TrimSpace(req.Duration) != "":
+		duration, err := time.ParseDuration(strings.TrimSpace(req.Duration))
+		if err != nil || duration <= 0 {
+			return domain.Invalid("duration", "invalid duration")
 		}
-		return res.ErrorJSON(c, statusCode, "status_change_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		until = time.Now().UTC().Add(duration)
+	default:
+		return domain.Invalid("until", "until or duration is required")
+	}
+	userID := c.Param("id")
+	actorID, _ := c.Get("user_id").(string)
+	user, err := h.service.Suspend(auditContext(c), userID, service.SuspendRequest{
+		Until:   until,
+		Reason:  domain.SuspensionReason(strings.ToUpper(strings.TrimSpace(req.ReasonCode))),
+		Note:    req.Note,
+		ActorID: actorID,
+	})
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusOK, h.newUserResponse(user))
+}
+
+func (h *Handler) LiftSuspension(c echo.Context) error {
+	req := new(liftSuspensionRequest)
+	if err := c.Bind(req); err != nil {
+		return middleware.ErrInvalidPayload
+	}
+	userID := c.Param("id")
+	actorID, _ := c.Get("user_id").(string)
+	user, err := h.service.LiftSuspension(auditContext(c), userID, service.LiftSuspensionRequest{
+		Reason:  req.Reason,
+		ActorID: actorID,
+	})
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusOK, h.newUserResponse(user))
+}
+
+func (h *Handler) DeleteUser(c echo.Context) error {
+	actorID, _ := c.Get("user_id").(string)
+	user, err := h.deletion.Delete(auditContext(c), c.Param("id"), actorID)
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusAccepted, h.newUserResponse(user))
+}
+
+func (h *Handler) RestoreUser(c echo.Context) error {
+	actorID, _ := c.Get("user_id").(string)
+	user, err := h.deletion.Restore(auditContext(c), c.Param("id"), actorID)
+	if err != nil {
+		return err
 	}
 	return res.JSON(c, http.StatusOK, h.newUserResponse(user))
 }
 
+// SubmitBulk queues an action over many users. Dry runs are evaluated
+// synchronously and return 200 with per-item results; real jobs return 202
+// and are polled through GetBulk.
+func (h *Handler) SubmitBulk(c echo.Context) error {
+	req := new(bulkRequest)
+	if err := c.Bind(req); err != nil {
+		return middleware.ErrInvalidPayload
+	}
+	action := domain.BulkAction(strings.ToLower(strings.TrimSpace(req.Action)))
+	if !action.IsValid() {
+		return domain.Invalid("action", "invalid action")
+	}
+	actorID, _ := c.Get("user_id").(string)
+	submit := service.BulkRequest{
+		Action:  action,
+		UserIDs: req.UserIDs,
+		Status:  domain.UserStatus(req.Status),
+		Reason:  req.Reason,
+		Role:    req.Role,
+		DryRun:  req.DryRun,
+		ActorID: actorID,
+	}
+	if req.Filter != nil {
+		filter, err := req.Filter.toQuery()
+		if err != nil {
+			return err
+		}
+		submit.Filter = &filter
+	}
+	job, err := h.bulk.Submit(c.Request().Context(), submit)
+	if err != nil {
+		return err
+	}
+	status := http.StatusAccepted
+	if job.DryRun {
+		status = http.StatusOK
+	}
+	return res.JSON(c, status, job)
+}
+
+func (h *Handler) GetBulk(c echo.Context) error {
+	job, err := h.bulk.Get(c.Request().Context(), c.Param("job_id"))
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusOK, job)
+}
+
+func (f *bulkFilterRequest) toQuery() (domain.UserQuery, error) {
+	query := domain.UserQuery{
+		IsActive:    f.IsActive,
+		CreatedFrom: f.CreatedFrom,
+		CreatedTo:   f.CreatedTo,
+		Provider:    domain.IdentityProvider(strings.ToLower(strings.TrimSpace(f.Provider))),
+		Search:      strings.TrimSpace(f.Q),
+	}
+	for _, raw := range f.Status {
+		query.Statuses = append(query.Statuses, domain.UserStatus(strings.ToUpper(strings.TrimSpace(raw))))
+	}
+	if !query.HasFilters() {
+		return query, domain.Invalid("filter", "filter must not be empty")
+	}
+	// Pagination is filled in by the service; validate the filters only.
+	query.Limit = 1
+	if err := query.Validate(); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// ImportUsers upserts users by email from a CSV or NDJSON upload, sent either
+// as the raw body or as the "file" part of a multipart form. The format comes
+// from the format query parameter, the content type or the file extension.
+// The response is a CSV report with one line per row, streamed as rows are
+// processed; dry_run=true reports what would happen without writing.
+func (h *Handler) ImportUsers(c echo.Context) error {
+	dryRun := false
+	if raw := strings.TrimSpace(c.QueryParam("dry_run")); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return domain.Invalid("dry_run", "invalid dry_run")
+		}
+		dryRun = value
+	}
+	body, contentType, filename, err := importSource(c)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	rows, err := service.NewImportReader(body, importFormat(c.QueryParam("format"), contentType, filename))
+	if err != nil {
+		return err
+	}
+
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	resp.Header().Set(echo.HeaderContentDisposition, `attachment; filename="user-import-report.csv"`)
+	resp.Header().Set("X-Dry-Run", strconv.FormatBool(dryRun))
+	resp.WriteHeader(http.StatusOK)
+	report := csv.NewWriter(resp)
+	_ = report.Write([]string{"line", "email", "result", "user_id", "error"})
+
+	actorID, _ := c.Get("user_id").(string)
+	err = h.imports.Import(auditContext(c), rows, service.ImportOptions{DryRun: dryRun, ActorID: actorID}, func(result domain.ImportResult) error {
+		if err := report.Write([]string{strconv.Itoa(result.Line), result.Email, string(result.Outcome), result.UserID, result.Error}); err != nil {
+			return err
+		}
+		report.Flush()
+		resp.Flush()
+		return report.Error()
+	})
+	if err != nil {
+		// The status line is already sent, so the failure ends the report
+		// with what the client may see; the error handler only logs the
+		// cause because the response is committed.
+		_ = report.Write([]string{"", "", "aborted", "", domain.PublicMessage(err)})
+	}
+	report.Flush()
+	return err
+}
+
+// exportColumns are the columns ExportUsers can emit.
+var exportColumns = map[string]func(user *domain.User) interface{}{
+	"id":                func(user *domain.User) interface{} { return user.ID },
+	"email":             func(user *domain.User) interface{} { return user.Email },
+	"display_name":      func(user *domain.User) interface{} { return exportDisplayName(user) },
+	"status":            func(user *domain.User) interface{} { return user.StatusOrDefault() },
+	"is_active":         func(user *domain.User) interface{} { return user.IsActive },
+	"created_at":        func(user *domain.User) interface{} { return user.CreatedAt },
+	"updated_at":        func(user *domain.User) interface{} { return user.UpdatedAt },
+	"suspended_until":   func(user *domain.User) interface{} { return user.SuspendedUntil },
+	"suspension_reason": func(user *domain.User) interface{} { return user.SuspensionReason },
+}
+
+var defaultExportColumns = []string{"id", "email", "display_name", "status", "is_active", "created_at"}
+
+// ExportUsers streams every user matching the list filters as csv (default)
+// or ndjson. columns picks and orders the fields. Emails are masked unless
+// the caller holds PermissionExportPII; the router registers this route
+// with RBACMiddleware.LoadPermission so the permission is in the context.
+func (h *Handler) ExportUsers(c echo.Context) error {
+	errs := &domain.ValidationError{}
+	format := strings.ToLower(strings.TrimSpace(c.QueryParam("format")))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		errs.Add("format", "invalid format")
+	}
+	columns := defaultExportColumns
+	if raw := strings.TrimSpace(c.QueryParam("columns")); raw != "" {
+		columns = nil
+		for _, part := range strings.Split(raw, ",") {
+			name := strings.ToLower(strings.TrimSpace(part))
+			if _, ok := exportColumns[name]; !ok {
+				errs.Add("columns", fmt.Sprintf("invalid column %q", name))
+				continue
+			}
+			columns = append(columns, name)
+		}
+	}
+	query := parseUserQuery(c, errs)
+	if err := errs.OrNil(); err != nil {
+		return err
+	}
+	pii := middleware.HasPermission(c, PermissionExportPII)
+
+	resp := c.Response()
+	contentType := "text/csv; charset=utf-8"
+	if format == "ndjson" {
+		contentType = "application/x-ndjson"
+	}
+	resp.Header().Set(echo.HeaderContentType, contentType)
+	resp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="users.%s"`, format))
+	resp.WriteHeader(http.StatusOK)
+
+	csvOut := csv.NewWriter(resp)
+	if format == "csv" {
+		if err := csvOut.Write(columns); err != nil {
+			return err
+		}
+	}
+	values := make([]interface{}, len(columns))
+	// Errors after the header is sent end the stream; the error handler
+	// only logs them because the response is already committed.
+	err := h.service.StreamUsers(c.Request().Context(), query, func(user *domain.User) error {
+		for idx, name := range columns {
+			values[idx] = exportColumns[name](user)
+			if name == "email" && !pii {
+				values[idx] = maskEmail(user.Email)
+			}
+		}
+		if format == "csv" {
+			return csvOut.Write(csvValues(values))
+		}
+		line, err := ndjsonLine(columns, values)
+		if err != nil {
+			return err
+		}
+		_, err = resp.Write(line)
+		return err
+	})
+	csvOut.Flush()
+	if err != nil {
+		return err
+	}
+	return csvOut.Error()
+}
+
+func exportDisplayName(user *domain.User) *string {
+	return profileField(user.Profile, func(value *domain.UserProfile) *string { return value.DisplayName })
+}
+
+func csvValues(values []interface{}) []string {
+	out := make([]string, len(values))
+	for idx, value := range values {
+		switch v := value.(type) {
+		case string:
+			out[idx] = v
+		case *string:
+			if v != nil {
+				out[idx] = *v
+			}
+		case bool:
+			out[idx] = strconv.FormatBool(v)
+		case time.Time:
+			out[idx] = v.UTC().Format(time.RFC3339)
+		case *time.Time:
+			if v != nil {
+				out[idx] = v.UTC().Format(time.RFC3339)
+			}
+		case *domain.SuspensionReason:
+			if v != nil {
+				out[idx] = string(*v)
+			}
+		default:
+			out[idx] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
+
+// ndjsonLine encodes one object with keys in column order.
+func ndjsonLine(columns []string, values []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for idx, name := range columns {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		value, err := json.Marshal(values[idx])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(strconv.Quote(name))
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// importSource returns the upload as a stream. Multipart forms are read part
+// by part, so the file is not buffered in memory or spooled to disk.
+func importSource(c echo.Context) (io.ReadCloser, string, string, error) {
+	req := c.Request()
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		return req.Body, mediaType, "", nil
+	}
+	parts, err := req.MultipartReader()
+	if err != nil {
+		return nil, "", "", domain.Invalid("file", "invalid multipart form")
+	}
+	for {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", "", domain.Invalid("file", "file is required")
+		}
+		if err != nil {
+			return nil, "", "", domain.Invalid("file", "invalid multipart form")
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		mediaType, _, _ = mime.ParseMediaType(part.Header.Get(echo.HeaderContentType))
+		return part, mediaType, part.FileName(), nil
+	}
+}
+
+func importFormat(explicit, mediaType, filename string) domain.ImportFormat {
+	if explicit = strings.ToLower(strings.TrimSpace(explicit)); explicit != "" {
+		return domain.ImportFormat(explicit)
+	}
+	switch mediaType {
+	case "text/csv":
+		return domain.ImportFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return domain.ImportFormatNDJSON
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return domain.ImportFormatCSV
+	case ".ndjson", ".jsonl":
+		return domain.ImportFormatNDJSON
+	}
+	return ""
+}
+
 func (h *Handler) ChangeRole(c echo.Context) error {
 	req := new(changeRoleRequest)
 	if err := c.Bind(req); err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid payload", middleware.RequestIDFromCtx(c), nil)
+		return middleware.ErrInvalidPayload
 	}
 	userID := c.Param("id")
-	if err := h.service.ChangeRole(c.Request().Context(), userID, req.Role); err != nil {
-		status := http.StatusBadRequest
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			status = http.StatusNotFound
-		}
-		return res.ErrorJSON(c, status, "role_change_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+	if err := h.service.ChangeRole(auditContext(c), userID, req.Role); err != nil {
+		return err
 	}
 	return res.JSON(c, http.StatusOK, map[string]string{"id": userID, "role": strings.ToUpper(strings.TrimSpace(req.Role))})
 }
 
+// auditContext returns the request context carrying the caller details that
+// the service records in the admin audit log.
+func auditContext(c echo.Context) context.Context {
+	actorID, _ := c.Get("user_id").(string)
+	if clientID, _ := c.Get("client_id").(string); actorID == "" && clientID != "" {
+		actorID = "service:" + clientID
+	}
+	return service.WithAuditActor(c.Request().Context(), domain.AuditActor{
+		ID:        actorID,
+		RequestID: middleware.RequestIDFromCtx(c),
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	})
+}
+
 func (h *Handler) newUserResponse(user *domain.User) *userResponse {
 	if user == nil {
 		return nil
@@ -207,15 +830,19 @@ func (h *Handler) newUserResponse(user *domain.User) *userResponse {
 
 	profile := h.decorateProfile(user.Profile)
 	return &userResponse{
-		ID:           user.ID,
-		Email:        maskEmail(user.Email),
-		Status:       user.StatusOrDefault(),
-		IsActive:     user.IsActive,
-		DisplayName:  profileField(profile, func(value *domain.UserProfile) *string { return value.DisplayName }),
-		AvatarFileID: profileField(profile, func(value *domain.UserProfile) *string { return value.AvatarFileID }),
-		AvatarURL:    profileField(profile, func(value *domain.UserProfile) *string { return value.AvatarURL }),
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
+		ID:               user.ID,
+		Email:            maskEmail(user.Email),
+		Status:           user.StatusOrDefault(),
+		IsActive:         user.IsActive,
+		DisplayName:      profileField(profile, func(value *domain.UserProfile) *string { return value.DisplayName }),
+		AvatarFileID:     profileField(profile, func(value *domain.UserProfile) *string { return value.AvatarFileID }),
+		AvatarURL:        profileField(profile, func(value *domain.UserProfile) *string { return value.AvatarURL }),
+		SuspendedUntil:   user.SuspendedUntil,
+		SuspensionReason: user.SuspensionReason,
+		DeletedAt:        user.DeletedAt,
+		PurgeAfter:       user.PurgeAfter,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
 	}
 }
 
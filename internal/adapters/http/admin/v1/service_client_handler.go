@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/usecase"
+	res "github.com/example/user-service/pkg/http"
+)
+
+// ServiceClientHandler lets admins manage the API keys of internal callers.
+type ServiceClientHandler struct {
+	service service.ServiceClientService
+}
+
+func NewServiceClientHandler(s service.ServiceClientService) *ServiceClientHandler {
+	return &ServiceClientHandler{service: s}
+}
+
+func (h *ServiceClientHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("", h.ListClients)
+	g.POST("", h.IssueClient)
+	g.POST("/:id/rotate", h.RotateClient)
+	g.DELETE("/:id", h.RevokeClient)
+}
+
+type issueClientRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// issuedClientResponse is the only response that contains the plain key.
+type issuedClientResponse struct {
+	Client *domain.ServiceClient `json:"client"`
+	APIKey string                `json:"api_key"`
+}
+
+func (h *ServiceClientHandler) ListClients(c echo.Context) error {
+	clients, err := h.service.List(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	if clients == nil {
+		clients = []domain.ServiceClient{}
+	}
+	return res.JSON(c, http.StatusOK, map[string]interface{}{"clients": clients})
+}
+
+func (h *ServiceClientHandler) IssueClient(c echo.Context) error {
+	req := new(issueClientRequest)
+	if err := c.Bind(req); err != nil {
+		return middleware.ErrInvalidPayload
+	}
+	actorID, _ := c.Get("user_id").(string)
+	client, key, err := h.service.Issue(c.Request().Context(), service.IssueServiceClientRequest{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		ActorID:   actorID,
+	})
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusCreated, issuedClientResponse{Client: client, APIKey: key})
+}
+
+func (h *ServiceClientHandler) RotateClient(c echo.Context) error {
+	client, key, err := h.service.Rotate(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusOK, issuedClientResponse{Client: client, APIKey: key})
+}
+
+func (h *ServiceClientHandler) RevokeClient(c echo.Context) error {
+	client, err := h.service.Revoke(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusOK, client)
+}
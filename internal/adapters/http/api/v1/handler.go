@@ -19,14 +19,16 @@ import (
 
 type Handler struct {
 	users        service.UserService
+	deletion     service.AccountDeletionService
+	exports      service.DataExportService
 	storage      filestorage.Client
 	imageProc    imageprocessor.Client
 	avatarPreset string
 	avatarKind   string
 }
 
-func NewHandler(users service.UserService, storage filestorage.Client, imgProc imageprocessor.Client, avatarPreset, avatarKind string) *Handler {
-	return &Handler{users: users, storage: storage, imageProc: imgProc, avatarPreset: avatarPreset, avatarKind: avatarKind}
+func NewHandler(users service.UserService, deletion service.AccountDeletionService, exports service.DataExportService, storage filestorage.Client, imgProc imageprocessor.Client, avatarPreset, avatarKind string) *Handler {
+	return &Handler{users: users, deletion: deletion, exports: exports, storage: storage, imageProc: imgProc, avatarPreset: avatarPreset, avatarKind: avatarKind}
 }
 
 type updateProfileRequest struct {
@@ -55,6 +57,20 @@ type profileResponse struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+type exportRequest struct {
+	Format string `json:"format"`
+}
+
+type exportJobResponse struct {
+	ID          string              `json:"id"`
+	Status      domain.ExportStatus `json:"status"`
+	Format      domain.ExportFormat `json:"format"`
+	DownloadURL string              `json:"download_url,omitempty"`
+	Error       *string             `json:"error,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+}
+
 type attachIdentityRequest struct {
 	Provider       string  `json:"provider"`
 	ProviderUserID string  `json:"provider_user_id"`
@@ -67,7 +83,8 @@ func (h *Handler) RegisterRoutes(g *echo.Group) {
 	g.GET("/me", h.GetMe)
 	g.GET("/:id", h.GetByID)
 	g.PATCH("/me", h.UpdateProfile)
-	g.POST("/me/avatar", h.UploadAvatar)
+	g.DELETE("/me", h.DeleteMe)
+	g.GET("/me/export/:job_id", h.GetExport)
 	g.GET("/me/identities", h.ListMyIdentities)
 	g.POST("/me/identities", h.AttachIdentity)
 	g.DELETE("/me/identities/:provider/:provider_user_id", h.RemoveIdentity)
@@ -77,8 +94,9 @@ func (h *Handler) GetMe(c echo.Context) error {
 	userID := c.Get("user_id").(string)
 	user, err := h.users.GetMe(c.Request().Context(), userID)
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusNotFound, "not_found", "user not found", middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
+	c.Response().Header().Set(middleware.HeaderETag, user.ETag())
 	return res.JSON(c, http.StatusOK, h.newSelfUserResponse(user))
 }
 
@@ -87,7 +105,7 @@ func (h *Handler) GetByID(c echo.Context) error {
 	requester := c.Get("user_id").(string)
 	user, err := h.users.GetByID(c.Request().Context(), requester, userID)
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusNotFound, "not_found", "user not found", middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
 	return res.JSON(c, http.StatusOK, h.newPublicUserResponse(user))
 }
@@ -97,29 +115,96 @@ func (h *Handler) UpdateProfile(c echo.Context) error {
 	decoder := json.NewDecoder(c.Request().Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(&req); err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid payload", middleware.RequestIDFromCtx(c), nil)
+		return middleware.ErrInvalidPayload
 	}
 	if decoder.More() {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid payload", middleware.RequestIDFromCtx(c), nil)
+		return middleware.ErrInvalidPayload
+	}
+	userID := c.Get("user_id").(string)
+	user, err := h.users.UpdateProfile(c.Request().Context(), userID, req.DisplayName, c.Request().Header.Get(middleware.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(middleware.HeaderETag, user.ETag())
+	return res.JSON(c, http.StatusOK, h.newProfileResponse(user.Profile))
+}
+
+// DeleteMe schedules the caller's account for deletion. The account can be
+// restored via RestoreMe until purge_after.
+func (h *Handler) DeleteMe(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	user, err := h.deletion.Delete(c.Request().Context(), userID, userID)
+	if err != nil {
+		return err
 	}
+	return res.JSON(c, http.StatusAccepted, map[string]interface{}{
+		"id":          user.ID,
+		"status":      user.Status,
+		"deleted_at":  user.DeletedAt,
+		"purge_after": user.PurgeAfter,
+	})
+}
+
+// RestoreMe cancels a pending deletion of the caller's account. It is mounted
+// behind AuthMiddleware.HandlerAllowDeleted.
+func (h *Handler) RestoreMe(c echo.Context) error {
 	userID := c.Get("user_id").(string)
-	profile, err := h.users.UpdateProfile(c.Request().Context(), userID, req.DisplayName)
+	user, err := h.deletion.Restore(c.Request().Context(), userID, userID)
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusInternalServerError, "update_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
+	}
+	return res.JSON(c, http.StatusOK, h.newSelfUserResponse(user))
+}
+
+// RequestExport queues a personal-data export of the caller. The archive is
+// built in the background; poll GetExport for the download link.
+func (h *Handler) RequestExport(c echo.Context) error {
+	req := new(exportRequest)
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(req); err != nil {
+			return middleware.ErrInvalidPayload
+		}
+	}
+	userID := c.Get("user_id").(string)
+	format := domain.ExportFormat(strings.ToLower(strings.TrimSpace(req.Format)))
+	job, err := h.exports.RequestExport(c.Request().Context(), userID, format)
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusAccepted, newExportJobResponse(job, ""))
+}
+
+func (h *Handler) GetExport(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	result, err := h.exports.GetExport(c.Request().Context(), userID, c.Param("job_id"))
+	if err != nil {
+		return err
+	}
+	return res.JSON(c, http.StatusOK, newExportJobResponse(result.Job, result.DownloadURL))
+}
+
+func newExportJobResponse(job *domain.ExportJob, downloadURL string) *exportJobResponse {
+	return &exportJobResponse{
+		ID:          job.ID,
+		Status:      job.Status,
+		Format:      job.Format,
+		DownloadURL: downloadURL,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
 	}
-	return res.JSON(c, http.StatusOK, h.newProfileResponse(profile))
 }
 
 func (h *Handler) AttachIdentity(c echo.Context) error {
 	req := new(attachIdentityRequest)
 	if err := c.Bind(req); err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid payload", middleware.RequestIDFromCtx(c), nil)
+		return middleware.ErrInvalidPayload
 	}
 	provider := domain.IdentityProvider(strings.ToLower(req.Provider))
 	userID := c.Get("user_id").(string)
 	identity, profile, err := h.users.AttachIdentity(c.Request().Context(), userID, provider, req.ProviderUserID, req.Email, req.DisplayName, req.AvatarURL)
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "attach_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
 	return res.JSON(c, http.StatusCreated, map[string]interface{}{"identity": identity, "profile": h.decorateProfile(profile)})
 }
@@ -129,7 +214,7 @@ func (h *Handler) RemoveIdentity(c echo.Context) error {
 	providerUserID := c.Param("provider_user_id")
 	userID := c.Get("user_id").(string)
 	if err := h.users.RemoveIdentity(c.Request().Context(), userID, provider, providerUserID); err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "detach_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
 	return res.JSON(c, http.StatusOK, map[string]string{"status": "detached"})
 }
@@ -138,7 +223,7 @@ func (h *Handler) ListMyIdentities(c echo.Context) error {
 	userID := c.Get("user_id").(string)
 	identities, err := h.users.ListIdentities(c.Request().Context(), userID)
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusInternalServerError, "list_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
 	return res.JSON(c, http.StatusOK, map[string]any{"identities": identities})
 }
@@ -148,20 +233,20 @@ const maxAvatarSize = 5 * 1024 * 1024
 func (h *Handler) UploadAvatar(c echo.Context) error {
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "file is required", middleware.RequestIDFromCtx(c), nil)
+		return domain.Invalid("file", "file is required")
 	}
 	src, err := fileHeader.Open()
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "file open failed", middleware.RequestIDFromCtx(c), nil)
+		return domain.Invalid("file", "file open failed")
 	}
 	defer src.Close()
 
 	data, err := io.ReadAll(io.LimitReader(src, maxAvatarSize+1))
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "file read failed", middleware.RequestIDFromCtx(c), nil)
+		return domain.Invalid("file", "file read failed")
 	}
 	if len(data) > maxAvatarSize {
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "file too large", middleware.RequestIDFromCtx(c), nil)
+		return domain.Invalid("file", "file too large")
 	}
 
 	userID := c.Get("user_id").(string)
@@ -172,7 +257,7 @@ func (h *Handler) UploadAvatar(c echo.Context) error {
 	switch processingMode {
 	case "EAGER", "LAZY", "DISABLED":
 	default:
-		return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid processing_mode", middleware.RequestIDFromCtx(c), nil)
+		return domain.Invalid("processing_mode", "invalid processing_mode")
 	}
 
 	uploadResp, err := h.storage.Upload(c.Request().Context(), filestorage.UploadRequest{
@@ -184,17 +269,17 @@ func (h *Handler) UploadAvatar(c echo.Context) error {
 		Data:           data,
 	})
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusBadRequest, "upload_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
 
 	profile, err := h.users.SetAvatarFileID(c.Request().Context(), userID, uploadResp.ID)
 	if err != nil {
-		return res.ErrorJSON(c, http.StatusInternalServerError, "update_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+		return err
 	}
 
 	if processingMode == "EAGER" && h.imageProc != nil {
 		if err := h.imageProc.Generate(c.Request().Context(), uploadResp.ID, userID, h.avatarKind, h.avatarPreset, nil); err != nil {
-			return res.ErrorJSON(c, http.StatusInternalServerError, "processing_failed", err.Error(), middleware.RequestIDFromCtx(c), nil)
+			return err
 		}
 	}
 
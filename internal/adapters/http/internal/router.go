@@ -4,6 +4,9 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+
+	"github.com/example/user-service/pkg/health"
+	"github.com/example/user-service/pkg/metrics"
 )
 
 // Register mounts internal/health endpoints under provided group.
@@ -12,3 +15,25 @@ func Register(g *echo.Group) {
 		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 	})
 }
+
+// RegisterProbes mounts the Kubernetes probes. /live only shows the process
+// serves requests; /ready runs the dependency checks and answers 503 when a
+// critical one fails or the service is shutting down.
+func RegisterProbes(g *echo.Group, checks *health.Registry) {
+	g.GET("/live", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	})
+	g.GET("/ready", func(c echo.Context) error {
+		report := checks.Check(c.Request().Context())
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, report)
+	})
+}
+
+// RegisterMetrics exposes the Prometheus metrics of the service.
+func RegisterMetrics(g *echo.Group) {
+	g.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+}
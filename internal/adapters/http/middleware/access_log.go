@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	pkglog "github.com/example/user-service/pkg/log"
+	"github.com/example/user-service/pkg/tracing"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are never written to the access log.
+var sensitiveHeaders = map[string]bool{
+	echo.HeaderAuthorization: true,
+	"Proxy-Authorization":    true,
+	echo.HeaderCookie:        true,
+	echo.HeaderXCSRFToken:    true,
+	HeaderAuthSignature:      true,
+	"X-Api-Key":              true,
+}
+
+// AccessLog writes one zerolog line per request and puts a logger carrying
+// the request and trace IDs into the request context, for handlers and
+// usecases to pick up with pkglog.FromContext. Auth adds the user ID and
+// role to it once known.
+//
+// Only one in sampleSuccess requests below 400 is logged; failures always
+// are. Requests that failed also log their headers, with credentials
+// redacted. Email addresses are masked everywhere.
+func AccessLog(logger pkglog.Logger, sampleSuccess uint32) echo.MiddlewareFunc {
+	if sampleSuccess == 0 {
+		sampleSuccess = 1
+	}
+	var successes atomic.Uint32
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			reqLogger := logger.With().
+				Str("request_id", RequestIDFromCtx(c)).
+				Str("trace_id", tracing.TraceID(req.Context())).
+				Logger()
+			c.SetRequest(req.WithContext(pkglog.WithContext(req.Context(), reqLogger)))
+
+			err := next(c)
+
+			status := responseStatus(c, err)
+			if status < http.StatusBadRequest && (successes.Add(1)-1)%sampleSuccess != 0 {
+				return err
+			}
+			event := pkglog.FromContext(c.Request().Context()).Info()
+			switch {
+			case status >= http.StatusInternalServerError:
+				event = pkglog.FromContext(c.Request().Context()).Error()
+			case status >= http.StatusBadRequest:
+				event = pkglog.FromContext(c.Request().Context()).Warn()
+			}
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			event.
+				Str("method", req.Method).
+				Str("route", route).
+				Str("uri", pkglog.RedactEmails(req.RequestURI)).
+				Int("status", status).
+				Dur("latency", time.Since(start)).
+				Str("bytes_in", req.Header.Get(echo.HeaderContentLength)).
+				Int64("bytes_out", c.Response().Size).
+				Str("remote_ip", c.RealIP()).
+				Str("user_agent", req.UserAgent())
+			if err != nil {
+				event.Str("error", pkglog.RedactEmails(err.Error()))
+			}
+			if status >= http.StatusBadRequest {
+				event.Dict("headers", redactHeaders(req.Header))
+			}
+			event.Msg("request")
+			return err
+		}
+	}
+}
+
+// withLogFields adds fields, such as the authenticated caller, to the
+// request-scoped logger.
+func withLogFields(c echo.Context, fields func(zerolog.Context) zerolog.Context) {
+	ctx := c.Request().Context()
+	logger := fields(pkglog.FromContext(ctx).With()).Logger()
+	c.SetRequest(c.Request().WithContext(pkglog.WithContext(ctx, logger)))
+}
+
+func redactHeaders(header http.Header) *zerolog.Event {
+	dict := zerolog.Dict()
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if sensitiveHeaders[name] {
+			value = redacted
+		}
+		dict.Str(name, pkglog.RedactEmails(value))
+	}
+	return dict
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/example/user-service/internal/domain"
+	res "github.com/example/user-service/pkg/http"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// APIKeyAuthenticator resolves an API key to the calling service client.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*domain.ServiceClient, error)
+}
+
+// APIKeyMiddleware authenticates other services sending
+// "Authorization: ApiKey <key>". The client is exposed as "service_client",
+// "client_id" and "scopes", next to the "user_id"/"role" set for users.
+type APIKeyMiddleware struct {
+	clients APIKeyAuthenticator
+	logger  pkglog.Logger
+}
+
+func NewAPIKeyMiddleware(clients APIKeyAuthenticator, logger pkglog.Logger) *APIKeyMiddleware {
+	return &APIKeyMiddleware{clients: clients, logger: logger}
+}
+
+func (m *APIKeyMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		parts := strings.SplitN(c.Request().Header.Get(echo.HeaderAuthorization), " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "apikey") || strings.TrimSpace(parts[1]) == "" {
+			return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "missing api key", TraceIDFromCtx(c), nil)
+		}
+		client, err := m.clients.Authenticate(c.Request().Context(), strings.TrimSpace(parts[1]))
+		if err != nil {
+			if !errors.Is(err, domain.ErrInvalidAPIKey) {
+				m.logger.Error().Err(err).Str("request_id", RequestIDFromCtx(c)).Msg("api key lookup failed")
+				return res.ErrorJSON(c, http.StatusInternalServerError, "internal_error", "api key lookup failed", TraceIDFromCtx(c), nil)
+			}
+			m.logger.Warn().Str("remote_addr", c.Request().RemoteAddr).Str("request_id", RequestIDFromCtx(c)).Msg("rejected api key")
+			return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "invalid api key", TraceIDFromCtx(c), nil)
+		}
+		c.Set("service_client", client)
+		c.Set("client_id", client.ID)
+		c.Set("scopes", []string(client.Scopes))
+		withLogFields(c, func(l zerolog.Context) zerolog.Context {
+			return l.Str("client_id", client.ID)
+		})
+		return next(c)
+	}
+}
+
+// RequireScope rejects service clients that were not granted scope.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !HasScope(c, scope) {
+				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "scope "+scope+" required", TraceIDFromCtx(c), nil)
+			}
+			return next(c)
+		}
+	}
+}
+
+// HasScope reports whether the calling service client holds scope.
+func HasScope(c echo.Context, scope string) bool {
+	scopes, _ := c.Get("scopes").([]string)
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
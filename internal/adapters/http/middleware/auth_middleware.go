@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -14,37 +15,59 @@ import (
 	"github.com/example/user-service/config"
 	repo "github.com/example/user-service/internal/adapters/postgres"
 	rbacclient "github.com/example/user-service/internal/adapters/rbac"
+	"github.com/example/user-service/internal/domain"
 	res "github.com/example/user-service/pkg/http"
 	pkglog "github.com/example/user-service/pkg/log"
+	"github.com/example/user-service/pkg/tracing"
 )
 
 const roleCheckSubject = "rbac.checkRole"
 
 type AuthMiddleware struct {
-	cfg    *config.Config
-	logger pkglog.Logger
-	rbac   rbacclient.Client
-	users  repo.UserRepository
-	nats   *nats.Conn
-	verify TokenVerifier
+	cfg     *config.Config
+	logger  pkglog.Logger
+	rbac    rbacclient.Client
+	users   repo.UserRepository
+	nats    *nats.Conn
+	verify  TokenVerifier
+	gateway *gatewayAuth
+	cache   *PrincipalCache
 }
 
 type TokenVerifier func(ctx context.Context, token string) (string, string, string, error)
 
 func NewAuthMiddleware(cfg *config.Config, logger pkglog.Logger, rbac rbacclient.Client, users repo.UserRepository, natsConn *nats.Conn) *AuthMiddleware {
-	return &AuthMiddleware{cfg: cfg, logger: logger, rbac: rbac, users: users, nats: natsConn}
+	return &AuthMiddleware{cfg: cfg, logger: logger, rbac: rbac, users: users, nats: natsConn, gateway: newGatewayAuth(cfg)}
 }
 
 func NewAuthMiddlewareWithVerifier(cfg *config.Config, logger pkglog.Logger, rbac rbacclient.Client, users repo.UserRepository, natsConn *nats.Conn, verifier TokenVerifier) *AuthMiddleware {
-	return &AuthMiddleware{cfg: cfg, logger: logger, rbac: rbac, users: users, nats: natsConn, verify: verifier}
+	return &AuthMiddleware{cfg: cfg, logger: logger, rbac: rbac, users: users, nats: natsConn, verify: verifier, gateway: newGatewayAuth(cfg)}
+}
+
+// WithPrincipalCache makes the middleware reuse verified principals from
+// cache for repeated tokens.
+func (a *AuthMiddleware) WithPrincipalCache(cache *PrincipalCache) *AuthMiddleware {
+	a.cache = cache
+	return a
 }
 
 func (a *AuthMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
+	return a.handle(next, false)
+}
+
+// HandlerAllowDeleted authenticates like Handler but lets soft-deleted users
+// through, so they can restore their account during the grace period.
+func (a *AuthMiddleware) HandlerAllowDeleted(next echo.HandlerFunc) echo.HandlerFunc {
+	return a.handle(next, true)
+}
+
+func (a *AuthMiddleware) handle(next echo.HandlerFunc, allowDeleted bool) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		var (
 			userID          string
 			role            string
 			email           string
+			token           string
 			err             error
 			trustedByHeader bool
 		)
@@ -57,7 +80,15 @@ func (a *AuthMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
 
 		if headerUserID != "" {
 			if strings.TrimSpace(headerRole) == "" {
-				return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "missing role", RequestIDFromCtx(c), nil)
+				return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "missing role", TraceIDFromCtx(c), nil)
+			}
+			if err := a.gateway.verify(c.Request(), headerUserID, headerRole); err != nil {
+				a.logger.Warn().Err(err).
+					Str("remote_addr", c.Request().RemoteAddr).
+					Str("user_id", headerUserID).
+					Str("request_id", RequestIDFromCtx(c)).
+					Msg("rejected identity headers")
+				return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", err.Error(), TraceIDFromCtx(c), nil)
 			}
 			userID = headerUserID
 			role = headerRole
@@ -66,20 +97,35 @@ func (a *AuthMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
 		} else {
 			header := c.Request().Header.Get(echo.HeaderAuthorization)
 			if header == "" {
-				return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "missing token", RequestIDFromCtx(c), nil)
+				return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "missing token", TraceIDFromCtx(c), nil)
 			}
 			parts := strings.SplitN(header, " ", 2)
 			if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
-				return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "invalid token", RequestIDFromCtx(c), nil)
+				return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "invalid token", TraceIDFromCtx(c), nil)
+			}
+			token = parts[1]
+			// A cached principal skips verification and the user and RBAC
+			// lookups; only the account state is checked again.
+			if cached, ok := a.cache.get(token); ok {
+				if rejected, err := a.rejectUser(c, &cached.user, allowDeleted); rejected {
+					return err
+				}
+				cached.set(c)
+				return next(c)
 			}
 			if a.verify != nil {
-				userID, role, email, err = a.verify(c.Request().Context(), parts[1])
+				userID, role, email, err = a.verify(c.Request().Context(), token)
 			} else {
-				userID, role, email, err = a.verifyWithAuthService(c.Request().Context(), parts[1])
+				userID, role, email, err = a.verifyWithAuthService(c.Request().Context(), token)
 			}
 		}
+		if errors.Is(err, domain.ErrUnavailable) {
+			return err
+		}
 		if err != nil {
-			return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", err.Error(), RequestIDFromCtx(c), nil)
+			// Why a token was rejected is not the client's business.
+			a.logger.Debug().Err(err).Str("request_id", RequestIDFromCtx(c)).Msg("token rejected")
+			return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "invalid token", TraceIDFromCtx(c), nil)
 		}
 		if strings.TrimSpace(role) == "" && a.rbac != nil {
 			if fetchedRole, err := a.rbac.GetRoleByUserID(c.Request().Context(), userID); err == nil {
@@ -87,37 +133,64 @@ func (a *AuthMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
 			}
 		}
 
-		user, err := a.users.FindByID(c.Request().Context(), userID)
-		if err != nil || user == nil {
-			return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "user not found", RequestIDFromCtx(c), nil)
+		user, err := a.users.FindByIDIncludingDeleted(c.Request().Context(), userID)
+		if err != nil || user == nil || user.PurgedAt != nil {
+			return res.ErrorJSON(c, http.StatusUnauthorized, "unauthorized", "user not found", TraceIDFromCtx(c), nil)
+		}
+		if rejected, err := a.rejectUser(c, user, allowDeleted); rejected {
+			return err
 		}
-		c.Set("user", user)
-		c.Set("email", email)
 
 		if a.nats != nil && !trustedByHeader {
 			allowed, err := a.checkRole(c.Request().Context(), userID, role)
 			if err != nil {
-				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", err.Error(), RequestIDFromCtx(c), nil)
+				return err
 			}
 			if !allowed {
-				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "role not allowed", RequestIDFromCtx(c), nil)
+				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "role not allowed", TraceIDFromCtx(c), nil)
 			}
 		}
 
-		c.Set("user_id", userID)
-		c.Set("role", strings.ToUpper(role))
+		p := principal{user: *user, userID: userID, role: strings.ToUpper(role), email: email}
 		if a.rbac != nil {
 			if perms, err := a.rbac.GetPermissionsByUserID(c.Request().Context(), userID); err == nil {
-				c.Set("permissions", perms)
+				p.permissions = perms
 			}
 		}
+		if !trustedByHeader {
+			a.cache.put(token, p)
+		}
+		p.set(c)
 		return next(c)
 	}
 }
 
+// rejectUser writes the error response for accounts that may not sign in
+// and reports whether it did.
+func (a *AuthMiddleware) rejectUser(c echo.Context, user *domain.User, allowDeleted bool) (bool, error) {
+	if user.IsDeleted() && !allowDeleted {
+		details := map[string]interface{}{"deleted_at": user.DeletedAt, "purge_after": user.PurgeAfter}
+		return true, res.ErrorJSON(c, http.StatusForbidden, "account_deleted", "account is scheduled for deletion", TraceIDFromCtx(c), details)
+	}
+	if user.Status == domain.UserStatusBlocked {
+		return true, res.ErrorJSON(c, http.StatusForbidden, "user_blocked", "user is blocked", TraceIDFromCtx(c), nil)
+	}
+	if user.IsSuspended(time.Now()) {
+		details := map[string]interface{}{"suspended_until": user.SuspendedUntil, "reason_code": user.SuspensionReason}
+		message := "user is suspended"
+		if user.SuspendedUntil != nil {
+			message += " until " + user.SuspendedUntil.UTC().Format(time.RFC3339)
+		}
+		return true, res.ErrorJSON(c, http.StatusForbidden, "user_suspended", message, TraceIDFromCtx(c), details)
+	}
+	return false, nil
+}
+
+// checkRole asks RBAC over NATS whether role is allowed. Failures are typed:
+// an error reply is forbidden, a missing or undecodable one unavailable.
 func (a *AuthMiddleware) checkRole(ctx context.Context, userID, role string) (bool, error) {
 	if a.nats == nil {
-		return false, errors.New("rbac nats connection not configured")
+		return false, domain.Unavailable("rbac", errors.New("nats connection not configured"))
 	}
 	payload := struct {
 		UserID string `json:"user_id"`
@@ -128,19 +201,23 @@ func (a *AuthMiddleware) checkRole(ctx context.Context, userID, role string) (bo
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	msg, err := a.nats.RequestWithContext(ctx, roleCheckSubject, data)
+	ctx, span, req := tracing.StartNATSRequest(ctx, roleCheckSubject, data)
+	msg, err := a.nats.RequestMsgWithContext(ctx, req)
+	tracing.End(span, err)
 	if err != nil {
-		return false, err
+		return false, domain.Unavailable("rbac", err)
 	}
 	var resp struct {
 		OK    bool   `json:"ok"`
 		Error string `json:"error,omitempty"`
 	}
 	if err := json.Unmarshal(msg.Data, &resp); err != nil {
-		return false, err
+		return false, domain.Unavailable("rbac", fmt.Errorf("decode role check reply: %w", err))
 	}
 	if resp.Error != "" {
-		return false, errors.New(resp.Error)
+		forbidden := domain.Forbidden("forbidden", "role not allowed")
+		forbidden.Err = errors.New(resp.Error)
+		return false, forbidden
 	}
 	return resp.OK, nil
 }
@@ -154,32 +231,43 @@ type verifyResp struct {
 }
 
 func (a *AuthMiddleware) verifyWithAuthService(ctx context.Context, token string) (string, string, string, error) {
-	if a.nats == nil {
-		return "", "", "", errors.New("auth service not reachable")
-	}
-	payload := map[string]string{"token": token}
-	data, _ := json.Marshal(payload)
-	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
-	defer cancel()
-	msg, err := a.nats.RequestWithContext(ctx, a.cfg.NATSAuthVerify, data)
-	if err != nil {
-		return "", "", "", err
-	}
-	var resp verifyResp
-	if err := json.Unmarshal(msg.Data, &resp); err != nil {
-		return "", "", "", err
-	}
-	if !resp.OK {
-		if resp.Error == "" {
-			resp.Error = "invalid token"
+	return NATSTokenVerifier(a.nats, a.cfg.NATSAuthVerify)(ctx, token)
+}
+
+// NATSTokenVerifier asks the auth service to verify each token over NATS.
+// Transport failures and undecodable replies are domain.Unavailable, so an
+// auth outage is a 503 rather than a rejected token.
+func NATSTokenVerifier(conn *nats.Conn, subject string) TokenVerifier {
+	return func(ctx context.Context, token string) (string, string, string, error) {
+		if conn == nil {
+			return "", "", "", domain.Unavailable("auth", errors.New("nats connection not configured"))
 		}
-		return "", "", "", errors.New(resp.Error)
-	}
-	role := ""
-	if resp.Claims != nil {
-		if r, ok := resp.Claims["role"].(string); ok {
-			role = r
+		payload := map[string]string{"token": token}
+		data, _ := json.Marshal(payload)
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		ctx, span, req := tracing.StartNATSRequest(ctx, subject, data)
+		msg, err := conn.RequestMsgWithContext(ctx, req)
+		tracing.End(span, err)
+		if err != nil {
+			return "", "", "", domain.Unavailable("auth", err)
+		}
+		var resp verifyResp
+		if err := json.Unmarshal(msg.Data, &resp); err != nil {
+			return "", "", "", domain.Unavailable("auth", fmt.Errorf("decode verify reply: %w", err))
+		}
+		if !resp.OK {
+			if resp.Error == "" {
+				resp.Error = "invalid token"
+			}
+			return "", "", "", errors.New(resp.Error)
+		}
+		role := ""
+		if resp.Claims != nil {
+			if r, ok := resp.Claims["role"].(string); ok {
+				role = r
+			}
 		}
+		return resp.UserID, role, resp.Email, nil
 	}
-	return resp.UserID, role, resp.Email, nil
 }
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IPExtractor returns how c.RealIP() finds the client address. Without
+// trusted proxies it is the peer address of the connection. Otherwise
+// X-Forwarded-For is walked from the right while the hops are trusted
+// proxies, so a client cannot forge its address by sending the header.
+func IPExtractor(trustedProxies []netip.Prefix) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, prefix := range trustedProxies {
+		prefix = prefix.Masked()
+		options = append(options, echo.TrustIPRange(&net.IPNet{
+			IP:   net.IP(prefix.Addr().AsSlice()),
+			Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+		}))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
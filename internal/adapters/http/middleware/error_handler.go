@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/domain"
+	res "github.com/example/user-service/pkg/http"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// ErrInvalidPayload is returned by handlers for request bodies that do not
+// decode.
+var ErrInvalidPayload = domain.Invalid("body", "invalid payload")
+
+// kindStatus is the response status of each domain error kind.
+var kindStatus = map[error]int{
+	domain.ErrNotFound:    http.StatusNotFound,
+	domain.ErrConflict:    http.StatusConflict,
+	domain.ErrValidation:  http.StatusBadRequest,
+	domain.ErrForbidden:   http.StatusForbidden,
+	domain.ErrUnavailable: http.StatusServiceUnavailable,
+}
+
+// codeStatus overrides kindStatus for codes with a more specific status.
+var codeStatus = map[string]int{
+	"precondition_failed":   http.StatusPreconditionFailed,
+	"restore_window_closed": http.StatusGone,
+}
+
+type problem struct {
+	status  int
+	code    string
+	message string
+	details interface{}
+}
+
+// problemFor maps err to the response clients get for it. Only messages
+// written for clients are passed on; anything unknown is a bare 500.
+func problemFor(err error) problem {
+	var (
+		validationErr *domain.ValidationError
+		domainErr     *domain.Error
+		httpErr       *echo.HTTPError
+	)
+	switch {
+	case errors.As(err, &validationErr):
+		return problem{status: http.StatusBadRequest, code: "validation_failed", message: "request is invalid", details: validationErr.Fields}
+	case errors.As(err, &domainErr):
+		status, ok := codeStatus[domainErr.Code]
+		if !ok {
+			status = kindStatus[domainErr.Kind]
+		}
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		return problem{status: status, code: domainErr.Code, message: domainErr.Message}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return problem{status: http.StatusNotFound, code: "not_found", message: "resource not found"}
+	case errors.As(err, &httpErr):
+		message, ok := httpErr.Message.(string)
+		if !ok || httpErr.Code >= http.StatusInternalServerError {
+			message = http.StatusText(httpErr.Code)
+		}
+		return problem{status: httpErr.Code, code: statusCode(httpErr.Code), message: message}
+	}
+	return problem{status: http.StatusInternalServerError, code: "internal_error", message: "internal server error"}
+}
+
+// statusCode derives an error code from a status, e.g. "method_not_allowed".
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// ErrorHandler is the echo HTTPErrorHandler. Handlers return errors and it
+// writes them as problem documents, so the status and code of each error
+// are decided in one place. Unknown errors become 500s whose cause stays in
+// the access log. Errors after the response was committed, such as a failed
+// stream, cannot be reported to the client and are logged instead, since the
+// access log may sample the request away as a success.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		pkglog.FromContext(c.Request().Context()).Error().Err(err).
+			Str("request_id", RequestIDFromCtx(c)).
+			Msg("error after response was committed")
+		return
+	}
+	p := problemFor(err)
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(p.status)
+	} else {
+		err = res.ErrorJSON(c, p.status, p.code, p.message, TraceIDFromCtx(c), p.details)
+	}
+	if err != nil {
+		pkglog.FromContext(c.Request().Context()).Error().Err(err).Msg("write error response")
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/user-service/config"
+)
+
+const (
+	HeaderAuthTimestamp = "X-Auth-Timestamp"
+	HeaderAuthSignature = "X-Auth-Signature"
+)
+
+const defaultGatewayReplayWindow = time.Minute
+
+// gatewayAuth decides whether X-User-Id/X-User-Role headers were set by the
+// gateway: either the connection comes from a trusted network, or the headers
+// carry a fresh HMAC signature that has not been seen before.
+//
+// Seen signatures are kept in memory, so each replica rejects replays on its
+// own; a signed request replayed to another replica within the window is
+// accepted. Deployments that need more should trust the gateway by network.
+type gatewayAuth struct {
+	secret  []byte
+	window  time.Duration
+	trusted []netip.Prefix
+	now     func() time.Time
+
+	mu sync.Mutex
+	// seen buckets the signatures by the replay window their expiry falls
+	// in, so expired signatures are dropped a bucket at a time. At most three
+	// buckets are live at once.
+	seen map[int64]map[string]struct{}
+}
+
+func newGatewayAuth(cfg *config.Config) *gatewayAuth {
+	window := cfg.GatewayReplayWindow
+	if window <= 0 {
+		window = defaultGatewayReplayWindow
+	}
+	return &gatewayAuth{
+		secret:  []byte(cfg.GatewayHeaderSecret),
+		window:  window,
+		trusted: cfg.GatewayTrustedCIDRs,
+		now:     time.Now,
+		seen:    map[int64]map[string]struct{}{},
+	}
+}
+
+// GatewaySignature returns the hex HMAC-SHA256 the gateway sends in
+// X-Auth-Signature for the given identity, unix timestamp and request ID.
+func GatewaySignature(secret, userID, role, timestamp, requestID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{userID, role, timestamp, requestID}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (g *gatewayAuth) verify(r *http.Request, userID, role string) error {
+	if g.fromTrustedNetwork(r.RemoteAddr) {
+		return nil
+	}
+	if len(g.secret) == 0 {
+		return errors.New("identity headers are not accepted from this address")
+	}
+	timestamp := r.Header.Get(HeaderAuthTimestamp)
+	signature := r.Header.Get(HeaderAuthSignature)
+	if timestamp == "" || signature == "" {
+		return errors.New("identity headers are not signed")
+	}
+	requestID := r.Header.Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		return errors.New("signed identity headers require a request id")
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid signature timestamp")
+	}
+	now := g.now()
+	signedAt := time.Unix(unix, 0)
+	if signedAt.Before(now.Add(-g.window)) || signedAt.After(now.Add(g.window)) {
+		return errors.New("stale identity signature")
+	}
+	expected := GatewaySignature(string(g.secret), userID, role, timestamp, requestID)
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return errors.New("invalid identity signature")
+	}
+	return g.remember(expected, signedAt.Add(g.window), now)
+}
+
+// remember rejects a signature already used within the replay window.
+func (g *gatewayAuth) remember(signature string, expiresAt, now time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	window := int64(g.window)
+	for bucket := range g.seen {
+		// The bucket's last signature expired at its end.
+		if (bucket+1)*window <= now.UnixNano() {
+			delete(g.seen, bucket)
+		}
+	}
+	for _, signatures := range g.seen {
+		if _, ok := signatures[signature]; ok {
+			return errors.New("replayed identity signature")
+		}
+	}
+	bucket := expiresAt.UnixNano() / window
+	if g.seen[bucket] == nil {
+		g.seen[bucket] = map[string]struct{}{}
+	}
+	g.seen[bucket][signature] = struct{}{}
+	return nil
+}
+
+// fromTrustedNetwork checks the peer address of the connection, not
+// forwarding headers, which the client controls.
+func (g *gatewayAuth) fromTrustedNetwork(remoteAddr string) bool {
+	if len(g.trusted) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddrPort(remoteAddr)
+	var ip netip.Addr
+	if err == nil {
+		ip = addr.Addr()
+	} else if ip, err = netip.ParseAddr(remoteAddr); err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range g.trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
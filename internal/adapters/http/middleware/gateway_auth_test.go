@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/config"
+)
+
+func TestGatewayAuthRememberExpiresBuckets(t *testing.T) {
+	g := newGatewayAuth(&config.Config{GatewayReplayWindow: time.Minute})
+	start := time.Unix(1_700_000_000, 0)
+
+	for i := 0; i < 100; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		require.NoError(t, g.remember("sig-"+strconv.Itoa(i), at.Add(time.Minute), at))
+	}
+	require.Error(t, g.remember("sig-99", start.Add(3*time.Minute), start.Add(100*time.Second)))
+	require.LessOrEqual(t, len(g.seen), 3)
+
+	// Once every signature has expired, the buckets are gone and a
+	// signature may be seen again.
+	later := start.Add(10 * time.Minute)
+	require.NoError(t, g.remember("sig-0", later.Add(time.Minute), later))
+	require.Len(t, g.seen, 1)
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	repo "github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/domain"
+	res "github.com/example/user-service/pkg/http"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyLease is how long an unfinished request blocks its key. A
+	// request still running after it is assumed lost, e.g. with a crashed
+	// instance, and the key may be used again.
+	idempotencyLease = 5 * time.Minute
+	// maxIdempotentRequestSize bounds the request body buffered for the
+	// fingerprint; larger requests with a key are refused.
+	maxIdempotentRequestSize = 10 << 20
+	// maxStoredResponseSize bounds the response body kept for replay. Larger
+	// responses are replayed with their status and headers only.
+	maxStoredResponseSize = 1 << 20
+)
+
+// replayedHeaders are the response headers stored with a completed request.
+var replayedHeaders = []string{echo.HeaderContentType, echo.HeaderLocation, echo.HeaderContentDisposition, HeaderETag}
+
+// IdempotencyMiddleware makes POST, PATCH and DELETE requests carrying an
+// Idempotency-Key safe to retry: the first response per caller and key is
+// stored and replayed for retries with the same request. It must run after
+// authentication; requests without a caller are passed through.
+type IdempotencyMiddleware struct {
+	records repo.IdempotencyRepository
+	ttl     time.Duration
+	logger  pkglog.Logger
+	now     func() time.Time
+	skip    map[string]bool
+}
+
+func NewIdempotencyMiddleware(records repo.IdempotencyRepository, ttl time.Duration, logger pkglog.Logger) *IdempotencyMiddleware {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &IdempotencyMiddleware{records: records, ttl: ttl, logger: logger, now: time.Now, skip: map[string]bool{}}
+}
+
+// SkipRoutes passes requests to the given route templates through without
+// idempotency, for routes streaming bodies too large to buffer.
+func (m *IdempotencyMiddleware) SkipRoutes(routes ...string) *IdempotencyMiddleware {
+	for _, route := range routes {
+		m.skip[route] = true
+	}
+	return m
+}
+
+// Handler is a no-op on a nil middleware.
+func (m *IdempotencyMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
+	if m == nil {
+		return next
+	}
+	return func(c echo.Context) error {
+		req := c.Request()
+		key := strings.TrimSpace(req.Header.Get(HeaderIdempotencyKey))
+		if key == "" || !isIdempotentMethod(req.Method) || m.skip[c.Path()] {
+			return next(c)
+		}
+		principal := idempotencyPrincipal(c)
+		if principal == "" {
+			return next(c)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "Idempotency-Key is too long", TraceIDFromCtx(c), nil)
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, maxIdempotentRequestSize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return res.ErrorJSON(c, http.StatusRequestEntityTooLarge, "payload_too_large", "request body is too large for an Idempotency-Key", TraceIDFromCtx(c), nil)
+		}
+		if err != nil {
+			return res.ErrorJSON(c, http.StatusBadRequest, "bad_request", "invalid request body", TraceIDFromCtx(c), nil)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := &domain.IdempotencyRecord{
+			Principal:   principal,
+			Key:         key,
+			Fingerprint: requestFingerprint(req, body),
+			ExpiresAt:   m.now().UTC().Add(idempotencyLease),
+		}
+		existing, err := m.records.Reserve(req.Context(), record)
+		if err != nil {
+			m.logger.Error().Err(err).Str("request_id", RequestIDFromCtx(c)).Msg("idempotency key lookup failed")
+			return res.ErrorJSON(c, http.StatusInternalServerError, "internal_error", "idempotency key lookup failed", TraceIDFromCtx(c), nil)
+		}
+		if existing != nil {
+			return m.replay(c, existing, record.Fingerprint)
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+		err = next(c)
+		c.Response().Writer = recorder.ResponseWriter
+
+		// The outcome is stored even if the client went away meanwhile, which
+		// is when it is most likely to retry.
+		ctx := context.WithoutCancel(req.Context())
+		// Failures are not remembered, so the client can retry them.
+		status := c.Response().Status
+		if err != nil || !c.Response().Committed || status >= http.StatusInternalServerError {
+			if releaseErr := m.records.Release(ctx, record.ID); releaseErr != nil {
+				m.logger.Error().Err(releaseErr).Str("request_id", RequestIDFromCtx(c)).Msg("idempotency key release failed")
+			}
+			return err
+		}
+		headers := domain.JSONMap{}
+		for _, name := range replayedHeaders {
+			if value := c.Response().Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if err := m.records.Complete(ctx, record.ID, status, headers, recorder.stored(), m.now().UTC().Add(m.ttl)); err != nil {
+			m.logger.Error().Err(err).Str("request_id", RequestIDFromCtx(c)).Msg("idempotency key completion failed")
+		}
+		return nil
+	}
+}
+
+func (m *IdempotencyMiddleware) replay(c echo.Context, record *domain.IdempotencyRecord, fingerprint string) error {
+	if record.Fingerprint != fingerprint {
+		return res.ErrorJSON(c, http.StatusConflict, "idempotency_key_reused", "Idempotency-Key was used for a different request", TraceIDFromCtx(c), nil)
+	}
+	if record.InFlight() {
+		c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+		return res.ErrorJSON(c, http.StatusConflict, "request_in_progress", "a request with this Idempotency-Key is still in progress", TraceIDFromCtx(c), nil)
+	}
+	header := c.Response().Header()
+	for name, value := range record.ResponseHeaders {
+		if value, ok := value.(string); ok {
+			header.Set(name, value)
+		}
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+	c.Response().WriteHeader(*record.ResponseStatus)
+	_, err := c.Response().Write(record.ResponseBody)
+	return err
+}
+
+func isIdempotentMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPatch || method == http.MethodDelete
+}
+
+func idempotencyPrincipal(c echo.Context) string {
+	if userID, ok := c.Get("user_id").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	if clientID, ok := c.Get("client_id").(string); ok && clientID != "" {
+		return "client:" + clientID
+	}
+	return ""
+}
+
+// requestFingerprint hashes the method, URI and body. Multipart boundaries
+// are random per attempt in most clients, so they are left out.
+func requestFingerprint(req *http.Request, body []byte) string {
+	if mediaType, params, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType)); err == nil &&
+		strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		body = bytes.ReplaceAll(body, []byte(params["boundary"]), nil)
+	}
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder copies the response body while it is written, up to
+// maxStoredResponseSize.
+type responseRecorder struct {
+	http.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if !r.overflow {
+		if r.body.Len()+len(data) > maxStoredResponseSize {
+			r.overflow = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(data)
+		}
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// stored returns the body to replay; nil if it was too large to keep.
+func (r *responseRecorder) stored() []byte {
+	if r.overflow {
+		return nil
+	}
+	return r.body.Bytes()
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/user-service/pkg/metrics"
+)
+
+// unmatchedRoute labels requests that matched no route, so scanners probing
+// random paths cannot blow up the label set.
+const unmatchedRoute = "unmatched"
+
+// Metrics records a counter and a latency histogram per route template and
+// status.
+func Metrics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		status := responseStatus(c, err)
+		route := c.Path()
+		if route == "" || status == http.StatusNotFound && route == "/*" {
+			route = unmatchedRoute
+		}
+		metrics.ObserveHTTP(c.Request().Method, route, status, time.Since(start))
+		return err
+	}
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/metrics"
+)
+
+// principal is what the auth middleware resolves a bearer token to.
+type principal struct {
+	user        domain.User
+	userID      string
+	role        string
+	email       string
+	permissions []string
+}
+
+func (p principal) set(c echo.Context) {
+	user := p.user
+	c.Set("user", &user)
+	c.Set("email", p.email)
+	c.Set("user_id", p.userID)
+	c.Set("role", p.role)
+	if p.permissions != nil {
+		c.Set("permissions", append([]string(nil), p.permissions...))
+	}
+	withLogFields(c, func(l zerolog.Context) zerolog.Context {
+		return l.Str("user_id", p.userID).Str("role", p.role)
+	})
+}
+
+// PrincipalCache keeps verified principals keyed by a hash of their token,
+// so repeated requests skip token verification and the user and RBAC
+// lookups. Entries live until the token expires or the TTL passes, whichever
+// comes first; the least recently used entry is dropped when full. EvictUser
+// removes every entry of a user after logout or a status change.
+type PrincipalCache struct {
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[[sha256.Size]byte]*list.Element
+	byUser  map[string]map[[sha256.Size]byte]struct{}
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type cacheEntry struct {
+	key       [sha256.Size]byte
+	principal principal
+	expiresAt time.Time
+}
+
+// PrincipalCacheStats is a snapshot of the counters of one cache; the
+// service-wide totals are exported through pkg/metrics.
+type PrincipalCacheStats struct {
+	Entries   int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	HitRatio  float64
+}
+
+func NewPrincipalCache(maxEntries int, ttl time.Duration) *PrincipalCache {
+	return &PrincipalCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    map[[sha256.Size]byte]*list.Element{},
+		byUser:     map[string]map[[sha256.Size]byte]struct{}{},
+	}
+}
+
+// get is safe on a nil cache, which never hits.
+func (pc *PrincipalCache) get(token string) (principal, bool) {
+	if pc == nil {
+		return principal{}, false
+	}
+	key := sha256.Sum256([]byte(token))
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	elem, ok := pc.entries[key]
+	if ok {
+		entry := elem.Value.(*cacheEntry)
+		if pc.now().Before(entry.expiresAt) {
+			pc.order.MoveToFront(elem)
+			pc.hits.Add(1)
+			metrics.AuthCacheHit()
+			return entry.principal, true
+		}
+		pc.remove(elem)
+	}
+	pc.misses.Add(1)
+	metrics.AuthCacheMiss()
+	return principal{}, false
+}
+
+func (pc *PrincipalCache) put(token string, p principal) {
+	if pc == nil || pc.maxEntries <= 0 {
+		return
+	}
+	expiresAt := pc.now().Add(pc.ttl)
+	if exp, ok := tokenExpiry(token); ok && exp.Before(expiresAt) {
+		expiresAt = exp
+	}
+	if !pc.now().Before(expiresAt) {
+		return
+	}
+	key := sha256.Sum256([]byte(token))
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if elem, ok := pc.entries[key]; ok {
+		pc.remove(elem)
+	}
+	pc.entries[key] = pc.order.PushFront(&cacheEntry{key: key, principal: p, expiresAt: expiresAt})
+	if pc.byUser[p.userID] == nil {
+		pc.byUser[p.userID] = map[[sha256.Size]byte]struct{}{}
+	}
+	pc.byUser[p.userID][key] = struct{}{}
+	for pc.order.Len() > pc.maxEntries {
+		pc.remove(pc.order.Back())
+		pc.evictions.Add(1)
+		metrics.AuthCacheEvicted(1)
+	}
+}
+
+// EvictUser drops all cached tokens of userID and returns how many there
+// were.
+func (pc *PrincipalCache) EvictUser(userID string) int {
+	if pc == nil {
+		return 0
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	keys := pc.byUser[userID]
+	for key := range keys {
+		pc.remove(pc.entries[key])
+	}
+	n := len(keys)
+	pc.evictions.Add(uint64(n))
+	metrics.AuthCacheEvicted(n)
+	return n
+}
+
+func (pc *PrincipalCache) Stats() PrincipalCacheStats {
+	if pc == nil {
+		return PrincipalCacheStats{}
+	}
+	pc.mu.Lock()
+	entries := pc.order.Len()
+	pc.mu.Unlock()
+	stats := PrincipalCacheStats{
+		Entries:   entries,
+		Hits:      pc.hits.Load(),
+		Misses:    pc.misses.Load(),
+		Evictions: pc.evictions.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// remove must be called with mu held.
+func (pc *PrincipalCache) remove(elem *list.Element) {
+	entry := pc.order.Remove(elem).(*cacheEntry)
+	delete(pc.entries, entry.key)
+	if keys := pc.byUser[entry.principal.userID]; keys != nil {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(pc.byUser, entry.principal.userID)
+		}
+	}
+}
+
+// tokenExpiry reads exp from a JWT payload. It is only used for tokens that
+// were already verified, so the signature is not checked again.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(*claims.Exp*float64(time.Second))), true
+}
@@ -37,7 +37,7 @@ func (m *RBACMiddleware) requireRoles(roles []string) echo.MiddlewareFunc {
 		return func(c echo.Context) error {
 			userID, _ := c.Get("user_id").(string)
 			if userID == "" {
-				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "missing user", RequestIDFromCtx(c), nil)
+				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "missing user", TraceIDFromCtx(c), nil)
 			}
 			allowed := false
 			if cached, ok := c.Get("role").(string); ok {
@@ -69,7 +69,7 @@ func (m *RBACMiddleware) requireRoles(roles []string) echo.MiddlewareFunc {
 				}
 			}
 			if !allowed {
-				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "role required", RequestIDFromCtx(c), nil)
+				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "role required", TraceIDFromCtx(c), nil)
 			}
 			return next(c)
 		}
@@ -81,27 +81,51 @@ func (m *RBACMiddleware) RequirePermission(permission string) echo.MiddlewareFun
 		return func(c echo.Context) error {
 			userID, _ := c.Get("user_id").(string)
 			if userID == "" {
-				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "missing user", RequestIDFromCtx(c), nil)
+				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "missing user", TraceIDFromCtx(c), nil)
 			}
-			allowed := false
-			if cached, ok := c.Get("permissions").([]string); ok {
-				for _, p := range cached {
-					if p == permission {
-						allowed = true
-						break
-					}
-				}
+			if !m.checkPermission(c, userID, permission) {
+				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "permission required", TraceIDFromCtx(c), nil)
 			}
-			if !allowed && m.client != nil {
-				ok, err := m.client.CheckPermission(c.Request().Context(), userID, permission)
-				if err == nil {
-					allowed = ok
-				}
-			}
-			if !allowed {
-				return res.ErrorJSON(c, http.StatusForbidden, "forbidden", "permission required", RequestIDFromCtx(c), nil)
+			return next(c)
+		}
+	}
+}
+
+// LoadPermission runs the RequirePermission check without rejecting the
+// request. A granted permission is added to the cached "permissions" so the
+// handler can branch on it with HasPermission.
+func (m *RBACMiddleware) LoadPermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID, _ := c.Get("user_id").(string)
+			if userID != "" && !HasPermission(c, permission) && m.checkPermission(c, userID, permission) {
+				cached, _ := c.Get("permissions").([]string)
+				c.Set("permissions", append(append([]string(nil), cached...), permission))
 			}
 			return next(c)
 		}
 	}
 }
+
+func (m *RBACMiddleware) checkPermission(c echo.Context, userID, permission string) bool {
+	if HasPermission(c, permission) {
+		return true
+	}
+	if m.client == nil {
+		return false
+	}
+	ok, err := m.client.CheckPermission(c.Request().Context(), userID, permission)
+	return err == nil && ok
+}
+
+// HasPermission reports whether permission is among the caller's cached
+// permissions.
+func HasPermission(c echo.Context, permission string) bool {
+	cached, _ := c.Get("permissions").([]string)
+	for _, p := range cached {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
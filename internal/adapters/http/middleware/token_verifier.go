@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/example/user-service/internal/adapters/jwt"
+)
+
+// LocalTokenVerifier verifies tokens in process. Tokens signed with a key the
+// verifier does not know are passed to fallback, if any, so a key rotation
+// that has not reached the JWKS document yet does not lock users out.
+func LocalTokenVerifier(verifier *jwt.Verifier, fallback TokenVerifier) TokenVerifier {
+	return func(ctx context.Context, token string) (string, string, string, error) {
+		claims, err := verifier.Verify(ctx, token)
+		if errors.Is(err, jwt.ErrUnknownKey) && fallback != nil {
+			return fallback(ctx, token)
+		}
+		if err != nil {
+			return "", "", "", err
+		}
+		userID := claims.Subject
+		if userID == "" {
+			userID = claims.String("user_id")
+		}
+		if userID == "" {
+			return "", "", "", errors.New("token has no subject")
+		}
+		return userID, claims.String("role"), claims.String("email"), nil
+	}
+}
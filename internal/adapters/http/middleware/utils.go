@@ -1,6 +1,10 @@
 package middleware
 
-import "github.com/labstack/echo/v4"
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/user-service/pkg/tracing"
+)
 
 // RequestIDFromCtx extracts request ID from response or request headers.
 func RequestIDFromCtx(c echo.Context) string {
@@ -9,3 +13,28 @@ func RequestIDFromCtx(c echo.Context) string {
 	}
 	return c.Request().Header.Get(echo.HeaderXRequestID)
 }
+
+// TraceIDFromCtx returns the trace ID of the request span, falling back to the
+// request ID for requests that are not traced.
+func TraceIDFromCtx(c echo.Context) string {
+	if traceID := tracing.TraceID(c.Request().Context()); traceID != "" {
+		return traceID
+	}
+	return RequestIDFromCtx(c)
+}
+
+// responseStatus returns the status the request ends with. Middleware sees a
+// returned error before echo's error handler writes it, so unless a response
+// was already sent its status is taken from the error.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	return problemFor(err).status
+}
+
+// Conditional request headers, which echo does not define.
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
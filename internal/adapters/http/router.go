@@ -11,36 +11,89 @@ import (
 	apiv1 "github.com/example/user-service/internal/adapters/http/api/v1"
 	internalhttp "github.com/example/user-service/internal/adapters/http/internal"
 	authmw "github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/adapters/ratelimit"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/health"
 )
 
 type Router struct {
-	cfg          *config.Config
-	apiHandler   *apiv1.Handler
-	adminHandler *adminv1.Handler
-	authMW       *authmw.AuthMiddleware
-	rbacMW       *authmw.RBACMiddleware
+	cfg           *config.Config
+	apiHandler    *apiv1.Handler
+	adminHandler  *adminv1.Handler
+	auditHandler  *adminv1.AuditHandler
+	clientHandler *adminv1.ServiceClientHandler
+	authMW        *authmw.AuthMiddleware
+	rbacMW        *authmw.RBACMiddleware
+	apiKeyMW      *authmw.APIKeyMiddleware
+	rateLimiter   *authmw.RateLimiter
+	idempotency   *authmw.IdempotencyMiddleware
+	health        *health.Registry
+	accessLog     echo.MiddlewareFunc
 }
 
-func NewRouter(cfg *config.Config, apiHandler *apiv1.Handler, adminHandler *adminv1.Handler, authMW *authmw.AuthMiddleware, rbacMW *authmw.RBACMiddleware) *Router {
-	return &Router{cfg: cfg, apiHandler: apiHandler, adminHandler: adminHandler, authMW: authMW, rbacMW: rbacMW}
+func NewRouter(cfg *config.Config, apiHandler *apiv1.Handler, adminHandler *adminv1.Handler, auditHandler *adminv1.AuditHandler, clientHandler *adminv1.ServiceClientHandler, authMW *authmw.AuthMiddleware, rbacMW *authmw.RBACMiddleware, apiKeyMW *authmw.APIKeyMiddleware, rateLimiter *authmw.RateLimiter, idempotency *authmw.IdempotencyMiddleware, checks *health.Registry, accessLog echo.MiddlewareFunc) *Router {
+	return &Router{cfg: cfg, apiHandler: apiHandler, adminHandler: adminHandler, auditHandler: auditHandler, clientHandler: clientHandler, authMW: authMW, rbacMW: rbacMW, apiKeyMW: apiKeyMW, rateLimiter: rateLimiter, idempotency: idempotency, health: checks, accessLog: accessLog}
 }
 
 func (r *Router) Setup(e *echo.Echo) {
 	e.HideBanner = true
-	e.Use(middleware.Recover())
+	e.HTTPErrorHandler = authmw.ErrorHandler
+	e.IPExtractor = authmw.IPExtractor(r.cfg.TrustedProxyCIDRs)
+	// Metrics goes first so requests that panic are counted as 500s.
+	e.Use(authmw.Metrics)
+	e.Use(authmw.Tracing)
 	e.Use(middleware.RequestID())
-	e.Use(middleware.Logger())
+	// The access log sits outside Recover to log panics with their 500.
+	e.Use(r.accessLog)
+	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{r.cfg.CORSAllowOrigins},
-		AllowHeaders: []string{echo.HeaderAuthorization, echo.HeaderContentType, echo.HeaderXRequestedWith},
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		AllowOrigins:  []string{r.cfg.CORSAllowOrigins},
+		AllowHeaders:  []string{echo.HeaderAuthorization, echo.HeaderContentType, echo.HeaderXRequestedWith, authmw.HeaderIfMatch, authmw.HeaderIdempotencyKey},
+		AllowMethods:  []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		ExposeHeaders: []string{authmw.HeaderRateLimitLimit, authmw.HeaderRateLimitRemaining, authmw.HeaderRateLimitReset, echo.HeaderRetryAfter, authmw.HeaderIdempotentReplayed, authmw.HeaderETag},
 	}))
 	internalGroup := e.Group("/internal")
 	internalhttp.Register(internalGroup)
+	internalhttp.RegisterMetrics(internalGroup)
+	internalhttp.RegisterProbes(internalGroup, r.health)
+
+	// Budgets are per caller. The IP budget runs before authentication so
+	// floods without a valid credential never reach token verification.
+	// Expensive routes are charged against their own budget on top of the one
+	// of their API.
+	ipLimit := r.rateLimiter.LimitByIP("ip", ratelimit.Limit{PerMinute: r.cfg.RateLimitIPPerMin})
+	apiLimit := r.rateLimiter.Limit("api", ratelimit.Limit{PerMinute: r.cfg.RateLimitPerMin})
+	adminLimit := r.rateLimiter.Limit("admin", ratelimit.Limit{PerMinute: r.cfg.RateLimitAdminPerMin})
+	serviceLimit := r.rateLimiter.Limit("service", ratelimit.Limit{PerMinute: r.cfg.RateLimitServicePerMin})
+	avatarLimit := r.rateLimiter.Limit("avatar", ratelimit.Limit{PerMinute: r.cfg.RateLimitExpensivePerMin})
+	exportLimit := r.rateLimiter.Limit("export", ratelimit.Limit{PerMinute: r.cfg.RateLimitExpensivePerMin})
+
+	// Other services read and write users with API keys; the admin handlers
+	// are reused with scopes in place of roles. Updates cannot change roles,
+	// and creates refuse the privileged ones.
+	serviceGroup := e.Group("/internal/v1/users", ipLimit, r.apiKeyMW.Handler, serviceLimit, r.idempotency.Handler)
+	serviceGroup.GET("", r.adminHandler.ListUsers, authmw.RequireScope(domain.ScopeUsersRead))
+	serviceGroup.GET("/:id", r.adminHandler.GetUser, authmw.RequireScope(domain.ScopeUsersRead))
+	serviceGroup.POST("", r.adminHandler.CreateUserForService, authmw.RequireScope(domain.ScopeUsersWrite))
+	serviceGroup.PATCH("/:id", r.adminHandler.UpdateUserForService, authmw.RequireScope(domain.ScopeUsersWrite))
 
-	apiGroup := e.Group("/api/v1/users", r.authMW.Handler)
+	// Restoring must be reachable by soft-deleted users, so it bypasses the
+	// group middleware that rejects them.
+	e.POST("/api/v1/users/me/restore", r.apiHandler.RestoreMe, ipLimit, r.authMW.HandlerAllowDeleted, apiLimit, r.idempotency.Handler)
+	e.POST("/api/v1/users/me/avatar", r.apiHandler.UploadAvatar, ipLimit, r.authMW.Handler, apiLimit, avatarLimit, r.idempotency.Handler)
+
+	apiGroup := e.Group("/api/v1/users", ipLimit, r.authMW.Handler, apiLimit, r.idempotency.Handler)
+	apiGroup.POST("/me/export", r.apiHandler.RequestExport, exportLimit)
 	apiv1.RegisterRoutes(apiGroup, r.apiHandler)
 
-	adminGroup := e.Group("/admin/v1/users", r.authMW.Handler, r.rbacMW.RequireAnyRole("admin", "moderator"))
+	adminGroup := e.Group("/admin/v1/users", ipLimit, r.authMW.Handler, adminLimit, r.rbacMW.RequireAnyRole("admin", "moderator"), r.idempotency.Handler)
+	// Exports unmask emails only for callers holding the PII permission.
+	adminGroup.GET("/export", r.adminHandler.ExportUsers, exportLimit, r.rbacMW.LoadPermission(adminv1.PermissionExportPII))
 	adminv1.RegisterRoutes(adminGroup, r.adminHandler)
+
+	auditGroup := e.Group("/admin/v1/audit", ipLimit, r.authMW.Handler, adminLimit, r.rbacMW.RequireRole("admin"))
+	r.auditHandler.RegisterRoutes(auditGroup)
+
+	clientGroup := e.Group("/admin/v1/service-clients", ipLimit, r.authMW.Handler, adminLimit, r.rbacMW.RequireRole("admin"))
+	r.clientHandler.RegisterRoutes(clientGroup)
 }
@@ -8,6 +8,10 @@ import (
 	"net/http"
 	"path"
 	"time"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/metrics"
+	"github.com/example/user-service/pkg/tracing"
 )
 
 type Client interface {
@@ -28,13 +32,19 @@ type generateRequest struct {
 }
 
 func NewHTTPClient(baseURL string, timeout time.Duration) Client {
-	return &httpClient{baseURL: baseURL, client: &http.Client{Timeout: timeout}}
+	return &httpClient{baseURL: baseURL, client: &http.Client{Timeout: timeout, Transport: tracing.Transport("imageprocessor", nil)}}
 }
 
-func (c *httpClient) Generate(ctx context.Context, originalID, ownerID, fileKind, presetGroup string, variants []string) error {
+func (c *httpClient) Generate(ctx context.Context, originalID, ownerID, fileKind, presetGroup string, variants []string) (err error) {
 	if c.baseURL == "" {
 		return fmt.Errorf("image processor url is not configured")
 	}
+	defer func(start time.Time) {
+		metrics.ObserveClient("imageprocessor", "generate", err, time.Since(start))
+		if err != nil {
+			err = domain.Unavailable("imageprocessor", err)
+		}
+	}(time.Now())
 	body := generateRequest{
 		PresetGroup: presetGroup,
 		Variants:    variants,
@@ -0,0 +1,267 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// minRSAKeyBits rejects keys too short to be trusted.
+const minRSAKeyBits = 2048
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is a public verification key and the only algorithm it may be used
+// with.
+type Key struct {
+	ID        string
+	Algorithm string
+	Public    crypto.PublicKey
+}
+
+// KeySet resolves the key for a token's kid header.
+type KeySet interface {
+	Lookup(ctx context.Context, kid string) (Key, error)
+}
+
+// StaticKeySet verifies every token with a single configured key.
+type StaticKeySet struct {
+	key Key
+}
+
+func NewStaticKeySet(key Key) *StaticKeySet {
+	return &StaticKeySet{key: key}
+}
+
+// Lookup returns the key for any kid unless the key has an ID of its own.
+func (s *StaticKeySet) Lookup(_ context.Context, kid string) (Key, error) {
+	if s.key.ID != "" && kid != "" && kid != s.key.ID {
+		return Key{}, ErrUnknownKey
+	}
+	return s.key, nil
+}
+
+// ParsePublicKeyPEM reads a PKIX public key or certificate. Literal "\n"
+// sequences are accepted so the key fits in a single environment variable.
+func ParsePublicKeyPEM(data string) (Key, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(strings.TrimSpace(data), `\n`, "\n")))
+	if block == nil {
+		return Key{}, errors.New("public key is not PEM encoded")
+	}
+	var public crypto.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("parse public key: %w", err)
+		}
+		public = parsed
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("parse certificate: %w", err)
+		}
+		public = cert.PublicKey
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	alg, err := algorithmFor(public)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Algorithm: alg, Public: public}, nil
+}
+
+func algorithmFor(public crypto.PublicKey) (string, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return "", fmt.Errorf("rsa key shorter than %d bits", minRSAKeyBits)
+		}
+		return AlgRS256, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return "", errors.New("only P-256 ecdsa keys are supported")
+		}
+		return AlgES256, nil
+	case ed25519.PublicKey:
+		return AlgEdDSA, nil
+	}
+	return "", fmt.Errorf("unsupported public key type %T", public)
+}
+
+// JWKS is a key set fetched from a JSON Web Key Set URL. Call Refresh
+// periodically to pick up rotated keys; a lookup for an unknown kid also
+// triggers a refresh, at most once per minRefresh.
+type JWKS struct {
+	url        string
+	client     *http.Client
+	minRefresh time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]Key
+	lastRefresh time.Time
+	refreshMu   sync.Mutex
+}
+
+func NewJWKS(url string, client *http.Client, minRefresh time.Duration) *JWKS {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &JWKS{url: url, client: client, minRefresh: minRefresh, keys: map[string]Key{}}
+}
+
+func (j *JWKS) Lookup(ctx context.Context, kid string) (Key, error) {
+	if key, ok := j.key(kid); ok {
+		return key, nil
+	}
+	j.mu.RLock()
+	recent := time.Since(j.lastRefresh) < j.minRefresh
+	j.mu.RUnlock()
+	if !recent {
+		if err := j.Refresh(ctx); err != nil {
+			return Key{}, fmt.Errorf("%w: %v", ErrUnknownKey, err)
+		}
+		if key, ok := j.key(kid); ok {
+			return key, nil
+		}
+	}
+	return Key{}, ErrUnknownKey
+}
+
+func (j *JWKS) key(kid string) (Key, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// Refresh replaces the key set with the current document. Keys of
+// unsupported types are skipped; the previous set is kept on error.
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.refreshMu.Lock()
+	defer j.refreshMu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.client.Do(req)
+	j.mu.Lock()
+	j.lastRefresh = time.Now()
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks fetch failed with status %d", resp.StatusCode)
+	}
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+	keys := make(map[string]Key, len(doc.Keys))
+	for _, raw := range doc.Keys {
+		if key, err := raw.key(); err == nil {
+			keys[key.ID] = key
+		}
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+	return nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) key() (Key, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return Key{}, fmt.Errorf("key %q is not a signing key", k.Kid)
+	}
+	var public crypto.PublicKey
+	switch {
+	case k.Kty == "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return Key{}, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return Key{}, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return Key{}, errors.New("rsa exponent out of range")
+		}
+		public = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return Key{}, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return Key{}, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return Key{}, errors.New("ec point is not on P-256")
+		}
+		public = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return Key{}, errors.New("invalid ed25519 key")
+		}
+		public = ed25519.PublicKey(x)
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	alg, err := algorithmFor(public)
+	if err != nil {
+		return Key{}, err
+	}
+	if k.Alg != "" && k.Alg != alg {
+		return Key{}, fmt.Errorf("key %q declares unsupported alg %q", k.Kid, k.Alg)
+	}
+	return Key{ID: k.Kid, Algorithm: alg, Public: public}, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
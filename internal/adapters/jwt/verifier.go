@@ -0,0 +1,220 @@
+// Package jwt verifies signed access tokens locally against a static key or
+// a JWKS document.
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrTokenExpired         = errors.New("token expired")
+	ErrTokenNotYetValid     = errors.New("token not valid yet")
+	ErrInvalidIssuer        = errors.New("invalid token issuer")
+	ErrInvalidAudience      = errors.New("invalid token audience")
+)
+
+// Claims is the verified token payload.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore *time.Time
+	Raw       map[string]interface{}
+}
+
+// String returns a string claim, or "" when it is missing or not a string.
+func (c *Claims) String(name string) string {
+	value, _ := c.Raw[name].(string)
+	return value
+}
+
+type VerifierConfig struct {
+	// Issuer and Audience are required to match when set.
+	Issuer   string
+	Audience string
+	// ClockSkew is tolerated on exp and nbf.
+	ClockSkew time.Duration
+}
+
+type Verifier struct {
+	keys KeySet
+	cfg  VerifierConfig
+	now  func() time.Time
+}
+
+func NewVerifier(keys KeySet, cfg VerifierConfig) *Verifier {
+	return &Verifier{keys: keys, cfg: cfg, now: time.Now}
+}
+
+type header struct {
+	Alg  string          `json:"alg"`
+	Kid  string          `json:"kid"`
+	Crit json.RawMessage `json:"crit"`
+}
+
+// Verify checks the signature and registered claims of a compact JWS token.
+// Errors wrapping ErrUnknownKey mean the token may still be valid but was
+// signed with a key this verifier does not have.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	var hdr header
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return nil, err
+	}
+	if len(hdr.Crit) > 0 {
+		return nil, fmt.Errorf("%w: crit header is not supported", ErrMalformedToken)
+	}
+	switch hdr.Alg {
+	case AlgRS256, AlgES256, AlgEdDSA:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, hdr.Alg)
+	}
+	key, err := v.keys.Lookup(ctx, hdr.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != hdr.Alg {
+		return nil, fmt.Errorf("%w: key %q does not use %s", ErrUnsupportedAlgorithm, key.ID, hdr.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if !verifySignature(key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, err
+	}
+	claims, err := parseClaims(raw)
+	if err != nil {
+		return nil, err
+	}
+	return claims, v.validate(claims)
+}
+
+func (v *Verifier) validate(claims *Claims) error {
+	now := v.now()
+	if now.After(claims.ExpiresAt.Add(v.cfg.ClockSkew)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(v.cfg.ClockSkew).Before(*claims.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+	if v.cfg.Issuer != "" && claims.Issuer != v.cfg.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.cfg.Audience != "" {
+		for _, aud := range claims.Audience {
+			if aud == v.cfg.Audience {
+				return nil
+			}
+		}
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(out); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+func verifySignature(key Key, signed, signature []byte) bool {
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(public, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(public, signed, signature)
+	}
+	return false
+}
+
+func parseClaims(raw map[string]interface{}) (*Claims, error) {
+	claims := &Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Issuer, _ = raw["iss"].(string)
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		claims.Audience = []string{aud}
+	case []interface{}:
+		for _, item := range aud {
+			value, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: aud must be a string or list of strings", ErrMalformedToken)
+			}
+			claims.Audience = append(claims.Audience, value)
+		}
+	default:
+		return nil, fmt.Errorf("%w: aud must be a string or list of strings", ErrMalformedToken)
+	}
+	exp, err := numericDate(raw, "exp")
+	if err != nil {
+		return nil, err
+	}
+	if exp == nil {
+		return nil, fmt.Errorf("%w: exp is required", ErrMalformedToken)
+	}
+	claims.ExpiresAt = *exp
+	if claims.NotBefore, err = numericDate(raw, "nbf"); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func numericDate(raw map[string]interface{}, name string) (*time.Time, error) {
+	value, ok := raw[name]
+	if !ok {
+		return nil, nil
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be a number", ErrMalformedToken, name)
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a number", ErrMalformedToken, name)
+	}
+	at := time.Unix(0, int64(seconds*float64(time.Second)))
+	return &at, nil
+}
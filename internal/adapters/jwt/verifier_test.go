@@ -0,0 +1,228 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_Algorithms(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		alg    string
+		public crypto.PublicKey
+		signer crypto.Signer
+	}{
+		{alg: AlgRS256, public: &rsaKey.PublicKey, signer: rsaKey},
+		{alg: AlgES256, public: &ecKey.PublicKey, signer: ecKey},
+		{alg: AlgEdDSA, public: edPublic, signer: edPrivate},
+	}
+	for _, tc := range cases {
+		t.Run(tc.alg, func(t *testing.T) {
+			key, err := ParsePublicKeyPEM(publicPEM(t, tc.public))
+			require.NoError(t, err)
+			require.Equal(t, tc.alg, key.Algorithm)
+			verifier := NewVerifier(NewStaticKeySet(key), VerifierConfig{Issuer: "auth", Audience: "frontend"})
+
+			token := signToken(t, tc.alg, "", tc.signer, validClaims())
+			claims, err := verifier.Verify(context.Background(), token)
+			require.NoError(t, err)
+			require.Equal(t, "user-1", claims.Subject)
+			require.Equal(t, "admin", claims.String("role"))
+
+			parts := strings.Split(token, ".")
+			forged := signToken(t, tc.alg, "", tc.signer, map[string]interface{}{"sub": "user-2", "exp": time.Now().Add(time.Hour).Unix()})
+			_, err = verifier.Verify(context.Background(), parts[0]+"."+strings.Split(forged, ".")[1]+"."+parts[2])
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestVerifier_Claims(t *testing.T) {
+	t.Parallel()
+
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	verifier := NewVerifier(NewStaticKeySet(Key{Algorithm: AlgES256, Public: &signer.PublicKey}), VerifierConfig{Issuer: "auth", Audience: "frontend", ClockSkew: 30 * time.Second})
+	now := time.Now()
+
+	cases := []struct {
+		name    string
+		edit    func(map[string]interface{})
+		wantErr error
+	}{
+		{name: "valid"},
+		{name: "audience list", edit: func(c map[string]interface{}) { c["aud"] = []string{"mobile", "frontend"} }},
+		{name: "expired within skew", edit: func(c map[string]interface{}) { c["exp"] = now.Add(-10 * time.Second).Unix() }},
+		{name: "expired", edit: func(c map[string]interface{}) { c["exp"] = now.Add(-time.Minute).Unix() }, wantErr: ErrTokenExpired},
+		{name: "missing exp", edit: func(c map[string]interface{}) { delete(c, "exp") }, wantErr: ErrMalformedToken},
+		{name: "not yet valid", edit: func(c map[string]interface{}) { c["nbf"] = now.Add(time.Minute).Unix() }, wantErr: ErrTokenNotYetValid},
+		{name: "nbf within skew", edit: func(c map[string]interface{}) { c["nbf"] = now.Add(10 * time.Second).Unix() }},
+		{name: "wrong issuer", edit: func(c map[string]interface{}) { c["iss"] = "someone" }, wantErr: ErrInvalidIssuer},
+		{name: "wrong audience", edit: func(c map[string]interface{}) { c["aud"] = "mobile" }, wantErr: ErrInvalidAudience},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := validClaims()
+			if tc.edit != nil {
+				tc.edit(claims)
+			}
+			_, err := verifier.Verify(context.Background(), signToken(t, AlgES256, "", signer, claims))
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+
+	_, err = verifier.Verify(context.Background(), signToken(t, "none", "", nil, validClaims()))
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	_, err = verifier.Verify(context.Background(), signToken(t, AlgRS256, "", signer, validClaims()))
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+}
+
+func TestJWKS_RotationAndUnknownKey(t *testing.T) {
+	t.Parallel()
+
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newPublic, newPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var (
+		rotated atomic.Bool
+		fetches atomic.Int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []map[string]string{{
+			"kid": "old", "kty": "EC", "crv": "P-256", "alg": AlgES256,
+			"x": base64.RawURLEncoding.EncodeToString(oldKey.X.FillBytes(make([]byte, 32))),
+			"y": base64.RawURLEncoding.EncodeToString(oldKey.Y.FillBytes(make([]byte, 32))),
+		}}
+		if rotated.Load() {
+			keys = append(keys, map[string]string{"kid": "new", "kty": "OKP", "crv": "Ed25519", "x": base64.RawURLEncoding.EncodeToString(newPublic)})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, server.Client(), time.Hour)
+	require.NoError(t, jwks.Refresh(context.Background()))
+	verifier := NewVerifier(jwks, VerifierConfig{Issuer: "auth", Audience: "frontend"})
+
+	_, err = verifier.Verify(context.Background(), signToken(t, AlgES256, "old", oldKey, validClaims()))
+	require.NoError(t, err)
+
+	// Refreshed within minRefresh, so an unknown kid is not fetched again.
+	_, err = verifier.Verify(context.Background(), signToken(t, AlgEdDSA, "new", newPrivate, validClaims()))
+	require.ErrorIs(t, err, ErrUnknownKey)
+	require.EqualValues(t, 1, fetches.Load())
+
+	rotated.Store(true)
+	require.NoError(t, jwks.Refresh(context.Background()))
+	_, err = verifier.Verify(context.Background(), signToken(t, AlgEdDSA, "new", newPrivate, validClaims()))
+	require.NoError(t, err)
+}
+
+func TestJWKS_RefreshesOnUnknownKey(t *testing.T) {
+	t.Parallel()
+
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1", "kty": "EC", "crv": "P-256",
+			"x": base64.RawURLEncoding.EncodeToString(signer.X.FillBytes(make([]byte, 32))),
+			"y": base64.RawURLEncoding.EncodeToString(signer.Y.FillBytes(make([]byte, 32))),
+		}}})
+	}))
+	defer server.Close()
+
+	verifier := NewVerifier(NewJWKS(server.URL, server.Client(), 0), VerifierConfig{})
+	_, err = verifier.Verify(context.Background(), signToken(t, AlgES256, "k1", signer, validClaims()))
+	require.NoError(t, err)
+	_, err = verifier.Verify(context.Background(), signToken(t, AlgES256, "k2", signer, validClaims()))
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":  "user-1",
+		"iss":  "auth",
+		"aud":  "frontend",
+		"role": "admin",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func signToken(t *testing.T, alg, kid string, signer crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(header) + "." + encode(claims)
+	if signer == nil {
+		return signed + "."
+	}
+	var signature []byte
+	switch key := signer.(type) {
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signed))
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		signature = append(fixed32(r), fixed32(s)...)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func fixed32(value *big.Int) []byte {
+	return value.FillBytes(make([]byte, 32))
+}
+
+func publicPEM(t *testing.T, public crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
@@ -3,6 +3,7 @@ package nats
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strings"
 
 	natsgo "github.com/nats-io/nats.go"
@@ -10,19 +11,23 @@ import (
 
 	repo "github.com/example/user-service/internal/adapters/postgres"
 	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+	"github.com/example/user-service/pkg/tracing"
 )
 
 type CreateUserHandler struct {
 	users    repo.UserRepository
 	profiles repo.UserProfileRepository
+	tx       repo.TxManager
+	outbox   repo.OutboxRepository
 }
 
-func NewCreateUserHandler(users repo.UserRepository, profiles repo.UserProfileRepository) *CreateUserHandler {
-	return &CreateUserHandler{users: users, profiles: profiles}
+func NewCreateUserHandler(users repo.UserRepository, profiles repo.UserProfileRepository, tx repo.TxManager, outbox repo.OutboxRepository) *CreateUserHandler {
+	return &CreateUserHandler{users: users, profiles: profiles, tx: tx, outbox: outbox}
 }
 
 // Handle processes user.create-user requests.
-func (h *CreateUserHandler) Handle(msg *natsgo.Msg) {
+func (h *CreateUserHandler) Handle(ctx context.Context, msg *natsgo.Msg) {
 	var req struct {
 		ID     string `json:"id"`
 		Email  string `json:"email"`
@@ -37,22 +42,30 @@ func (h *CreateUserHandler) Handle(msg *natsgo.Msg) {
 		Respond(msg, map[string]interface{}{"ok": false, "error": "id_required"})
 		return
 	}
-	ctx := context.Background()
 	if _, err := h.users.FindByID(ctx, req.ID); err == nil {
 		Respond(msg, map[string]interface{}{"ok": true})
 		return
-	} else if err != nil && err != gorm.ErrRecordNotFound {
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		Respond(msg, map[string]interface{}{"ok": false, "error": err.Error()})
 		return
 	}
 
-	user := &domain.User{ID: req.ID}
-	if err := user.SetStatus(domain.UserStatusNew); err != nil {
+	user, err := domain.NewUser(strings.TrimSpace(req.Email), domain.UserStatusNew)
+	if err != nil {
 		Respond(msg, map[string]interface{}{"ok": false, "error": err.Error()})
 		return
 	}
-	user.Email = strings.TrimSpace(req.Email)
-	if err := h.users.Create(ctx, user); err != nil {
+	user.ID = req.ID
+	err = h.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := h.users.Create(ctx, user); err != nil {
+			return err
+		}
+		if err := h.profiles.Create(ctx, &domain.UserProfile{UserID: user.ID}); err != nil {
+			return err
+		}
+		return h.outbox.Enqueue(ctx, events.NewUserEvent(events.UserCreated, user.ID, user.Email, tracing.TraceID(ctx)))
+	})
+	if err != nil {
 		// Retained mutating RPC must stay retry-safe. A concurrent duplicate
 		// create for the same user ID is treated as a successful idempotent
 		// replay instead of surfacing a false-negative error to the caller.
@@ -63,7 +76,5 @@ func (h *CreateUserHandler) Handle(msg *natsgo.Msg) {
 		Respond(msg, map[string]interface{}{"ok": false, "error": err.Error()})
 		return
 	}
-	profile := &domain.UserProfile{UserID: user.ID}
-	_ = h.profiles.Create(ctx, profile)
 	Respond(msg, map[string]interface{}{"ok": true})
 }
@@ -0,0 +1,184 @@
+package nats
+
+import (
+	"context"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+
+	repo "github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/domain"
+	pkglog "github.com/example/user-service/pkg/log"
+	"github.com/example/user-service/pkg/metrics"
+)
+
+const maxOutboxBackoff = 5 * time.Minute
+
+// Publisher is the part of *natsgo.Conn used by OutboxRelay.
+type Publisher interface {
+	PublishMsg(msg *natsgo.Msg) error
+	FlushTimeout(timeout time.Duration) error
+}
+
+type OutboxRelayConfig struct {
+	PollInterval    time.Duration
+	BatchSize       int
+	MaxAttempts     int
+	RetryBackoff    time.Duration
+	Retention       time.Duration
+	CleanupInterval time.Duration
+	FlushTimeout    time.Duration
+}
+
+// OutboxRelay publishes outbox rows over core NATS. A row is marked as
+// published once a flush confirms the server has read it; that is not a
+// delivery or persistence ack, so delivery is best-effort: messages without a
+// subscriber, or in flight when the server fails, are lost. Rows whose flush
+// fails are published again, so consumers may see duplicates and can drop
+// them by the row ID in the Nats-Msg-Id header.
+type OutboxRelay struct {
+	pub    Publisher
+	outbox repo.OutboxRepository
+	tx     repo.TxManager
+	cfg    OutboxRelayConfig
+	logger pkglog.Logger
+}
+
+func NewOutboxRelay(pub Publisher, outbox repo.OutboxRepository, tx repo.TxManager, cfg OutboxRelayConfig, logger pkglog.Logger) *OutboxRelay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = time.Hour
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = 2 * time.Second
+	}
+	return &OutboxRelay{pub: pub, outbox: outbox, tx: tx, cfg: cfg, logger: logger}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(r.cfg.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			for {
+				n, err := r.RelayOnce(ctx)
+				if err != nil {
+					r.logger.Error().Err(err).Msg("outbox relay failed")
+					break
+				}
+				if n < r.cfg.BatchSize {
+					break
+				}
+			}
+		case <-cleanup.C:
+			if _, err := r.Cleanup(ctx); err != nil {
+				r.logger.Error().Err(err).Msg("outbox cleanup failed")
+			}
+		}
+	}
+}
+
+// RelayOnce publishes one batch of due messages and returns how many rows
+// were picked up.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	var count int
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		msgs, err := r.outbox.LockPending(ctx, r.cfg.BatchSize, r.cfg.MaxAttempts)
+		if err != nil {
+			return err
+		}
+		count = len(msgs)
+
+		sent := make([]domain.OutboxMessage, 0, len(msgs))
+		for _, m := range msgs {
+			out := natsgo.NewMsg(m.Subject)
+			out.Header.Set(natsgo.MsgIdHdr, m.ID)
+			out.Data = m.Payload
+			if err := r.pub.PublishMsg(out); err != nil {
+				if err := r.fail(ctx, m, err); err != nil {
+					return err
+				}
+				continue
+			}
+			sent = append(sent, m)
+		}
+		if len(sent) == 0 {
+			return nil
+		}
+
+		if err := r.pub.FlushTimeout(r.cfg.FlushTimeout); err != nil {
+			for _, m := range sent {
+				if err := r.fail(ctx, m, err); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, m := range sent {
+			if err := r.outbox.MarkPublished(ctx, m.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Cleanup removes published rows older than the retention period. Rows that
+// ran out of attempts are dead letters: they are reported by the
+// dead_letters gauge and removed once they are older than the retention
+// period as well.
+func (r *OutboxRelay) Cleanup(ctx context.Context) (int64, error) {
+	if r.cfg.Retention <= 0 {
+		return 0, nil
+	}
+	before := time.Now().UTC().Add(-r.cfg.Retention)
+	deleted, err := r.outbox.DeletePublishedBefore(ctx, before)
+	if err != nil || r.cfg.MaxAttempts <= 0 {
+		return deleted, err
+	}
+	dead, err := r.outbox.DeleteDeadBefore(ctx, r.cfg.MaxAttempts, before)
+	if err != nil {
+		return deleted, err
+	}
+	if dead > 0 {
+		r.logger.Warn().Int64("count", dead).Msg("outbox dead letters purged")
+	}
+	pending, err := r.outbox.CountDead(ctx, r.cfg.MaxAttempts)
+	if err != nil {
+		return deleted + dead, err
+	}
+	metrics.SetDeadLetters("outbox", pending)
+	return deleted + dead, nil
+}
+
+func (r *OutboxRelay) fail(ctx context.Context, m domain.OutboxMessage, cause error) error {
+	r.logger.Warn().Err(cause).Str("outbox_id", m.ID).Str("subject", m.Subject).Int("attempts", m.Attempts+1).Msg("outbox publish failed")
+	return r.outbox.MarkFailed(ctx, m.ID, cause, time.Now().UTC().Add(r.backoff(m.Attempts+1)))
+}
+
+func (r *OutboxRelay) backoff(attempt int) time.Duration {
+	delay := r.cfg.RetryBackoff
+	for i := 1; i < attempt && delay < maxOutboxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxOutboxBackoff {
+		delay = maxOutboxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,79 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	natsgo "github.com/nats-io/nats.go"
+
+	repo "github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/events"
+)
+
+// UserEvicter drops cached authentication state of a user.
+type UserEvicter interface {
+	EvictUser(userID string) int
+}
+
+// revokingEvents are the user events after which a cached principal may no
+// longer be valid.
+var revokingEvents = map[string]bool{
+	events.UserStatusChanged: true,
+	events.UserRoleChanged:   true,
+	events.UserDeleted:       true,
+	events.UserPurged:        true,
+}
+
+// SubscribeRevocations evicts users named on revokeSubject (e.g. on logout,
+// payload {"user_id": "..."}) and users whose status or role changed
+// according to the user events under eventsPrefix. Plain subscriptions are
+// used so every instance clears its own cache.
+func SubscribeRevocations(conn *natsgo.Conn, revokeSubject, eventsPrefix string, cache UserEvicter) error {
+	if conn == nil {
+		return errors.New("nats connection is nil")
+	}
+	if _, err := conn.Subscribe(revokeSubject, func(msg *natsgo.Msg) {
+		var payload struct {
+			UserID string `json:"user_id"`
+		}
+		if err := json.Unmarshal(msg.Data, &payload); err == nil && payload.UserID != "" {
+			cache.EvictUser(payload.UserID)
+		}
+	}); err != nil {
+		return err
+	}
+	_, err := conn.Subscribe(eventsPrefix+".*", func(msg *natsgo.Msg) {
+		var event events.UserEvent
+		if err := json.Unmarshal(msg.Data, &event); err == nil && revokingEvents[event.Event] && event.UserID != "" {
+			cache.EvictUser(event.UserID)
+		}
+	})
+	return err
+}
+
+// EvictOnCommit wraps outbox so that a revoking user event also evicts the
+// user from cache once its transaction commits. The instance that made the
+// change then stops serving the cached principal right away instead of after
+// the relay has published the event; other instances still rely on
+// SubscribeRevocations.
+func EvictOnCommit(outbox repo.OutboxRepository, cache UserEvicter) repo.OutboxRepository {
+	return &evictingOutbox{OutboxRepository: outbox, cache: cache}
+}
+
+type evictingOutbox struct {
+	repo.OutboxRepository
+	cache UserEvicter
+}
+
+func (o *evictingOutbox) Enqueue(ctx context.Context, event events.UserEvent) error {
+	if err := o.OutboxRepository.Enqueue(ctx, event); err != nil {
+		return err
+	}
+	if revokingEvents[event.Event] && event.UserID != "" {
+		repo.AfterCommit(ctx, func(context.Context) {
+			o.cache.EvictUser(event.UserID)
+		})
+	}
+	return nil
+}
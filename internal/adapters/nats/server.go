@@ -1,10 +1,16 @@
 package nats
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	natsgo "github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/user-service/pkg/metrics"
+	"github.com/example/user-service/pkg/tracing"
 )
 
 // Server wraps NATS connection for RPC handlers.
@@ -12,12 +18,20 @@ type Server struct {
 	Conn *natsgo.Conn
 }
 
-// Subscribe registers a queue subscription.
-func (s Server) Subscribe(subject, queue string, handler func(msg *natsgo.Msg)) error {
+// Subscribe registers a queue subscription. Handler calls are counted and
+// timed per subject, and run in a server span continuing the trace context
+// of the message headers.
+func (s Server) Subscribe(subject, queue string, handler func(ctx context.Context, msg *natsgo.Msg)) error {
 	if s.Conn == nil {
 		return errors.New("nats connection is nil")
 	}
-	_, err := s.Conn.QueueSubscribe(subject, queue, handler)
+	_, err := s.Conn.QueueSubscribe(subject, queue, func(msg *natsgo.Msg) {
+		start := time.Now()
+		ctx, span := tracing.Tracer().Start(tracing.ExtractNATS(context.Background(), msg), subject, trace.WithSpanKind(trace.SpanKindServer))
+		handler(ctx, msg)
+		span.End()
+		metrics.ObserveNATS(subject, time.Since(start))
+	})
 	return err
 }
 
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+// auditChainLock is the advisory lock key that serialises appends, so every
+// entry is chained to the one committed before it.
+const auditChainLock = 0x61756469
+
+type AuditLogRepository interface {
+	Append(ctx context.Context, entry *domain.AuditEntry) error
+	List(ctx context.Context, query domain.AuditQuery) ([]domain.AuditEntry, int64, error)
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]domain.AuditEntry, error)
+}
+
+type gormAuditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &gormAuditLogRepository{db: db}
+}
+
+// Append seals the entry against the latest hash and inserts it. The lock
+// is held until the surrounding transaction ends.
+func (r *gormAuditLogRepository) Append(ctx context.Context, entry *domain.AuditEntry) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+			return err
+		}
+		var prevHash string
+		err := tx.Model(&domain.AuditEntry{}).Select("hash").Order("id DESC").Limit(1).Scan(&prevHash).Error
+		if err != nil {
+			return err
+		}
+		entry.Seal(prevHash, time.Now())
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *gormAuditLogRepository) List(ctx context.Context, q domain.AuditQuery) ([]domain.AuditEntry, int64, error) {
+	query := conn(ctx, r.db).Model(&domain.AuditEntry{})
+	if q.ActorID != "" {
+		query = query.Where("actor_id = ?", q.ActorID)
+	}
+	if q.TargetUserID != "" {
+		query = query.Where("target_user_id = ?", q.TargetUserID)
+	}
+	if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("created_at < ?", *q.To)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []domain.AuditEntry
+	if err := query.Order("id DESC").Offset(q.Offset).Limit(q.Limit).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, count, nil
+}
+
+// ListAfter returns entries with an id above afterID in chain order.
+func (r *gormAuditLogRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]domain.AuditEntry, error) {
+	var entries []domain.AuditEntry
+	if err := conn(ctx, r.db).Where("id > ?", afterID).Order("id").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
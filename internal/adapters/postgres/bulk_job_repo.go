@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+type BulkJobRepository interface {
+	Create(ctx context.Context, job *domain.BulkJob) error
+	Update(ctx context.Context, job *domain.BulkJob) error
+	UpdateItem(ctx context.Context, item *domain.BulkJobItem) error
+	FindByID(ctx context.Context, id string) (*domain.BulkJob, error)
+	LockRunnable(ctx context.Context, staleBefore time.Time, limit int) ([]domain.BulkJob, error)
+}
+
+type gormBulkJobRepository struct {
+	db *gorm.DB
+}
+
+func NewBulkJobRepository(db *gorm.DB) BulkJobRepository {
+	return &gormBulkJobRepository{db: db}
+}
+
+// Create inserts the job together with its items.
+func (r *gormBulkJobRepository) Create(ctx context.Context, job *domain.BulkJob) error {
+	return conn(ctx, r.db).Create(job).Error
+}
+
+// Update saves the job row only; items are written through UpdateItem.
+func (r *gormBulkJobRepository) Update(ctx context.Context, job *domain.BulkJob) error {
+	return conn(ctx, r.db).Omit(clause.Associations).Save(job).Error
+}
+
+func (r *gormBulkJobRepository) UpdateItem(ctx context.Context, item *domain.BulkJobItem) error {
+	return conn(ctx, r.db).Save(item).Error
+}
+
+func (r *gormBulkJobRepository) FindByID(ctx context.Context, id string) (*domain.BulkJob, error) {
+	var job domain.BulkJob
+	err := conn(ctx, r.db).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("user_id")
+	}).Where("id = ?", id).First(&job).Error
+	if err != nil {
+		return nil, notFound(err, "bulk job")
+	}
+	return &job, nil
+}
+
+// LockRunnable must run inside a transaction. It returns pending jobs plus
+// running jobs not touched since staleBefore, with their items.
+func (r *gormBulkJobRepository) LockRunnable(ctx context.Context, staleBefore time.Time, limit int) ([]domain.BulkJob, error) {
+	var jobs []domain.BulkJob
+	err := conn(ctx, r.db).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Preload("Items").
+		Where("status = ? OR (status = ? AND updated_at < ?)", domain.BulkJobPending, domain.BulkJobRunning, staleBefore).
+		Order("created_at").Limit(limit).Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
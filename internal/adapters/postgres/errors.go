@@ -0,0 +1,35 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+// notFound turns gorm's record-not-found into domain.NotFound(resource).
+// The result still matches gorm.ErrRecordNotFound with errors.Is.
+func notFound(err error, resource string) error {
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	e := domain.NotFound(resource)
+	e.Err = err
+	return e
+}
+
+// userEmailIndex keeps the emails of live users unique.
+const userEmailIndex = "idx_user_email_live_unique"
+
+// emailInUse turns a violation of the unique email index, e.g. from a
+// concurrent create, into domain.ErrEmailInUse.
+func emailInUse(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == userEmailIndex {
+		return fmt.Errorf("%w: %w", domain.ErrEmailInUse, err)
+	}
+	return err
+}
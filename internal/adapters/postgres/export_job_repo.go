@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+type ExportJobRepository interface {
+	Create(ctx context.Context, job *domain.ExportJob) error
+	Update(ctx context.Context, job *domain.ExportJob) error
+	FindByID(ctx context.Context, id string) (*domain.ExportJob, error)
+	FindActiveByUser(ctx context.Context, userID string) (*domain.ExportJob, error)
+	LockRunnable(ctx context.Context, staleBefore time.Time, limit int) ([]domain.ExportJob, error)
+}
+
+type gormExportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewExportJobRepository(db *gorm.DB) ExportJobRepository {
+	return &gormExportJobRepository{db: db}
+}
+
+func (r *gormExportJobRepository) Create(ctx context.Context, job *domain.ExportJob) error {
+	return conn(ctx, r.db).Create(job).Error
+}
+
+func (r *gormExportJobRepository) Update(ctx context.Context, job *domain.ExportJob) error {
+	return conn(ctx, r.db).Save(job).Error
+}
+
+func (r *gormExportJobRepository) FindByID(ctx context.Context, id string) (*domain.ExportJob, error) {
+	var job domain.ExportJob
+	if err := conn(ctx, r.db).Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, notFound(err, "export")
+	}
+	return &job, nil
+}
+
+func (r *gormExportJobRepository) FindActiveByUser(ctx context.Context, userID string) (*domain.ExportJob, error) {
+	var job domain.ExportJob
+	err := conn(ctx, r.db).
+		Where("user_id = ? AND status IN ?", userID, []domain.ExportStatus{domain.ExportStatusPending, domain.ExportStatusRunning}).
+		Order("created_at DESC").First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// LockRunnable must run inside a transaction. It returns pending jobs plus
+// running jobs not touched since staleBefore, which belong to a crashed worker.
+func (r *gormExportJobRepository) LockRunnable(ctx context.Context, staleBefore time.Time, limit int) ([]domain.ExportJob, error) {
+	var jobs []domain.ExportJob
+	err := conn(ctx, r.db).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? OR (status = ? AND updated_at < ?)", domain.ExportStatusPending, domain.ExportStatusRunning, staleBefore).
+		Order("created_at").Limit(limit).Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+type IdempotencyRepository interface {
+	// Reserve stores record unless an unexpired record with the same
+	// principal and key exists; that record is returned instead, and nil
+	// when record was stored.
+	Reserve(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error)
+	Complete(ctx context.Context, id string, status int, headers domain.JSONMap, body []byte, expiresAt time.Time) error
+	// Release deletes a reservation so the key can be retried.
+	Release(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type gormIdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &gormIdempotencyRepository{db: db}
+}
+
+func (r *gormIdempotencyRepository) Reserve(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	var existing *domain.IdempotencyRecord
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("principal = ? AND key = ? AND expires_at <= ?", record.Principal, record.Key, time.Now().UTC()).
+			Delete(&domain.IdempotencyRecord{}).Error; err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil || result.RowsAffected == 1 {
+			return result.Error
+		}
+		existing = &domain.IdempotencyRecord{}
+		return tx.Where("principal = ? AND key = ?", record.Principal, record.Key).Take(existing).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (r *gormIdempotencyRepository) Complete(ctx context.Context, id string, status int, headers domain.JSONMap, body []byte, expiresAt time.Time) error {
+	return conn(ctx, r.db).Model(&domain.IdempotencyRecord{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"response_status":  status,
+		"response_headers": headers,
+		"response_body":    body,
+		"expires_at":       expiresAt,
+	}).Error
+}
+
+func (r *gormIdempotencyRepository) Release(ctx context.Context, id string) error {
+	return conn(ctx, r.db).Where("id = ?", id).Delete(&domain.IdempotencyRecord{}).Error
+}
+
+func (r *gormIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("expires_at < ?", before).Delete(&domain.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/pkg/metrics"
+	"github.com/example/user-service/pkg/tracing"
+)
+
+const (
+	queryStartKey = "instrument:query_start"
+	querySpanKey  = "instrument:query_span"
+)
+
+// RegisterInstrumentation times every statement run through db and wraps it
+// in a client span under the span of the calling context. Record-not-found
+// is an expected lookup outcome and counts as success.
+func RegisterInstrumentation(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, op := range []struct {
+		name  string
+		start func(name string, fn func(*gorm.DB)) error
+		end   func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	} {
+		if err := op.start("instrument:before_"+op.name, startQuery(op.name)); err != nil {
+			return err
+		}
+		if err := op.end("instrument:after_"+op.name, endQuery(op.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(queryStartKey, time.Now())
+		if db.Statement.Context == nil {
+			return
+		}
+		_, span := tracing.Tracer().Start(db.Statement.Context, "db."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+		))
+		db.InstanceSet(querySpanKey, span)
+	}
+}
+
+func endQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "none"
+		}
+		if value, ok := db.InstanceGet(querySpanKey); ok {
+			span := value.(trace.Span)
+			span.SetName("db." + operation + " " + table)
+			span.SetAttributes(semconv.DBCollectionName(table), semconv.DBQueryText(db.Statement.SQL.String()))
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+		if value, ok := db.InstanceGet(queryStartKey); ok {
+			metrics.ObserveQuery(operation, table, err, time.Since(value.(time.Time)))
+		}
+	}
+}
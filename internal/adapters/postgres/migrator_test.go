@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/migrations"
+)
+
+func TestLoadMigrationsOrdersAndPairsFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_b.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"0002_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"0001_a.down.sql": {Data: []byte("")},
+		"README.md":       {Data: []byte("ignored")},
+	}
+	got, err := loadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, Migration{Version: 1, Name: "a", Up: "CREATE TABLE a ();"}, got[0])
+	require.Equal(t, int64(2), got[1].Version)
+	require.Equal(t, "DROP TABLE b;", got[1].Down)
+}
+
+func TestLoadMigrationsRejectsBrokenSets(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {"0001_a.up.sql": {}},
+		"two names":    {"0001_a.up.sql": {}, "0001_b.down.sql": {}},
+		"duplicate":    {"0001_a.up.sql": {}, "1_a.up.sql": {}, "0001_a.down.sql": {}},
+	}
+	for name, fsys := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadMigrations(fsys)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	got, err := loadMigrations(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, got)
+	for i, m := range got {
+		require.Equal(t, int64(i+1), m.Version, "migration versions must be contiguous")
+	}
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+)
+
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, event events.UserEvent) error
+	LockPending(ctx context.Context, limit, maxAttempts int) ([]domain.OutboxMessage, error)
+	MarkPublished(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string, cause error, nextAttemptAt time.Time) error
+	DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error)
+	CountDead(ctx context.Context, maxAttempts int) (int64, error)
+	DeleteDeadBefore(ctx context.Context, maxAttempts int, before time.Time) (int64, error)
+}
+
+type gormOutboxRepository struct {
+	db            *gorm.DB
+	subjectPrefix string
+}
+
+func NewOutboxRepository(db *gorm.DB, subjectPrefix string) OutboxRepository {
+	return &gormOutboxRepository{db: db, subjectPrefix: subjectPrefix}
+}
+
+func (r *gormOutboxRepository) Enqueue(ctx context.Context, event events.UserEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	msg := &domain.OutboxMessage{
+		Subject:       event.Subject(r.subjectPrefix),
+		Payload:       payload,
+		NextAttemptAt: time.Now().UTC(),
+	}
+	return conn(ctx, r.db).Create(msg).Error
+}
+
+// LockPending must run inside a transaction: the selected rows stay locked
+// until it commits so concurrent relays skip them.
+func (r *gormOutboxRepository) LockPending(ctx context.Context, limit, maxAttempts int) ([]domain.OutboxMessage, error) {
+	var msgs []domain.OutboxMessage
+	query := conn(ctx, r.db).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("published_at IS NULL AND next_attempt_at <= ?", time.Now().UTC())
+	if maxAttempts > 0 {
+		query = query.Where("attempts < ?", maxAttempts)
+	}
+	if err := query.Order("created_at").Limit(limit).Find(&msgs).Error; err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (r *gormOutboxRepository) MarkPublished(ctx context.Context, id string) error {
+	return conn(ctx, r.db).Model(&domain.OutboxMessage{}).Where("id = ?", id).
+		Update("published_at", time.Now().UTC()).Error
+}
+
+func (r *gormOutboxRepository) MarkFailed(ctx context.Context, id string, cause error, nextAttemptAt time.Time) error {
+	return conn(ctx, r.db).Model(&domain.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      cause.Error(),
+		"next_attempt_at": nextAttemptAt,
+	}).Error
+}
+
+func (r *gormOutboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("published_at IS NOT NULL AND published_at < ?", before).Delete(&domain.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
+
+// CountDead counts the unpublished rows that ran out of attempts.
+func (r *gormOutboxRepository) CountDead(ctx context.Context, maxAttempts int) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&domain.OutboxMessage{}).
+		Where("published_at IS NULL AND attempts >= ?", maxAttempts).Count(&count).Error
+	return count, err
+}
+
+func (r *gormOutboxRepository) DeleteDeadBefore(ctx context.Context, maxAttempts int, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("published_at IS NULL AND attempts >= ? AND created_at < ?", maxAttempts, before).Delete(&domain.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+type RoleAssignmentRepository interface {
+	// Enqueue stores a pending role for the user, replacing any role still
+	// pending for it, so only the latest one is assigned.
+	Enqueue(ctx context.Context, userID, role string, notBefore time.Time) (*domain.RoleAssignment, error)
+	// Claim leases up to limit due assignments by moving their next attempt to
+	// leaseUntil, so RBAC can be called without holding a transaction. Only
+	// the latest assignment of each user is claimed.
+	Claim(ctx context.Context, limit, maxAttempts int, leaseUntil time.Time) ([]domain.RoleAssignment, error)
+	// ClaimOne leases the assignment if it is still pending and the latest
+	// one of its user.
+	ClaimOne(ctx context.Context, id string, leaseUntil time.Time) (bool, error)
+	// MarkAssigned reports false if the assignment was replaced meanwhile.
+	MarkAssigned(ctx context.Context, id string) (bool, error)
+	MarkFailed(ctx context.Context, id string, cause error, nextAttemptAt time.Time) error
+	// RequeueLatest makes the latest assignment of the user due again, so it
+	// is sent after one it overtook.
+	RequeueLatest(ctx context.Context, userID string) error
+	CountDead(ctx context.Context, maxAttempts int) (int64, error)
+}
+
+type gormRoleAssignmentRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleAssignmentRepository(db *gorm.DB) RoleAssignmentRepository {
+	return &gormRoleAssignmentRepository{db: db}
+}
+
+func (r *gormRoleAssignmentRepository) Enqueue(ctx context.Context, userID, role string, notBefore time.Time) (*domain.RoleAssignment, error) {
+	db := conn(ctx, r.db)
+	if err := db.Where("user_id = ? AND assigned_at IS NULL", userID).Delete(&domain.RoleAssignment{}).Error; err != nil {
+		return nil, err
+	}
+	assignment := &domain.RoleAssignment{UserID: userID, Role: role, NextAttemptAt: notBefore}
+	if err := db.Create(assignment).Error; err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// latestRoleAssignment matches rows no newer assignment of the same user
+// replaced.
+const latestRoleAssignment = "NOT EXISTS (SELECT 1 FROM role_assignment AS newer WHERE newer.user_id = role_assignment.user_id AND newer.created_at > role_assignment.created_at)"
+
+func (r *gormRoleAssignmentRepository) Claim(ctx context.Context, limit, maxAttempts int, leaseUntil time.Time) ([]domain.RoleAssignment, error) {
+	db := conn(ctx, r.db)
+	due := db.Model(&domain.RoleAssignment{}).Select("id").
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("assigned_at IS NULL AND next_attempt_at <= ?", time.Now().UTC()).
+		Where(latestRoleAssignment)
+	if maxAttempts > 0 {
+		due = due.Where("attempts < ?", maxAttempts)
+	}
+	due = due.Order("created_at").Limit(limit)
+
+	var assignments []domain.RoleAssignment
+	err := db.Model(&assignments).Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Update("next_attempt_at", leaseUntil).Error
+	if err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r *gormRoleAssignmentRepository) ClaimOne(ctx context.Context, id string, leaseUntil time.Time) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.RoleAssignment{}).
+		Where("id = ? AND assigned_at IS NULL", id).
+		Where(latestRoleAssignment).
+		Update("next_attempt_at", leaseUntil)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormRoleAssignmentRepository) MarkAssigned(ctx context.Context, id string) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.RoleAssignment{}).Where("id = ? AND assigned_at IS NULL", id).
+		Update("assigned_at", time.Now().UTC())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormRoleAssignmentRepository) MarkFailed(ctx context.Context, id string, cause error, nextAttemptAt time.Time) error {
+	return conn(ctx, r.db).Model(&domain.RoleAssignment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      cause.Error(),
+		"next_attempt_at": nextAttemptAt,
+	}).Error
+}
+
+func (r *gormRoleAssignmentRepository) RequeueLatest(ctx context.Context, userID string) error {
+	db := conn(ctx, r.db)
+	latest := db.Model(&domain.RoleAssignment{}).Select("id").Where("user_id = ?", userID).Order("created_at DESC").Limit(1)
+	return db.Model(&domain.RoleAssignment{}).Where("id = (?)", latest).Updates(map[string]interface{}{
+		"assigned_at":     nil,
+		"next_attempt_at": time.Now().UTC(),
+	}).Error
+}
+
+// CountDead counts the pending assignments that ran out of attempts.
+func (r *gormRoleAssignmentRepository) CountDead(ctx context.Context, maxAttempts int) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&domain.RoleAssignment{}).
+		Where("assigned_at IS NULL AND attempts >= ?", maxAttempts).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+type ServiceClientRepository interface {
+	Create(ctx context.Context, client *domain.ServiceClient) error
+	Update(ctx context.Context, client *domain.ServiceClient) error
+	FindByID(ctx context.Context, id string) (*domain.ServiceClient, error)
+	FindByKeyPrefix(ctx context.Context, prefix string) (*domain.ServiceClient, error)
+	List(ctx context.Context) ([]domain.ServiceClient, error)
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
+
+type gormServiceClientRepository struct {
+	db *gorm.DB
+}
+
+func NewServiceClientRepository(db *gorm.DB) ServiceClientRepository {
+	return &gormServiceClientRepository{db: db}
+}
+
+func (r *gormServiceClientRepository) Create(ctx context.Context, client *domain.ServiceClient) error {
+	return conn(ctx, r.db).Create(client).Error
+}
+
+func (r *gormServiceClientRepository) Update(ctx context.Context, client *domain.ServiceClient) error {
+	return conn(ctx, r.db).Save(client).Error
+}
+
+func (r *gormServiceClientRepository) FindByID(ctx context.Context, id string) (*domain.ServiceClient, error) {
+	var client domain.ServiceClient
+	if err := conn(ctx, r.db).Where("id = ?", id).First(&client).Error; err != nil {
+		return nil, notFound(err, "service client")
+	}
+	return &client, nil
+}
+
+func (r *gormServiceClientRepository) FindByKeyPrefix(ctx context.Context, prefix string) (*domain.ServiceClient, error) {
+	var client domain.ServiceClient
+	if err := conn(ctx, r.db).Where("key_prefix = ?", prefix).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *gormServiceClientRepository) List(ctx context.Context) ([]domain.ServiceClient, error) {
+	var clients []domain.ServiceClient
+	if err := conn(ctx, r.db).Order("created_at DESC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// TouchLastUsed only moves last_used_at forward, without bumping updated_at.
+func (r *gormServiceClientRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	return conn(ctx, r.db).Model(&domain.ServiceClient{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at).
+		UpdateColumn("last_used_at", at).Error
+}
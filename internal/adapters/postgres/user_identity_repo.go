@@ -14,6 +14,7 @@ type UserIdentityRepository interface {
 	FindByUserAndProvider(ctx context.Context, userID string, provider domain.IdentityProvider) (*domain.UserIdentity, error)
 	ListByUser(ctx context.Context, userID string) ([]domain.UserIdentity, error)
 	Delete(ctx context.Context, identity *domain.UserIdentity) error
+	DeleteByUser(ctx context.Context, userID string) error
 }
 
 type gormUserIdentityRepository struct {
@@ -25,33 +26,37 @@ func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
 }
 
 func (r *gormUserIdentityRepository) Create(ctx context.Context, identity *domain.UserIdentity) error {
-	return r.db.WithContext(ctx).Create(identity).Error
+	return conn(ctx, r.db).Create(identity).Error
 }
 
 func (r *gormUserIdentityRepository) FindByProviderUserID(ctx context.Context, provider domain.IdentityProvider, providerUserID string) (*domain.UserIdentity, error) {
 	var identity domain.UserIdentity
-	if err := r.db.WithContext(ctx).Where("provider = ? AND provider_user_id = ?", provider, providerUserID).First(&identity).Error; err != nil {
-		return nil, err
+	if err := conn(ctx, r.db).Where("provider = ? AND provider_user_id = ?", provider, providerUserID).First(&identity).Error; err != nil {
+		return nil, notFound(err, "identity")
 	}
 	return &identity, nil
 }
 
 func (r *gormUserIdentityRepository) FindByUserAndProvider(ctx context.Context, userID string, provider domain.IdentityProvider) (*domain.UserIdentity, error) {
 	var identity domain.UserIdentity
-	if err := r.db.WithContext(ctx).Where("user_id = ? AND provider = ?", userID, provider).First(&identity).Error; err != nil {
-		return nil, err
+	if err := conn(ctx, r.db).Where("user_id = ? AND provider = ?", userID, provider).First(&identity).Error; err != nil {
+		return nil, notFound(err, "identity")
 	}
 	return &identity, nil
 }
 
 func (r *gormUserIdentityRepository) ListByUser(ctx context.Context, userID string) ([]domain.UserIdentity, error) {
 	var identities []domain.UserIdentity
-	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&identities).Error; err != nil {
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Order("created_at DESC").Find(&identities).Error; err != nil {
 		return nil, err
 	}
 	return identities, nil
 }
 
 func (r *gormUserIdentityRepository) Delete(ctx context.Context, identity *domain.UserIdentity) error {
-	return r.db.WithContext(ctx).Delete(identity).Error
+	return conn(ctx, r.db).Delete(identity).Error
+}
+
+func (r *gormUserIdentityRepository) DeleteByUser(ctx context.Context, userID string) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&domain.UserIdentity{}).Error
 }
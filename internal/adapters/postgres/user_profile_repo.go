@@ -23,17 +23,18 @@ func NewUserProfileRepository(db *gorm.DB) UserProfileRepository {
 }
 
 func (r *gormUserProfileRepository) Create(ctx context.Context, profile *domain.UserProfile) error {
-	return r.db.WithContext(ctx).Create(profile).Error
+	return conn(ctx, r.db).Create(profile).Error
 }
 
+// Update is conditional on the profile version like UserRepository.Update.
 func (r *gormUserProfileRepository) Update(ctx context.Context, profile *domain.UserProfile) error {
-	return r.db.WithContext(ctx).Save(profile).Error
+	return updateVersioned(conn(ctx, r.db), profile, &profile.Version)
 }
 
 func (r *gormUserProfileRepository) FindByUserID(ctx context.Context, userID string) (*domain.UserProfile, error) {
 	var profile domain.UserProfile
-	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		return nil, err
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		return nil, notFound(err, "profile")
 	}
 	return &profile, nil
 }
@@ -12,6 +12,7 @@ type UserProviderRepository interface {
 	Create(ctx context.Context, provider *domain.UserProvider) error
 	Update(ctx context.Context, provider *domain.UserProvider) error
 	Delete(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userID string) error
 	FindByProvider(ctx context.Context, providerType, providerUserID string) (*domain.UserProvider, error)
 	FindByUserID(ctx context.Context, userID string) ([]domain.UserProvider, error)
 }
@@ -25,20 +26,20 @@ func NewUserProviderRepository(db *gorm.DB) UserProviderRepository {
 }
 
 func (r *gormUserProviderRepository) Create(ctx context.Context, provider *domain.UserProvider) error {
-	return r.db.WithContext(ctx).Create(provider).Error
+	return conn(ctx, r.db).Create(provider).Error
 }
 
 func (r *gormUserProviderRepository) Update(ctx context.Context, provider *domain.UserProvider) error {
-	return r.db.WithContext(ctx).Save(provider).Error
+	return conn(ctx, r.db).Save(provider).Error
 }
 
 func (r *gormUserProviderRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Delete(&domain.UserProvider{}, "id = ?", id).Error
+	return conn(ctx, r.db).Delete(&domain.UserProvider{}, "id = ?", id).Error
 }
 
 func (r *gormUserProviderRepository) FindByProvider(ctx context.Context, providerType, providerUserID string) (*domain.UserProvider, error) {
 	var provider domain.UserProvider
-	if err := r.db.WithContext(ctx).Where("provider_type = ? AND provider_user_id = ?", providerType, providerUserID).First(&provider).Error; err != nil {
+	if err := conn(ctx, r.db).Where("provider_type = ? AND provider_user_id = ?", providerType, providerUserID).First(&provider).Error; err != nil {
 		return nil, err
 	}
 	return &provider, nil
@@ -46,8 +47,12 @@ func (r *gormUserProviderRepository) FindByProvider(ctx context.Context, provide
 
 func (r *gormUserProviderRepository) FindByUserID(ctx context.Context, userID string) ([]domain.UserProvider, error) {
 	var providers []domain.UserProvider
-	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&providers).Error; err != nil {
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Find(&providers).Error; err != nil {
 		return nil, err
 	}
 	return providers, nil
 }
+
+func (r *gormUserProviderRepository) DeleteByUser(ctx context.Context, userID string) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&domain.UserProvider{}).Error
+}
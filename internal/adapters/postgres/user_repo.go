@@ -2,6 +2,9 @@ package repo
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -13,8 +16,14 @@ type UserRepository interface {
 	Update(ctx context.Context, user *domain.User) error
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	FindByID(ctx context.Context, id string) (*domain.User, error)
+	FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error)
 	Delete(ctx context.Context, id string) error
-	List(ctx context.Context, offset, limit int) ([]domain.User, int64, error)
+	List(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error)
+	ListByCursor(ctx context.Context, query domain.UserQuery) ([]domain.User, error)
+	Count(ctx context.Context, query domain.UserQuery) (int64, error)
+	Stream(ctx context.Context, query domain.UserQuery, batchSize int, fn func([]domain.User) error) error
+	ListExpiredSuspensions(ctx context.Context, now time.Time, limit int, exclude []string) ([]domain.User, error)
+	ListPurgeable(ctx context.Context, now time.Time, limit int) ([]domain.User, error)
 }
 
 type gormUserRepository struct {
@@ -26,42 +35,225 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 func (r *gormUserRepository) Create(ctx context.Context, user *domain.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return emailInUse(conn(ctx, r.db).Create(user).Error)
 }
 
+// Update writes user only if its version is still the one it was read with,
+// and bumps the version. Otherwise it returns domain.ErrVersionConflict.
 func (r *gormUserRepository) Update(ctx context.Context, user *domain.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	return emailInUse(updateVersioned(conn(ctx, r.db), user, &user.Version))
 }
 
 func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.WithContext(ctx).Preload("Profile").Where("email = ?", email).First(&user).Error; err != nil {
-		return nil, err
+	if err := r.live(ctx).Preload("Profile").Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, notFound(err, "user")
 	}
 	return &user, nil
 }
 
 func (r *gormUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.WithContext(ctx).Preload("Profile").Where("id = ?", id).First(&user).Error; err != nil {
-		return nil, err
+	if err := r.live(ctx).Preload("Profile").Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, notFound(err, "user")
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error) {
+	var user domain.User
+	if err := conn(ctx, r.db).Preload("Profile").Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, notFound(err, "user")
 	}
 	return &user, nil
 }
 
 func (r *gormUserRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Delete(&domain.User{}, "id = ?", id).Error
+	return conn(ctx, r.db).Delete(&domain.User{}, "id = ?", id).Error
 }
 
-func (r *gormUserRepository) List(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
+func (r *gormUserRepository) List(ctx context.Context, q domain.UserQuery) ([]domain.User, int64, error) {
 	var users []domain.User
 	var count int64
-	query := r.db.WithContext(ctx).Model(&domain.User{})
+	query := applyUserFilters(r.live(ctx).Model(&domain.User{}), q)
 	if err := query.Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
-	if err := query.Preload("Profile").Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error; err != nil {
+	if err := query.Preload("Profile").Offset(q.Offset).Limit(q.Limit).Order(userOrder(q)).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 	return users, count, nil
 }
+
+// ListByCursor returns up to query.Limit users after query.Cursor in keyset
+// order on (created_at, id). Backward cursors walk the list in reverse, so
+// rows come back in reverse display order.
+func (r *gormUserRepository) ListByCursor(ctx context.Context, q domain.UserQuery) ([]domain.User, error) {
+	desc := q.SortDesc
+	if q.Cursor != nil && q.Cursor.Backward {
+		desc = !desc
+	}
+	query := applyUserFilters(r.live(ctx).Model(&domain.User{}), q)
+	if q.Cursor != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		query = query.Where(`("user".created_at, "user".id) `+op+` (?, ?)`, q.Cursor.CreatedAt, q.Cursor.ID)
+	}
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	var users []domain.User
+	err := query.Preload("Profile").
+		Order(`"user".created_at ` + direction + `, "user".id ` + direction).
+		Limit(q.Limit).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Count returns the number of users matching the query. Estimated counts
+// read the planner statistics instead of scanning, which only applies to
+// unfiltered queries.
+func (r *gormUserRepository) Count(ctx context.Context, q domain.UserQuery) (int64, error) {
+	if q.Count == domain.UserCountEstimated && !q.HasFilters() {
+		var estimate int64
+		err := conn(ctx, r.db).Raw(`SELECT reltuples::bigint FROM pg_class WHERE oid = '"user"'::regclass`).Scan(&estimate).Error
+		if err != nil {
+			return 0, err
+		}
+		// reltuples is -1 until the table has been analysed.
+		if estimate >= 0 {
+			return estimate, nil
+		}
+	}
+	var count int64
+	if err := applyUserFilters(r.live(ctx).Model(&domain.User{}), q).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Stream passes every user matching the query to fn in batches of up to
+// batchSize, in list order. Rows are read through a server-side cursor so the
+// result set is never loaded at once; profiles are attached per batch.
+func (r *gormUserRepository) Stream(ctx context.Context, q domain.UserQuery, batchSize int, fn func([]domain.User) error) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		stmt := applyUserFilters(tx.Session(&gorm.Session{DryRun: true}).Model(&domain.User{}).Where(`"user".deleted_at IS NULL`), q).
+			Order(userOrder(q)).Find(&[]domain.User{}).Statement
+		if err := tx.Exec("DECLARE user_stream NO SCROLL CURSOR FOR "+stmt.SQL.String(), stmt.Vars...).Error; err != nil {
+			return err
+		}
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM user_stream", batchSize)
+		for {
+			var users []domain.User
+			if err := tx.Raw(fetch).Scan(&users).Error; err != nil {
+				return err
+			}
+			if len(users) == 0 {
+				return nil
+			}
+			if err := attachProfiles(tx, users); err != nil {
+				return err
+			}
+			if err := fn(users); err != nil {
+				return err
+			}
+			if len(users) < batchSize {
+				return nil
+			}
+		}
+	})
+}
+
+func attachProfiles(db *gorm.DB, users []domain.User) error {
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	var profiles []domain.UserProfile
+	if err := db.Where("user_id IN ?", ids).Find(&profiles).Error; err != nil {
+		return err
+	}
+	byUser := make(map[string]*domain.UserProfile, len(profiles))
+	for idx := range profiles {
+		byUser[profiles[idx].UserID] = &profiles[idx]
+	}
+	for idx := range users {
+		users[idx].Profile = byUser[users[idx].ID]
+	}
+	return nil
+}
+
+func applyUserFilters(db *gorm.DB, q domain.UserQuery) *gorm.DB {
+	if len(q.Statuses) > 0 {
+		db = db.Where(`"user".status IN ?`, q.Statuses)
+	}
+	if q.IsActive != nil {
+		db = db.Where(`"user".is_active = ?`, *q.IsActive)
+	}
+	if q.CreatedFrom != nil {
+		db = db.Where(`"user".created_at >= ?`, *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		db = db.Where(`"user".created_at < ?`, *q.CreatedTo)
+	}
+	if q.Provider != "" {
+		db = db.Where(`EXISTS (SELECT 1 FROM user_identity ui WHERE ui.user_id = "user".id AND ui.provider = ?)`, q.Provider)
+	}
+	if search := strings.TrimSpace(q.Search); search != "" {
+		// Substring matches are served by the pg_trgm indexes on email and
+		// display_name.
+		pattern := "%" + likeEscaper.Replace(search) + "%"
+		db = db.Where(`("user".email ILIKE ? OR EXISTS (SELECT 1 FROM user_profile up WHERE up.user_id = "user".id AND up.display_name ILIKE ?))`, pattern, pattern)
+	}
+	return db
+}
+
+// userOrder builds the ORDER BY clause from a validated sort field; id is
+// appended so pages are stable when sort values tie.
+func userOrder(q domain.UserQuery) string {
+	field := q.SortBy
+	if !field.IsValid() {
+		field = domain.UserSortCreatedAt
+	}
+	direction := "ASC"
+	if q.SortDesc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf(`"user".%s %s, "user".id %s`, field, direction, direction)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *gormUserRepository) ListExpiredSuspensions(ctx context.Context, now time.Time, limit int, exclude []string) ([]domain.User, error) {
+	var users []domain.User
+	q := r.live(ctx).Where("status = ? AND suspended_until <= ?", domain.UserStatusSuspended, now)
+	if len(exclude) > 0 {
+		q = q.Where("id NOT IN ?", exclude)
+	}
+	err := q.Order("suspended_until").Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUserRepository) ListPurgeable(ctx context.Context, now time.Time, limit int) ([]domain.User, error) {
+	var users []domain.User
+	err := conn(ctx, r.db).Preload("Profile").
+		Where("deleted_at IS NOT NULL AND purged_at IS NULL AND purge_after <= ?", now).
+		Order("purge_after").Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// live scopes queries to users that are not soft-deleted.
+func (r *gormUserRepository) live(ctx context.Context) *gorm.DB {
+	return conn(ctx, r.db).Where(`"user".deleted_at IS NULL`)
+}
@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+type UserStatusHistoryRepository interface {
+	Create(ctx context.Context, entry *domain.UserStatusHistory) error
+	ListByUser(ctx context.Context, userID string) ([]domain.UserStatusHistory, error)
+}
+
+type gormUserStatusHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewUserStatusHistoryRepository(db *gorm.DB) UserStatusHistoryRepository {
+	return &gormUserStatusHistoryRepository{db: db}
+}
+
+func (r *gormUserStatusHistoryRepository) Create(ctx context.Context, entry *domain.UserStatusHistory) error {
+	return conn(ctx, r.db).Create(entry).Error
+}
+
+func (r *gormUserStatusHistoryRepository) ListByUser(ctx context.Context, userID string) ([]domain.UserStatusHistory, error) {
+	var entries []domain.UserStatusHistory
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,24 @@
+package repo
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/user-service/internal/domain"
+)
+
+// updateVersioned saves all columns of model where its version column still
+// holds *version, incrementing it. Associations are not saved.
+func updateVersioned(db *gorm.DB, model interface{}, version *int64) error {
+	expected := *version
+	*version = expected + 1
+	result := db.Model(model).Select("*").Omit(clause.Associations, "created_at").
+		Where("version = ?", expected).Updates(model)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = domain.ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = expected
+	}
+	return result.Error
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{PerMinute: 60, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "user:1", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, i, result.Remaining)
+	}
+	result, err := store.Take(ctx, "user:1", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.Reset)
+
+	// Other keys have their own bucket.
+	result, err = store.Take(ctx, "user:2", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// One token a second is refilled, never beyond the burst.
+	now = now.Add(1500 * time.Millisecond)
+	result, err = store.Take(ctx, "user:1", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	now = now.Add(time.Minute)
+	result, err = store.Take(ctx, "user:1", limit)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryStore_PrunesIdleBuckets(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{PerMinute: 10}
+
+	_, _ = store.Take(context.Background(), "ip:10.0.0.1", limit)
+	now = now.Add(idleBucketTTL)
+	_, _ = store.Take(context.Background(), "ip:10.0.0.2", limit)
+	require.Len(t, store.buckets, 1)
+	require.Contains(t, store.buckets, "ip:10.0.0.2")
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type rateLimitBucket struct {
+	Key       string `gorm:"primaryKey"`
+	Tokens    float64
+	UpdatedAt time.Time `gorm:"autoUpdateTime:false"`
+}
+
+func (rateLimitBucket) TableName() string {
+	return "rate_limit_bucket"
+}
+
+// PostgresStore shares buckets between replicas through the
+// rate_limit_bucket table. Each take locks its row for the duration of a
+// short transaction.
+type PostgresStore struct {
+	db  *gorm.DB
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db, now: time.Now}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.now().UTC()
+	var result Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row := rateLimitBucket{Key: key, Tokens: limit.capacity(), UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).Take(&row).Error; err != nil {
+			return err
+		}
+		b := bucket{tokens: row.Tokens, updatedAt: row.UpdatedAt}
+		result = b.take(limit, now)
+		return tx.Model(&rateLimitBucket{}).Where("key = ?", key).
+			Updates(map[string]interface{}{"tokens": b.tokens, "updated_at": b.updatedAt}).Error
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	s.prune(ctx, now)
+	return result, nil
+}
+
+// prune drops idle buckets at most once per idleBucketTTL per replica.
+// Failures are ignored; the next prune retries.
+func (s *PostgresStore) prune(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	due := now.Sub(s.lastPrune) >= idleBucketTTL
+	if due {
+		s.lastPrune = now
+	}
+	s.mu.Unlock()
+	if due {
+		s.db.WithContext(ctx).Where("updated_at < ?", now.Add(-idleBucketTTL)).Delete(&rateLimitBucket{})
+	}
+}
@@ -0,0 +1,79 @@
+// Package ratelimit implements token buckets with in-memory and Postgres
+// storage.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// idleBucketTTL is how long an untouched bucket is kept. Buckets of the
+// configured limits refill well within it, so dropping them loses nothing.
+const idleBucketTTL = time.Hour
+
+// Limit is a token bucket holding up to Burst tokens and refilling PerMinute
+// tokens a minute. Burst defaults to PerMinute.
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.PerMinute)
+}
+
+func (l Limit) perSecond() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available; zero when
+	// the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store takes a token from the bucket named key, creating a full bucket
+// when there is none.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refills b up to now and removes one token if there is one.
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	capacity := limit.capacity()
+	rate := limit.perSecond()
+	if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	}
+	b.updatedAt = now
+
+	result := Result{Limit: int(capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = secondsToDuration((capacity - b.tokens) / rate)
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
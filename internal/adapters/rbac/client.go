@@ -11,6 +11,10 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/metrics"
+	"github.com/example/user-service/pkg/tracing"
 )
 
 type Client interface {
@@ -39,7 +43,7 @@ type cachingClient struct {
 }
 
 func NewHTTPClient(baseURL string, timeout time.Duration) Client {
-	return &httpClient{baseURL: baseURL, client: &http.Client{Timeout: timeout}}
+	return &httpClient{baseURL: baseURL, client: &http.Client{Timeout: timeout, Transport: tracing.Transport("rbac", nil)}}
 }
 
 func NewCachingClient(delegate Client, ttl time.Duration) Client {
@@ -121,7 +125,13 @@ func (c *httpClient) get(ctx context.Context, path string, params url.Values, ou
 	bo.InitialInterval = 200 * time.Millisecond
 	bo.MaxElapsedTime = 3 * time.Second
 
-	return backoff.Retry(op, backoff.WithContext(bo, ctx))
+	start := time.Now()
+	err := backoff.RetryNotify(op, backoff.WithContext(bo, ctx), metrics.RetryNotifier("rbac", path))
+	metrics.ObserveClient("rbac", path, err, time.Since(start))
+	if err != nil {
+		return domain.Unavailable("rbac", err)
+	}
+	return nil
 }
 
 func (c *httpClient) sendJSON(ctx context.Context, method, path string, payload interface{}) error {
@@ -150,7 +160,13 @@ func (c *httpClient) sendJSON(ctx context.Context, method, path string, payload
 	bo.InitialInterval = 200 * time.Millisecond
 	bo.MaxElapsedTime = 3 * time.Second
 
-	return backoff.Retry(op, backoff.WithContext(bo, ctx))
+	start := time.Now()
+	err := backoff.RetryNotify(op, backoff.WithContext(bo, ctx), metrics.RetryNotifier("rbac", path))
+	metrics.ObserveClient("rbac", path, err, time.Since(start))
+	if err != nil {
+		return domain.Unavailable("rbac", err)
+	}
+	return nil
 }
 
 func (c *cachingClient) cacheKey(parts ...string) string {
@@ -162,9 +178,11 @@ func (c *cachingClient) withCache(key string, loader func() (interface{}, error)
 	c.mu.RLock()
 	if entry, ok := c.cache[key]; ok && now.Before(entry.expiresAt) {
 		c.mu.RUnlock()
+		metrics.RBACCacheHit()
 		return entry.value, nil
 	}
 	c.mu.RUnlock()
+	metrics.RBACCacheMiss()
 
 	value, err := loader()
 	if err != nil {
@@ -172,7 +190,9 @@ func (c *cachingClient) withCache(key string, loader func() (interface{}, error)
 	}
 	c.mu.Lock()
 	c.cache[key] = cacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
+	entries := len(c.cache)
 	c.mu.Unlock()
+	metrics.SetRBACCacheEntries(entries)
 	return value, nil
 }
 
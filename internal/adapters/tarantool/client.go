@@ -9,6 +9,10 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/metrics"
+	"github.com/example/user-service/pkg/tracing"
 )
 
 type Client interface {
@@ -35,7 +39,7 @@ type response struct {
 func NewHTTPClient(baseURL string, timeout time.Duration) Client {
 	return &httpClient{
 		baseURL: baseURL,
-		client:  &http.Client{Timeout: timeout},
+		client:  &http.Client{Timeout: timeout, Transport: tracing.Transport("tarantool", nil)},
 	}
 }
 
@@ -111,5 +115,11 @@ func (c *httpClient) postWithRetry(ctx context.Context, path string, payload int
 	bo.InitialInterval = 200 * time.Millisecond
 	bo.MaxElapsedTime = 3 * time.Second
 
-	return backoff.Retry(op, backoff.WithContext(bo, ctx))
+	start := time.Now()
+	err := backoff.RetryNotify(op, backoff.WithContext(bo, ctx), metrics.RetryNotifier("tarantool", path))
+	metrics.ObserveClient("tarantool", path, err, time.Since(start))
+	if err != nil {
+		return domain.Unavailable("tarantool", err)
+	}
+	return nil
 }
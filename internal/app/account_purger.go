@@ -0,0 +1,43 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/user-service/internal/usecase"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// accountPurger periodically anonymises deleted accounts whose grace period
+// has ended.
+type accountPurger struct {
+	service  service.AccountDeletionService
+	interval time.Duration
+	logger   pkglog.Logger
+}
+
+func newAccountPurger(svc service.AccountDeletionService, interval time.Duration, logger pkglog.Logger) *accountPurger {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &accountPurger{service: svc, interval: interval, logger: logger}
+}
+
+func (p *accountPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := p.service.PurgeExpired(ctx, time.Now().UTC())
+			if err != nil {
+				p.logger.Error().Err(err).Msg("account purge failed")
+			}
+			if count > 0 {
+				p.logger.Info().Int("count", count).Msg("deleted accounts purged")
+			}
+		}
+	}
+}
@@ -21,34 +21,64 @@ import (
 	apiv1 "github.com/example/user-service/internal/adapters/http/api/v1"
 	mw "github.com/example/user-service/internal/adapters/http/middleware"
 	"github.com/example/user-service/internal/adapters/imageprocessor"
+	"github.com/example/user-service/internal/adapters/jwt"
 	natsadapter "github.com/example/user-service/internal/adapters/nats"
 	repo "github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/adapters/ratelimit"
 	rbacclient "github.com/example/user-service/internal/adapters/rbac"
 	"github.com/example/user-service/internal/usecase"
+	"github.com/example/user-service/pkg/cursor"
+	"github.com/example/user-service/pkg/health"
 	pkglog "github.com/example/user-service/pkg/log"
+	"github.com/example/user-service/pkg/tracing"
 )
 
 type App struct {
-	cfg      *config.Config
-	logger   pkglog.Logger
-	db       *gorm.DB
-	echo     *echo.Echo
-	natsConn *nats.Conn
+	cfg         *config.Config
+	logger      pkglog.Logger
+	db          *gorm.DB
+	echo        *echo.Echo
+	natsConn    *nats.Conn
+	relay       *natsadapter.OutboxRelay
+	sweeper     *suspensionSweeper
+	purger      *accountPurger
+	exporter    *exportWorker
+	bulk        *bulkWorker
+	roles       *roleAssignmentWorker
+	idempotency *idempotencySweeper
+	jwks        *jwksRefresher
+	tracing     func(context.Context) error
+	health      *health.Registry
 }
 
 func New(ctx context.Context) (*App, error) {
 	cfg := config.MustLoad()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 	logger := pkglog.New(cfg.AppEnv)
 
-	db, err := gorm.Open(postgres.Open(buildDSN(cfg)), &gorm.Config{
-		Logger: loggerForGorm(cfg),
-		NamingStrategy: schema.NamingStrategy{
-			SingularTable: true,
-		},
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: cfg.AppName,
+		Environment: cfg.AppEnv,
+		Exporter:    cfg.TracesExporter,
+		Endpoint:    cfg.OTLPEndpoint,
+		SampleRatio: cfg.TracesSampleRatio,
 	})
 	if err != nil {
+		return nil, fmt.Errorf("set up tracing: %w", err)
+	}
+
+	db, err := OpenDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateOnStart(ctx, cfg, db, logger); err != nil {
 		return nil, err
 	}
+	if err := repo.RegisterInstrumentation(db); err != nil {
+		return nil, fmt.Errorf("instrument database: %w", err)
+	}
 
 	filestorageClient := filestorage.NewHTTPClient(cfg.FileStorageURL, 5*time.Second)
 	rbacHTTP := rbacclient.NewHTTPClient(cfg.RBACURL, 3*time.Second)
@@ -65,55 +95,162 @@ func New(ctx context.Context) (*App, error) {
 
 	userRepo := repo.NewUserRepository(db)
 	profileRepo := repo.NewUserProfileRepository(db)
-	_ = repo.NewUserProviderRepository(db)
+	providerRepo := repo.NewUserProviderRepository(db)
 	identityRepo := repo.NewUserIdentityRepository(db)
-	userService := service.NewUserService(userRepo, profileRepo, identityRepo)
-	manageService := service.NewUserManageService(userRepo, profileRepo, rbacClient)
+	outboxRepo := repo.NewOutboxRepository(db, cfg.NATSUserEvents)
+	// Cached principals are only safe while revocations reach every
+	// instance, so the cache needs NATS. Changes made here evict locally on
+	// commit as well, without waiting for the relay.
+	var authCache *mw.PrincipalCache
+	switch {
+	case cfg.AuthCacheSize <= 0:
+	case natsConn == nil:
+		logger.Warn().Msg("auth cache disabled: revocations need NATS")
+	default:
+		authCache = mw.NewPrincipalCache(cfg.AuthCacheSize, cfg.AuthCacheTTL)
+		if err := natsadapter.SubscribeRevocations(natsConn, cfg.NATSAuthRevoke, cfg.NATSUserEvents, authCache); err != nil {
+			return nil, fmt.Errorf("subscribe auth revocations: %w", err)
+		}
+		outboxRepo = natsadapter.EvictOnCommit(outboxRepo, authCache)
+	}
+	txManager := repo.NewTxManager(db)
+	roleAssignmentRepo := repo.NewRoleAssignmentRepository(db)
+	userService := service.NewUserService(userRepo, profileRepo, identityRepo, txManager, outboxRepo)
+	statusHistoryRepo := repo.NewUserStatusHistoryRepository(db)
+	auditRepo := repo.NewAuditLogRepository(db)
+	manageService := service.NewUserManageService(userRepo, profileRepo, statusHistoryRepo, rbacClient, txManager, outboxRepo, auditRepo, roleAssignmentRepo)
+	deletionService := service.NewAccountDeletionService(userRepo, profileRepo, identityRepo, providerRepo, statusHistoryRepo, txManager, outboxRepo, auditRepo, cfg.AccountDeletionGrace)
+	exportService := service.NewDataExportService(repo.NewExportJobRepository(db), userRepo, identityRepo, providerRepo, statusHistoryRepo, filestorageClient, txManager, service.DataExportConfig{
+		FileKind: cfg.ExportFileKind,
+		URLTTL:   cfg.ExportURLTTL,
+	})
 
 	var imageProcClient imageprocessor.Client
 	if cfg.ImageProcessorURL != "" {
 		imageProcClient = imageprocessor.NewHTTPClient(cfg.ImageProcessorURL, 10*time.Second)
 	}
 
-	apiHandler := apiv1.NewHandler(userService, filestorageClient, imageProcClient, cfg.AvatarPresetGroup, cfg.AvatarFileKind)
-	adminHandler := adminv1.NewHandler(manageService, filestorageClient)
+	bulkService := service.NewBulkService(repo.NewBulkJobRepository(db), userRepo, manageService, deletionService, txManager)
+	importService := service.NewUserImportService(userRepo, profileRepo, statusHistoryRepo, rbacClient, txManager, outboxRepo, auditRepo, roleAssignmentRepo)
+
+	apiHandler := apiv1.NewHandler(userService, deletionService, exportService, filestorageClient, imageProcClient, cfg.AvatarPresetGroup, cfg.AvatarFileKind)
+	cursors, err := cursor.NewSigner([]byte(cfg.CursorSigningKey))
+	if err != nil {
+		return nil, err
+	}
+	adminHandler := adminv1.NewHandler(manageService, deletionService, bulkService, importService, filestorageClient, cursors)
+
+	auditHandler := adminv1.NewAuditHandler(service.NewAuditService(auditRepo))
+	clientService := service.NewServiceClientService(repo.NewServiceClientRepository(db))
+	clientHandler := adminv1.NewServiceClientHandler(clientService)
 
 	authMW := mw.NewAuthMiddleware(cfg, logger, rbacClient, userRepo, natsConn)
+	var jwks *jwksRefresher
+	switch cfg.AuthTokenVerifier {
+	case "", "nats":
+	case "local":
+		keys, refresher, err := localKeySet(ctx, cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		jwks = refresher
+		verifier := jwt.NewVerifier(keys, jwt.VerifierConfig{Issuer: cfg.JWTIssuer, Audience: cfg.JWTAudience, ClockSkew: cfg.JWTClockSkew})
+		var fallback mw.TokenVerifier
+		if natsConn != nil {
+			fallback = mw.NATSTokenVerifier(natsConn, cfg.NATSAuthVerify)
+		}
+		authMW = mw.NewAuthMiddlewareWithVerifier(cfg, logger, rbacClient, userRepo, natsConn, mw.LocalTokenVerifier(verifier, fallback))
+	default:
+		return nil, fmt.Errorf("unknown AUTH_TOKEN_VERIFIER %q", cfg.AuthTokenVerifier)
+	}
+	if authCache != nil {
+		authMW.WithPrincipalCache(authCache)
+	}
 	rbacMW := mw.NewRBACMiddleware(rbacClient)
+	apiKeyMW := mw.NewAPIKeyMiddleware(clientService, logger)
+
+	var rateLimitStore ratelimit.Store
+	switch cfg.RateLimitStore {
+	case "", "memory":
+		rateLimitStore = ratelimit.NewMemoryStore()
+	case "postgres":
+		rateLimitStore = ratelimit.NewPostgresStore(db)
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", cfg.RateLimitStore)
+	}
+	rateLimiter := mw.NewRateLimiter(rateLimitStore, logger)
+	idempotencyRepo := repo.NewIdempotencyRepository(db)
+	// Imports stream their upload and report, so they are not buffered for
+	// replay.
+	idempotencyMW := mw.NewIdempotencyMiddleware(idempotencyRepo, cfg.IdempotencyTTL, logger).SkipRoutes("/admin/v1/users/import")
 
+	readiness := newReadiness(cfg, db, natsConn)
 	e := echo.New()
-	router := httpadapter.NewRouter(cfg, apiHandler, adminHandler, authMW, rbacMW)
+	router := httpadapter.NewRouter(cfg, apiHandler, adminHandler, auditHandler, clientHandler, authMW, rbacMW, apiKeyMW, rateLimiter, idempotencyMW, readiness, mw.AccessLog(logger, cfg.AccessLogSampleSuccess))
 	router.Setup(e)
 
+	var relay *natsadapter.OutboxRelay
 	if natsConn != nil {
 		rpc := natsadapter.Server{Conn: natsConn}
-		createHandler := natsadapter.NewCreateUserHandler(userRepo, profileRepo)
+		createHandler := natsadapter.NewCreateUserHandler(userRepo, profileRepo, txManager, outboxRepo)
 		_ = rpc.Subscribe(cfg.NATSUserCreate, "ms-go-user", createHandler.Handle)
+
+		relay = natsadapter.NewOutboxRelay(natsConn, outboxRepo, txManager, natsadapter.OutboxRelayConfig{
+			PollInterval: cfg.OutboxPollInterval,
+			BatchSize:    cfg.OutboxBatchSize,
+			MaxAttempts:  cfg.OutboxMaxAttempts,
+			RetryBackoff: cfg.OutboxRetryBackoff,
+			Retention:    cfg.OutboxRetention,
+		}, logger)
 	}
 
-	return &App{cfg: cfg, logger: logger, db: db, echo: e, natsConn: natsConn}, nil
+	sweeper := newSuspensionSweeper(manageService, cfg.SuspensionSweepInterval, logger)
+	purger := newAccountPurger(deletionService, cfg.AccountPurgeInterval, logger)
+	exporter := newExportWorker(exportService, cfg.ExportPollInterval, logger)
+	bulk := newBulkWorker(bulkService, cfg.BulkPollInterval, logger)
+	roles := newRoleAssignmentWorker(service.NewRoleAssignmentService(roleAssignmentRepo, rbacClient, userRepo, outboxRepo, service.RoleAssignmentConfig{
+		MaxAttempts:  cfg.RoleAssignmentMaxAttempts,
+		RetryBackoff: cfg.RoleAssignmentRetryBackoff,
+	}), cfg.RoleAssignmentPollInterval, logger)
+	idempotency := newIdempotencySweeper(idempotencyRepo, cfg.IdempotencySweepInterval, logger)
+
+	return &App{cfg: cfg, logger: logger, db: db, echo: e, natsConn: natsConn, relay: relay, sweeper: sweeper, purger: purger, exporter: exporter, bulk: bulk, roles: roles, idempotency: idempotency, jwks: jwks, tracing: shutdownTracing, health: readiness}, nil
 }
 
 func (a *App) Run(ctx context.Context) error {
 	errCh := make(chan error, 1)
-	go func() {
-		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		_ = a.echo.Shutdown(shutdownCtx)
-	}()
+	if a.relay != nil {
+		go a.relay.Run(ctx)
+	}
+	go a.sweeper.Run(ctx)
+	go a.purger.Run(ctx)
+	go a.exporter.Run(ctx)
+	go a.bulk.Run(ctx)
+	go a.roles.Run(ctx)
+	go a.idempotency.Run(ctx)
+	if a.jwks != nil {
+		go a.jwks.Run(ctx)
+	}
 	go func() {
 		errCh <- a.echo.Start(":" + a.cfg.AppPort)
 	}()
 	select {
 	case <-ctx.Done():
-		return nil
 	case err := <-errCh:
 		if errors.Is(err, http.ErrServerClosed) {
 			return nil
 		}
 		return err
 	}
+
+	// Fail readiness first and keep serving for the drain delay, so load
+	// balancers stop sending traffic before the listener closes.
+	a.health.SetShuttingDown()
+	a.logger.Info().Dur("drain_delay", a.cfg.ShutdownDrainDelay).Msg("shutting down")
+	time.Sleep(a.cfg.ShutdownDrainDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return a.echo.Shutdown(shutdownCtx)
 }
 
 func (a *App) Close() {
@@ -125,6 +262,44 @@ func (a *App) Close() {
 			_ = sqlDB.Close()
 		}
 	}
+	if a.tracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = a.tracing(ctx)
+	}
+}
+
+// localKeySet returns the keys for local token verification: the JWKS
+// document when JWT_JWKS_URL is set, otherwise JWT_PUBLIC_KEY. A JWKS that
+// cannot be fetched at startup is not fatal; unknown keys fall back to the
+// auth service until a refresh succeeds.
+func localKeySet(ctx context.Context, cfg *config.Config, logger pkglog.Logger) (jwt.KeySet, *jwksRefresher, error) {
+	if cfg.JWTJWKSURL != "" {
+		keys := jwt.NewJWKS(cfg.JWTJWKSURL, &http.Client{Timeout: 5 * time.Second}, 30*time.Second)
+		if err := keys.Refresh(ctx); err != nil {
+			logger.Warn().Err(err).Msg("initial jwks fetch failed")
+		}
+		return keys, newJWKSRefresher(keys, cfg.JWTJWKSRefreshInterval, logger), nil
+	}
+	if cfg.JWTPublicKey == "" {
+		return nil, nil, errors.New("local token verification needs JWT_JWKS_URL or JWT_PUBLIC_KEY")
+	}
+	key, err := jwt.ParsePublicKeyPEM(cfg.JWTPublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("JWT_PUBLIC_KEY: %w", err)
+	}
+	return jwt.NewStaticKeySet(key), nil, nil
+}
+
+// OpenDB connects to the service database with the naming and logging
+// settings the repositories expect.
+func OpenDB(cfg *config.Config) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(buildDSN(cfg)), &gorm.Config{
+		Logger: loggerForGorm(cfg),
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+		},
+	})
 }
 
 func buildDSN(cfg *config.Config) string {
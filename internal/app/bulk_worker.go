@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/user-service/internal/usecase"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// bulkWorker periodically applies queued bulk admin jobs.
+type bulkWorker struct {
+	service  service.BulkService
+	interval time.Duration
+	logger   pkglog.Logger
+}
+
+func newBulkWorker(svc service.BulkService, interval time.Duration, logger pkglog.Logger) *bulkWorker {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	return &bulkWorker{service: svc, interval: interval, logger: logger}
+}
+
+func (w *bulkWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := w.service.ProcessPending(ctx)
+			if err != nil {
+				w.logger.Error().Err(err).Msg("bulk job processing failed")
+			}
+			if count > 0 {
+				w.logger.Info().Int("count", count).Msg("bulk jobs processed")
+			}
+		}
+	}
+}
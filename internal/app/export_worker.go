@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/user-service/internal/usecase"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// exportWorker periodically builds queued personal-data exports.
+type exportWorker struct {
+	service  service.DataExportService
+	interval time.Duration
+	logger   pkglog.Logger
+}
+
+func newExportWorker(svc service.DataExportService, interval time.Duration, logger pkglog.Logger) *exportWorker {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &exportWorker{service: svc, interval: interval, logger: logger}
+}
+
+func (w *exportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := w.service.ProcessPending(ctx)
+			if err != nil {
+				w.logger.Error().Err(err).Msg("export processing failed")
+			}
+			if count > 0 {
+				w.logger.Info().Int("count", count).Msg("user exports processed")
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	repo "github.com/example/user-service/internal/adapters/postgres"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// idempotencySweeper periodically deletes expired idempotency keys.
+type idempotencySweeper struct {
+	records  repo.IdempotencyRepository
+	interval time.Duration
+	logger   pkglog.Logger
+}
+
+func newIdempotencySweeper(records repo.IdempotencyRepository, interval time.Duration, logger pkglog.Logger) *idempotencySweeper {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &idempotencySweeper{records: records, interval: interval, logger: logger}
+}
+
+func (s *idempotencySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.records.DeleteExpired(ctx, time.Now().UTC())
+			if err != nil {
+				s.logger.Error().Err(err).Msg("idempotency key sweep failed")
+			}
+			if count > 0 {
+				s.logger.Info().Int64("count", count).Msg("expired idempotency keys deleted")
+			}
+		}
+	}
+}
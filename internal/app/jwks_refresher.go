@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/user-service/internal/adapters/jwt"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// jwksRefresher periodically reloads the JWKS document so rotated keys are
+// picked up before tokens signed with them arrive.
+type jwksRefresher struct {
+	keys     *jwt.JWKS
+	interval time.Duration
+	logger   pkglog.Logger
+}
+
+func newJWKSRefresher(keys *jwt.JWKS, interval time.Duration, logger pkglog.Logger) *jwksRefresher {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &jwksRefresher{keys: keys, interval: interval, logger: logger}
+}
+
+func (r *jwksRefresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.keys.Refresh(ctx); err != nil {
+				r.logger.Error().Err(err).Msg("jwks refresh failed")
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/config"
+	repo "github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/migrations"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// NewMigrator returns a migrator over the SQL files embedded in the binary.
+func NewMigrator(db *gorm.DB) (*repo.Migrator, error) {
+	return repo.NewMigrator(db, migrations.FS)
+}
+
+// migrateOnStart applies pending migrations when DB_MIGRATE_ON_START is set
+// and refuses to start on a schema that is still behind the binary.
+func migrateOnStart(ctx context.Context, cfg *config.Config, db *gorm.DB, logger pkglog.Logger) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	if cfg.DBMigrateOnStart {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate database: %w", err)
+		}
+		for _, m := range applied {
+			logger.Info().Int64("version", m.Version).Str("name", m.Name).Msg("migration applied")
+		}
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("check database schema: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind: %d pending migrations starting at %d_%s; run `user-service migrate up`", len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/nats-io/nats.go"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/config"
+	"github.com/example/user-service/pkg/health"
+)
+
+// newReadiness registers the dependency checks of /internal/ready. The
+// database and NATS are critical; the HTTP services only degrade features
+// and are optional.
+func newReadiness(cfg *config.Config, db *gorm.DB, natsConn *nats.Conn) *health.Registry {
+	checks := health.NewRegistry(cfg.ReadinessCacheTTL)
+	checks.Register(health.Check{Name: "postgres", Level: health.LevelCritical, Timeout: cfg.ReadinessCheckTimeout, Run: func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}})
+	if natsConn != nil {
+		checks.Register(health.Check{Name: "nats", Level: health.LevelCritical, Timeout: cfg.ReadinessCheckTimeout, Run: func(context.Context) error {
+			if status := natsConn.Status(); status != nats.CONNECTED {
+				return errors.New("connection " + status.String())
+			}
+			return nil
+		}})
+	}
+
+	client := &http.Client{Timeout: cfg.ReadinessCheckTimeout}
+	for _, dep := range []struct{ name, url string }{
+		{"rbac", cfg.RBACURL},
+		{"filestorage", cfg.FileStorageURL},
+		{"imageprocessor", cfg.ImageProcessorURL},
+	} {
+		if dep.url == "" {
+			continue
+		}
+		checks.Register(health.Check{Name: dep.name, Level: health.LevelOptional, Timeout: cfg.ReadinessCheckTimeout, Run: health.HTTPReachable(client, dep.url)})
+	}
+	return checks
+}
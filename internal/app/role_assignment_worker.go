@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/user-service/internal/usecase"
+	pkglog "github.com/example/user-service/pkg/log"
+	"github.com/example/user-service/pkg/metrics"
+)
+
+// roleAssignmentWorker periodically retries RBAC role assignments that
+// failed after their transaction committed.
+type roleAssignmentWorker struct {
+	service  service.RoleAssignmentService
+	interval time.Duration
+	logger   pkglog.Logger
+	dead     int64
+}
+
+func newRoleAssignmentWorker(svc service.RoleAssignmentService, interval time.Duration, logger pkglog.Logger) *roleAssignmentWorker {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &roleAssignmentWorker{service: svc, interval: interval, logger: logger}
+}
+
+func (w *roleAssignmentWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := w.service.ProcessPending(ctx)
+			if err != nil {
+				w.logger.Error().Err(err).Msg("role assignment retry failed")
+			}
+			if count > 0 {
+				w.logger.Info().Int("count", count).Msg("role assignments retried")
+			}
+			w.reportDead(ctx)
+		}
+	}
+}
+
+// reportDead exports the assignments that ran out of attempts and warns when
+// their number grows; they need an admin to assign the role again.
+func (w *roleAssignmentWorker) reportDead(ctx context.Context) {
+	dead, err := w.service.CountDead(ctx)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("count dead role assignments failed")
+		return
+	}
+	metrics.SetDeadLetters("role_assignment", dead)
+	if dead > w.dead {
+		w.logger.Warn().Int64("count", dead).Msg("role assignments ran out of attempts")
+	}
+	w.dead = dead
+}
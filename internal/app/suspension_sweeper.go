@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/user-service/internal/usecase"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// suspensionSweeper periodically reactivates users whose suspension elapsed.
+type suspensionSweeper struct {
+	service  service.UserManageService
+	interval time.Duration
+	logger   pkglog.Logger
+}
+
+func newSuspensionSweeper(svc service.UserManageService, interval time.Duration, logger pkglog.Logger) *suspensionSweeper {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &suspensionSweeper{service: svc, interval: interval, logger: logger}
+}
+
+func (s *suspensionSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.service.ReactivateExpiredSuspensions(ctx, time.Now().UTC())
+			if err != nil {
+				s.logger.Error().Err(err).Msg("suspension sweep failed")
+			}
+			if count > 0 {
+				s.logger.Info().Int("count", count).Msg("expired suspensions lifted")
+			}
+		}
+	}
+}
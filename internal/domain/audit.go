@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditUserCreate         AuditAction = "user.create"
+	AuditUserUpdate         AuditAction = "user.update"
+	AuditUserStatusChange   AuditAction = "user.status_change"
+	AuditUserSuspend        AuditAction = "user.suspend"
+	AuditUserSuspensionLift AuditAction = "user.suspension_lift"
+	AuditUserRoleChange     AuditAction = "user.role_change"
+	AuditUserDelete         AuditAction = "user.delete"
+	AuditUserRestore        AuditAction = "user.restore"
+)
+
+var ErrAuditChainBroken = errors.New("audit chain broken")
+
+func (a AuditAction) IsValid() bool {
+	switch a {
+	case AuditUserCreate, AuditUserUpdate, AuditUserStatusChange, AuditUserSuspend, AuditUserSuspensionLift, AuditUserRoleChange,
+		AuditUserDelete, AuditUserRestore:
+		return true
+	}
+	return false
+}
+
+// AuditActor identifies who performed an audited change and from where.
+type AuditActor struct {
+	ID        string
+	RequestID string
+	IP        string
+	UserAgent string
+}
+
+// AuditChange is the before and after value of one changed field.
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry is a row of the append-only admin audit log. Each entry stores
+// the hash of its predecessor and a hash over its own content, so editing or
+// removing a row breaks the chain from that point on.
+type AuditEntry struct {
+	ID           int64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	ActorID      *string         `gorm:"column:actor_id" json:"actor_id,omitempty"`
+	TargetUserID *string         `gorm:"column:target_user_id;type:uuid" json:"target_user_id,omitempty"`
+	Action       AuditAction     `gorm:"column:action;type:text;not null" json:"action"`
+	Changes      json.RawMessage `gorm:"column:changes;type:json;not null" json:"changes"`
+	RequestID    *string         `gorm:"column:request_id" json:"request_id,omitempty"`
+	IP           *string         `gorm:"column:ip" json:"ip,omitempty"`
+	UserAgent    *string         `gorm:"column:user_agent" json:"user_agent,omitempty"`
+	CreatedAt    time.Time       `gorm:"column:created_at;not null" json:"created_at"`
+	PrevHash     string          `gorm:"column:prev_hash;not null" json:"prev_hash"`
+	Hash         string          `gorm:"column:hash;not null" json:"hash"`
+}
+
+func (AuditEntry) TableName() string {
+	return "admin_audit_log"
+}
+
+// Seal links the entry to its predecessor's hash and computes its own.
+// CreatedAt is truncated to the database precision so the hash can be
+// recomputed from the stored row.
+func (e *AuditEntry) Seal(prevHash string, now time.Time) {
+	e.CreatedAt = now.UTC().Truncate(time.Microsecond)
+	e.PrevHash = prevHash
+	e.Hash = e.ComputeHash()
+}
+
+// ComputeHash returns the hex SHA-256 over the entry content and PrevHash.
+func (e *AuditEntry) ComputeHash() string {
+	content, _ := json.Marshal([]interface{}{
+		e.PrevHash,
+		stringValue(e.ActorID),
+		stringValue(e.TargetUserID),
+		e.Action,
+		string(e.Changes),
+		stringValue(e.RequestID),
+		stringValue(e.IP),
+		stringValue(e.UserAgent),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAfter checks that the entry follows prevHash and was not altered.
+func (e *AuditEntry) VerifyAfter(prevHash string) error {
+	if e.PrevHash != prevHash {
+		return fmt.Errorf("%w at entry %d: previous hash mismatch", ErrAuditChainBroken, e.ID)
+	}
+	if e.Hash != e.ComputeHash() {
+		return fmt.Errorf("%w at entry %d: content hash mismatch", ErrAuditChainBroken, e.ID)
+	}
+	return nil
+}
+
+// AuditQuery filters the audit log. Results are newest first.
+type AuditQuery struct {
+	ActorID      string
+	TargetUserID string
+	Action       AuditAction
+	From         *time.Time
+	To           *time.Time
+	Offset       int
+	Limit        int
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAuditEntryChain(t *testing.T) {
+	t.Parallel()
+
+	actor := "admin-1"
+	now := time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	first := AuditEntry{ID: 1, ActorID: &actor, Action: AuditUserCreate, Changes: json.RawMessage(`{"email":{"before":null,"after":"a@example.com"}}`)}
+	first.Seal("", now)
+	second := AuditEntry{ID: 2, ActorID: &actor, Action: AuditUserUpdate, Changes: json.RawMessage(`{"display_name":{"before":"A","after":"B"}}`)}
+	second.Seal(first.Hash, now.Add(time.Second))
+
+	if first.CreatedAt.Nanosecond()%1000 != 0 {
+		t.Fatalf("expected CreatedAt truncated to microseconds, got %v", first.CreatedAt)
+	}
+	if err := first.VerifyAfter(""); err != nil {
+		t.Fatalf("first entry: %v", err)
+	}
+	if err := second.VerifyAfter(first.Hash); err != nil {
+		t.Fatalf("second entry: %v", err)
+	}
+
+	tampered := second
+	tampered.Changes = json.RawMessage(`{"display_name":{"before":"A","after":"C"}}`)
+	if err := tampered.VerifyAfter(first.Hash); !errors.Is(err, ErrAuditChainBroken) {
+		t.Fatalf("expected ErrAuditChainBroken for altered content, got %v", err)
+	}
+	if err := second.VerifyAfter(""); !errors.Is(err, ErrAuditChainBroken) {
+		t.Fatalf("expected ErrAuditChainBroken for removed predecessor, got %v", err)
+	}
+}
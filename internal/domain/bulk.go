@@ -0,0 +1,121 @@
+package domain
+
+import "time"
+
+type BulkAction string
+
+const (
+	BulkActionChangeStatus BulkAction = "change_status"
+	BulkActionAssignRole   BulkAction = "assign_role"
+	BulkActionDelete       BulkAction = "delete"
+)
+
+func (a BulkAction) IsValid() bool {
+	return a == BulkActionChangeStatus || a == BulkActionAssignRole || a == BulkActionDelete
+}
+
+type BulkJobStatus string
+
+const (
+	BulkJobPending         BulkJobStatus = "PENDING"
+	BulkJobRunning         BulkJobStatus = "RUNNING"
+	BulkJobCompleted       BulkJobStatus = "COMPLETED"
+	BulkJobPartiallyFailed BulkJobStatus = "PARTIALLY_FAILED"
+	BulkJobFailed          BulkJobStatus = "FAILED"
+)
+
+type BulkItemStatus string
+
+const (
+	BulkItemPending   BulkItemStatus = "PENDING"
+	BulkItemSucceeded BulkItemStatus = "SUCCEEDED"
+	BulkItemFailed    BulkItemStatus = "FAILED"
+)
+
+// BulkJob is an admin operation applied to many users. Each target user has
+// its own BulkJobItem carrying the per-user outcome.
+type BulkJob struct {
+	ID           string        `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	Action       BulkAction    `gorm:"column:action;type:text;not null" json:"action"`
+	Status       BulkJobStatus `gorm:"column:status;type:text;not null" json:"status"`
+	TargetStatus *UserStatus   `gorm:"column:target_status;type:text" json:"target_status,omitempty"`
+	Role         *string       `gorm:"column:role" json:"role,omitempty"`
+	Reason       *string       `gorm:"column:reason" json:"reason,omitempty"`
+	DryRun       bool          `gorm:"column:dry_run;not null" json:"dry_run"`
+	Total        int           `gorm:"column:total;not null" json:"total"`
+	Succeeded    int           `gorm:"column:succeeded;not null" json:"succeeded"`
+	Failed       int           `gorm:"column:failed;not null" json:"failed"`
+	CreatedBy    *string       `gorm:"column:created_by;type:uuid" json:"created_by,omitempty"`
+	CreatedAt    time.Time     `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time     `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	CompletedAt  *time.Time    `gorm:"column:completed_at" json:"completed_at,omitempty"`
+	Items        []BulkJobItem `gorm:"foreignKey:JobID" json:"items,omitempty"`
+}
+
+func (BulkJob) TableName() string {
+	return "user_bulk_job"
+}
+
+type BulkJobItem struct {
+	ID        string         `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"-"`
+	JobID     string         `gorm:"type:uuid;not null;index" json:"-"`
+	UserID    string         `gorm:"type:text;not null" json:"user_id"`
+	Status    BulkItemStatus `gorm:"column:status;type:text;not null" json:"status"`
+	Error     *string        `gorm:"column:error" json:"error,omitempty"`
+	UpdatedAt time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (BulkJobItem) TableName() string {
+	return "user_bulk_job_item"
+}
+
+// Record stores the outcome of one item and updates the job counters. The
+// item keeps only the PublicMessage of err because jobs are shown to admins;
+// callers log the cause.
+func (j *BulkJob) Record(item *BulkJobItem, err error) {
+	if err != nil {
+		msg := PublicMessage(err)
+		item.Status = BulkItemFailed
+		item.Error = &msg
+		j.Failed++
+		return
+	}
+	item.Status = BulkItemSucceeded
+	item.Error = nil
+	j.Succeeded++
+}
+
+// Finish sets the final job status from the item counters.
+func (j *BulkJob) Finish(now time.Time) {
+	now = now.UTC()
+	j.CompletedAt = &now
+	switch {
+	case j.Failed == 0:
+		j.Status = BulkJobCompleted
+	case j.Succeeded == 0:
+		j.Status = BulkJobFailed
+	default:
+		j.Status = BulkJobPartiallyFailed
+	}
+}
+
+// Validate checks that the job carries the parameters its action needs.
+func (j *BulkJob) Validate() error {
+	if !j.Action.IsValid() {
+		return Invalid("action", "invalid bulk action")
+	}
+	switch j.Action {
+	case BulkActionChangeStatus:
+		if j.TargetStatus == nil || !j.TargetStatus.IsValid() {
+			return Invalid("status", "status is required for change_status")
+		}
+		if j.Reason == nil || *j.Reason == "" {
+			return ErrStatusReasonRequired
+		}
+	case BulkActionAssignRole:
+		if j.Role == nil || *j.Role == "" {
+			return Invalid("role", "role is required for assign_role")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAlreadyDeleted      = Conflict("already_deleted", "user is already deleted")
+	ErrNotDeleted          = Conflict("not_deleted", "user is not deleted")
+	ErrRestoreWindowClosed = Conflict("restore_window_closed", "restore window has closed")
+	ErrEmailInUse          = Conflict("email_in_use", "email already in use")
+)
+
+// Deletion holds the soft-delete state of a user. A deleted account can be
+// restored until PurgeAfter; after that it is anonymised by the purge job.
+type Deletion struct {
+	DeletedAt          *time.Time  `gorm:"column:deleted_at" json:"deleted_at,omitempty"`
+	PurgeAfter         *time.Time  `gorm:"column:purge_after" json:"purge_after,omitempty"`
+	DeletedBy          *string     `gorm:"column:deleted_by;type:uuid" json:"deleted_by,omitempty"`
+	StatusBeforeDelete *UserStatus `gorm:"column:status_before_delete;type:text" json:"-"`
+	PurgedAt           *time.Time  `gorm:"column:purged_at" json:"purged_at,omitempty"`
+}
+
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
+}
+
+// MarkDeleted soft-deletes the user with a grace period for restoring.
+func (u *User) MarkDeleted(now time.Time, grace time.Duration, actorID string) error {
+	if u.IsDeleted() {
+		return ErrAlreadyDeleted
+	}
+	previous := u.StatusOrDefault()
+	u.setStatus(UserStatusDeleted)
+	now = now.UTC()
+	purgeAfter := now.Add(grace)
+	u.DeletedAt = &now
+	u.PurgeAfter = &purgeAfter
+	u.StatusBeforeDelete = &previous
+	if actor := strings.TrimSpace(actorID); actor != "" {
+		u.DeletedBy = &actor
+	}
+	return nil
+}
+
+// Restore brings a soft-deleted user back to the status it had before.
+func (u *User) Restore(now time.Time) error {
+	if !u.IsDeleted() {
+		return ErrNotDeleted
+	}
+	if u.PurgedAt != nil || (u.PurgeAfter != nil && !now.Before(*u.PurgeAfter)) {
+		return ErrRestoreWindowClosed
+	}
+	status := UserStatusActive
+	if u.StatusBeforeDelete != nil && u.StatusBeforeDelete.IsValid() && *u.StatusBeforeDelete != UserStatusDeleted {
+		status = *u.StatusBeforeDelete
+	}
+	u.setStatus(status)
+	u.Deletion = Deletion{}
+	return nil
+}
+
+// Anonymize strips personal data from a deleted user.
+func (u *User) Anonymize(now time.Time) error {
+	if !u.IsDeleted() {
+		return ErrNotDeleted
+	}
+	now = now.UTC()
+	u.Email = fmt.Sprintf("deleted-%s@users.invalid", u.ID)
+	u.PurgedAt = &now
+	u.Suspension = Suspension{}
+	if u.Profile != nil {
+		u.Profile.DisplayName = nil
+		u.Profile.AvatarFileID = nil
+		u.Profile.AvatarURL = nil
+	}
+	return nil
+}
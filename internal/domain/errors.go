@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error kinds. Every typed error below matches exactly one of them with
+// errors.Is, which is how the HTTP layer picks the response status.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrValidation  = errors.New("validation failed")
+	ErrForbidden   = errors.New("forbidden")
+	ErrUnavailable = errors.New("dependency unavailable")
+)
+
+// Error is a domain error with a stable code that clients may rely on.
+// Message is safe to show to clients; the wrapped cause is not.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound reports a missing resource, e.g. NotFound("user").
+func NotFound(resource string) *Error {
+	return &Error{Kind: ErrNotFound, Code: "not_found", Message: resource + " not found"}
+}
+
+// Conflict reports a request that clashes with the current state.
+func Conflict(code, message string) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message}
+}
+
+// Forbidden reports a request the caller may not make.
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: message}
+}
+
+// Unavailable wraps the failure of a service or store the request depends
+// on, e.g. Unavailable("rbac", err).
+func Unavailable(dependency string, err error) *Error {
+	return &Error{Kind: ErrUnavailable, Code: "dependency_unavailable", Message: dependency + " is unavailable", Err: err}
+}
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Invalid returns a ValidationError for a single field.
+func Invalid(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// Add records another invalid field.
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Merge adds the fields of err if it is a ValidationError, so parsing and
+// domain validation can be reported together.
+func (e *ValidationError) Merge(err error) {
+	var other *ValidationError
+	if errors.As(err, &other) {
+		e.Fields = append(e.Fields, other.Fields...)
+	}
+}
+
+// OrNil returns e if it holds any field errors and nil otherwise, so
+// validation can collect errors and return them at the end.
+func (e *ValidationError) OrNil() error {
+	if e == nil || len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error joins the field messages, which are written to read on their own.
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// PublicMessage returns what clients may see of err: the field messages of a
+// ValidationError or the Message of an Error. Anything else may carry
+// internal details and is reported as "internal error".
+func PublicMessage(err error) string {
+	var (
+		validationErr *ValidationError
+		domainErr     *Error
+	)
+	switch {
+	case errors.As(err, &validationErr):
+		return validationErr.Error()
+	case errors.As(err, &domainErr):
+		return domainErr.Message
+	}
+	return "internal error"
+}
@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicMessageHidesInternalErrors(t *testing.T) {
+	require.Equal(t, "invalid role; invalid status", PublicMessage(&ValidationError{Fields: []FieldError{{Field: "role", Message: "invalid role"}, {Field: "status", Message: "invalid status"}}}))
+	require.Equal(t, "email already in use", PublicMessage(fmt.Errorf("%w: %w", ErrEmailInUse, errors.New(`duplicate key value violates unique constraint "idx_user_email_live_unique"`))))
+	require.Equal(t, "rbac is unavailable", PublicMessage(Unavailable("rbac", errors.New("dial tcp 10.0.0.7:80: connection refused"))))
+	require.Equal(t, "internal error", PublicMessage(errors.New("pq: connection reset")))
+}
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+type ExportStatus string
+
+const (
+	ExportStatusPending   ExportStatus = "PENDING"
+	ExportStatusRunning   ExportStatus = "RUNNING"
+	ExportStatusCompleted ExportStatus = "COMPLETED"
+	ExportStatusFailed    ExportStatus = "FAILED"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatJSON ExportFormat = "json"
+	ExportFormatZIP  ExportFormat = "zip"
+)
+
+var ErrInvalidExportFormat = Invalid("format", "invalid export format")
+
+func (f ExportFormat) IsValid() bool {
+	return f == ExportFormatJSON || f == ExportFormatZIP
+}
+
+// ExportJob tracks an asynchronous personal-data export requested by a user.
+type ExportJob struct {
+	ID          string       `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	UserID      string       `gorm:"type:uuid;not null;index" json:"user_id"`
+	Status      ExportStatus `gorm:"column:status;type:text;not null" json:"status"`
+	Format      ExportFormat `gorm:"column:format;type:text;not null" json:"format"`
+	FileID      *string      `gorm:"column:file_id" json:"file_id,omitempty"`
+	Error       *string      `gorm:"column:error" json:"error,omitempty"`
+	CreatedAt   time.Time    `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time    `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	CompletedAt *time.Time   `gorm:"column:completed_at" json:"completed_at,omitempty"`
+}
+
+func (ExportJob) TableName() string {
+	return "user_export_job"
+}
+
+func (j *ExportJob) IsFinished() bool {
+	return j.Status == ExportStatusCompleted || j.Status == ExportStatusFailed
+}
+
+// Complete marks the job done with the uploaded archive.
+func (j *ExportJob) Complete(fileID string, now time.Time) {
+	now = now.UTC()
+	j.Status = ExportStatusCompleted
+	j.FileID = &fileID
+	j.Error = nil
+	j.CompletedAt = &now
+}
+
+// Fail marks the job as failed with the cause.
+func (j *ExportJob) Fail(cause error, now time.Time) {
+	now = now.UTC()
+	msg := cause.Error()
+	j.Status = ExportStatusFailed
+	j.Error = &msg
+	j.CompletedAt = &now
+}
+
+// UserDataExport is the document handed to a user in a personal-data export.
+type UserDataExport struct {
+	ExportedAt    time.Time           `json:"exported_at"`
+	User          *User               `json:"user"`
+	Profile       *UserProfile        `json:"profile,omitempty"`
+	Identities    []UserIdentity      `json:"identities"`
+	Providers     []UserProvider      `json:"providers"`
+	StatusHistory []UserStatusHistory `json:"status_history"`
+}
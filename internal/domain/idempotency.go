@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord remembers the outcome of a mutating request sent with an
+// Idempotency-Key, so retries with the same key get the same response. The
+// response fields stay empty while the first request is still running.
+type IdempotencyRecord struct {
+	ID              string    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	Principal       string    `gorm:"column:principal;not null" json:"principal"`
+	Key             string    `gorm:"column:key;not null" json:"key"`
+	Fingerprint     string    `gorm:"column:fingerprint;not null" json:"fingerprint"`
+	ResponseStatus  *int      `gorm:"column:response_status" json:"response_status,omitempty"`
+	ResponseHeaders JSONMap   `gorm:"column:response_headers;type:jsonb" json:"response_headers,omitempty"`
+	ResponseBody    []byte    `gorm:"column:response_body" json:"-"`
+	ExpiresAt       time.Time `gorm:"column:expires_at;not null" json:"expires_at"`
+	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_key"
+}
+
+// InFlight reports whether the original request has not finished yet.
+func (r *IdempotencyRecord) InFlight() bool {
+	return r.ResponseStatus == nil
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// OutboxMessage is an event waiting to be relayed to the message broker.
+type OutboxMessage struct {
+	ID            string     `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	Subject       string     `gorm:"column:subject;not null" json:"subject"`
+	Payload       []byte     `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError     *string    `gorm:"column:last_error" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;not null" json:"next_attempt_at"`
+	PublishedAt   *time.Time `gorm:"column:published_at" json:"published_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "user_outbox"
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// RoleAssignment is an RBAC role that still has to be assigned to a user. It
+// is stored with the user change that requires it and assigned after commit,
+// so an unreachable RBAC service delays the role instead of failing the
+// change.
+type RoleAssignment struct {
+	ID            string     `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	UserID        string     `gorm:"column:user_id;type:uuid;not null" json:"user_id"`
+	Role          string     `gorm:"column:role;not null" json:"role"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError     *string    `gorm:"column:last_error" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;not null" json:"next_attempt_at"`
+	AssignedAt    *time.Time `gorm:"column:assigned_at" json:"assigned_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (RoleAssignment) TableName() string {
+	return "role_assignment"
+}
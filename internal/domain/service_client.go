@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scopes granted to service clients.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+)
+
+var (
+	ErrInvalidAPIKey        = errors.New("invalid api key")
+	ErrInvalidScope         = Invalid("scopes", "invalid scope")
+	ErrServiceClientRevoked = Conflict("service_client_revoked", "service client is revoked")
+)
+
+// privilegedRoles open the admin API, so only admins may grant them.
+var privilegedRoles = map[string]bool{"admin": true, "moderator": true}
+
+// IsPrivilegedRole reports whether role may not be granted by service clients.
+func IsPrivilegedRole(role string) bool {
+	return privilegedRoles[strings.ToLower(strings.TrimSpace(role))]
+}
+
+func IsValidScope(scope string) bool {
+	return scope == ScopeUsersRead || scope == ScopeUsersWrite
+}
+
+// ServiceClient is another service calling this one with an API key. Only a
+// hash of the key is stored; KeyPrefix identifies the key in lookups and
+// listings.
+type ServiceClient struct {
+	ID         string     `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	Name       string     `gorm:"column:name;not null" json:"name"`
+	KeyPrefix  string     `gorm:"column:key_prefix;not null" json:"key_prefix"`
+	KeyHash    string     `gorm:"column:key_hash;not null" json:"-"`
+	Scopes     StringList `gorm:"column:scopes;type:jsonb;not null" json:"scopes"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	CreatedBy  *string    `gorm:"column:created_by;type:uuid" json:"created_by,omitempty"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (ServiceClient) TableName() string {
+	return "service_client"
+}
+
+// Usable reports whether the client may authenticate at now.
+func (c *ServiceClient) Usable(now time.Time) bool {
+	if c.RevokedAt != nil {
+		return false
+	}
+	return c.ExpiresAt == nil || now.Before(*c.ExpiresAt)
+}
+
+func (c *ServiceClient) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// StringList stores a list of strings in a JSONB column.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		return fmt.Errorf("unsupported type %T for StringList", value)
+	}
+	var items []string
+	if err := json.Unmarshal(bytes, &items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
+}
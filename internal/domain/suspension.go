@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+type SuspensionReason string
+
+const (
+	SuspensionReasonSpam            SuspensionReason = "SPAM"
+	SuspensionReasonAbuse           SuspensionReason = "ABUSE"
+	SuspensionReasonFraud           SuspensionReason = "FRAUD"
+	SuspensionReasonPolicyViolation SuspensionReason = "POLICY_VIOLATION"
+	SuspensionReasonOther           SuspensionReason = "OTHER"
+)
+
+var ErrNotSuspended = Conflict("not_suspended", "user is not suspended")
+
+// Suspension holds the time-boxed suspension state of a user. It is empty
+// unless the user is in SUSPENDED status.
+type Suspension struct {
+	SuspendedUntil   *time.Time        `gorm:"column:suspended_until" json:"suspended_until,omitempty"`
+	SuspensionReason *SuspensionReason `gorm:"column:suspension_reason;type:text" json:"suspension_reason,omitempty"`
+	SuspendedBy      *string           `gorm:"column:suspended_by;type:uuid" json:"suspended_by,omitempty"`
+}
+
+func (r SuspensionReason) IsValid() bool {
+	switch r {
+	case SuspensionReasonSpam, SuspensionReasonAbuse, SuspensionReasonFraud, SuspensionReasonPolicyViolation, SuspensionReasonOther:
+		return true
+	}
+	return false
+}
+
+// Suspend moves the user to SUSPENDED until the given time.
+func (u *User) Suspend(until time.Time, reason SuspensionReason, actorID string, now time.Time) error {
+	if !reason.IsValid() {
+		return Invalid("reason_code", "invalid suspension reason")
+	}
+	if !until.After(now) {
+		return Invalid("until", "suspension end must be in the future")
+	}
+	if err := u.TransitionTo(UserStatusSuspended, string(reason)); err != nil {
+		return err
+	}
+	until = until.UTC()
+	u.SuspendedUntil = &until
+	u.SuspensionReason = &reason
+	if actor := strings.TrimSpace(actorID); actor != "" {
+		u.SuspendedBy = &actor
+	}
+	return nil
+}
+
+// LiftSuspension reactivates a suspended user.
+func (u *User) LiftSuspension(reason string) error {
+	if u.StatusOrDefault() != UserStatusSuspended {
+		return ErrNotSuspended
+	}
+	return u.TransitionTo(UserStatusActive, reason)
+}
+
+// IsSuspended reports whether the suspension is still in force at now.
+func (u *User) IsSuspended(now time.Time) bool {
+	if u.StatusOrDefault() != UserStatusSuspended {
+		return false
+	}
+	return u.SuspendedUntil == nil || u.SuspendedUntil.After(now)
+}
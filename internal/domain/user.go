@@ -1,17 +1,16 @@
 package domain
 
-import (
-	"fmt"
-	"time"
-)
+import "time"
 
 type UserStatus string
 
 const (
-	UserStatusNew      UserStatus = "NEW_USER"
-	UserStatusActive   UserStatus = "ACTIVE"
-	UserStatusInactive UserStatus = "INACTIVE"
-	UserStatusBlocked  UserStatus = "BLOCKED"
+	UserStatusNew       UserStatus = "NEW_USER"
+	UserStatusActive    UserStatus = "ACTIVE"
+	UserStatusInactive  UserStatus = "INACTIVE"
+	UserStatusBlocked   UserStatus = "BLOCKED"
+	UserStatusSuspended UserStatus = "SUSPENDED"
+	UserStatusDeleted   UserStatus = "DELETED"
 )
 
 type User struct {
@@ -20,9 +19,12 @@ type User struct {
 	PasswordHash *string    `gorm:"-" json:"-"`
 	Status       UserStatus `gorm:"column:status;type:text;default:NEW_USER" json:"status"`
 	IsActive     bool       `gorm:"column:is_active;default:true" json:"is_active"`
+	Version      int64      `gorm:"column:version;not null;default:1" json:"version"`
 	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
-	Profile      *UserProfile
+	Suspension
+	Deletion
+	Profile *UserProfile
 }
 
 func (User) TableName() string {
@@ -38,16 +40,36 @@ func (u *User) SetPasswordHash(hash string) {
 }
 
 func (s UserStatus) IsValid() bool {
-	return s == UserStatusActive || s == UserStatusInactive || s == UserStatusBlocked || s == UserStatusNew
+	return s == UserStatusActive || s == UserStatusInactive || s == UserStatusBlocked || s == UserStatusNew || s == UserStatusSuspended || s == UserStatusDeleted
+}
+
+// initialStatuses are the statuses a user may be created with. Suspension and
+// deletion carry state of their own and are reached through transitions only.
+var initialStatuses = map[UserStatus]bool{
+	UserStatusNew:      true,
+	UserStatusActive:   true,
+	UserStatusInactive: true,
+	UserStatusBlocked:  true,
 }
 
-func (u *User) SetStatus(status UserStatus) error {
-	if !status.IsValid() {
-		return fmt.Errorf("invalid user status")
+// NewUser returns a user that has not been stored yet. Later status changes
+// go through TransitionTo, MarkDeleted and Restore.
+func NewUser(email string, status UserStatus) (*User, error) {
+	if !initialStatuses[status] {
+		return nil, Invalid("status", "invalid user status")
 	}
+	user := &User{Email: email}
+	user.setStatus(status)
+	return user, nil
+}
+
+func (u *User) setStatus(status UserStatus) {
 	u.Status = status
 	u.IsActive = status == UserStatusActive || status == UserStatusNew
-	return nil
+	// A deleted account keeps its suspension so a restore brings it back.
+	if status != UserStatusSuspended && status != UserStatusDeleted {
+		u.Suspension = Suspension{}
+	}
 }
 
 func (u *User) StatusOrDefault() UserStatus {
@@ -59,15 +81,3 @@ func (u *User) StatusOrDefault() UserStatus {
 	}
 	return UserStatusInactive
 }
-
-func (u *User) Activate() {
-	_ = u.SetStatus(UserStatusActive)
-}
-
-func (u *User) Deactivate() {
-	_ = u.SetStatus(UserStatusInactive)
-}
-
-func (u *User) Block() {
-	_ = u.SetStatus(UserStatusBlocked)
-}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+)
+
+type ImportFormat string
+
+const (
+	ImportFormatCSV    ImportFormat = "csv"
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
+var (
+	ErrInvalidImportFormat = Invalid("format", "invalid import format")
+	// ErrMalformedImportRow marks a row that could not be decoded. The import
+	// reports it and carries on with the next row.
+	ErrMalformedImportRow = errors.New("malformed row")
+)
+
+func (f ImportFormat) IsValid() bool {
+	return f == ImportFormatCSV || f == ImportFormatNDJSON
+}
+
+// ImportRow is a single decoded record of a user import file. Line is the
+// 1-based position in the source so reports can point back at it.
+type ImportRow struct {
+	Line        int    `json:"-"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	Role        string `json:"role"`
+	Status      string `json:"status"`
+}
+
+type ImportOutcome string
+
+const (
+	ImportCreated   ImportOutcome = "created"
+	ImportUpdated   ImportOutcome = "updated"
+	ImportUnchanged ImportOutcome = "unchanged"
+	ImportFailed    ImportOutcome = "failed"
+)
+
+// ImportResult is the report entry for one row. In a dry run the outcome is
+// what would have happened and UserID is only set for existing users.
+type ImportResult struct {
+	Line    int           `json:"line"`
+	Email   string        `json:"email"`
+	Outcome ImportOutcome `json:"result"`
+	UserID  string        `json:"user_id,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Fail records err as the reason. Only its public message goes into the
+// report, which is handed to the client.
+func (r *ImportResult) Fail(err error) {
+	r.Outcome = ImportFailed
+	r.Error = PublicMessage(err)
+}
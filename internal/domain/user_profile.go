@@ -11,6 +11,7 @@ type UserProfile struct {
 	DisplayName  *string   `gorm:"column:display_name" json:"display_name"`
 	AvatarFileID *string   `gorm:"column:avatar_file_id" json:"avatar_file_id"`
 	AvatarURL    *string   `gorm:"-" json:"avatar_url,omitempty"`
+	Version      int64     `gorm:"column:version;not null;default:1" json:"version"`
 	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt    time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 }
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type UserSortField string
+
+const (
+	UserSortEmail     UserSortField = "email"
+	UserSortStatus    UserSortField = "status"
+	UserSortCreatedAt UserSortField = "created_at"
+	UserSortUpdatedAt UserSortField = "updated_at"
+)
+
+func (f UserSortField) IsValid() bool {
+	switch f {
+	case UserSortEmail, UserSortStatus, UserSortCreatedAt, UserSortUpdatedAt:
+		return true
+	}
+	return false
+}
+
+// MaxUserSearchLength bounds the substring search of UserQuery.
+const MaxUserSearchLength = 100
+
+type UserCountMode string
+
+const (
+	UserCountNone      UserCountMode = ""
+	UserCountExact     UserCountMode = "exact"
+	UserCountEstimated UserCountMode = "estimated"
+)
+
+// UserCursor is a keyset position on (created_at, id). Backward cursors page
+// towards the start of the list.
+type UserCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// UserPage is one keyset page. Next and Prev are nil at either end of the
+// list; TotalCount is only set when the query asks for it.
+type UserPage struct {
+	Users      []User
+	Next       *UserCursor
+	Prev       *UserCursor
+	TotalCount *int64
+}
+
+// UserQuery describes a filtered, sorted page of users. Zero values mean
+// "no filter"; the default order is created_at descending.
+type UserQuery struct {
+	Offset      int
+	Limit       int
+	Statuses    []UserStatus
+	IsActive    *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Provider    IdentityProvider
+	Search      string
+	SortBy      UserSortField
+	SortDesc    bool
+	// Cursor switches to keyset pagination; Offset is ignored.
+	Cursor *UserCursor
+	Count  UserCountMode
+}
+
+// HasFilters reports whether any filter narrows the result set.
+func (q UserQuery) HasFilters() bool {
+	return len(q.Statuses) > 0 || q.IsActive != nil || q.CreatedFrom != nil || q.CreatedTo != nil || q.Provider != "" || q.Search != ""
+}
+
+// Validate reports every invalid filter of the query at once.
+func (q UserQuery) Validate() error {
+	errs := &ValidationError{}
+	if q.Offset < 0 || q.Limit <= 0 {
+		errs.Add("page", "invalid pagination")
+	}
+	for _, status := range q.Statuses {
+		switch {
+		case status == UserStatusDeleted:
+			// Lists only hold live users, so the filter could never match.
+			errs.Add("status", "deleted users are not listed")
+		case !status.IsValid():
+			errs.Add("status", fmt.Sprintf("invalid status filter %q", status))
+		}
+	}
+	if q.Provider != "" && !q.Provider.IsValid() {
+		errs.Add("provider", fmt.Sprintf("invalid provider filter %q", q.Provider))
+	}
+	if len(q.Search) > MaxUserSearchLength {
+		errs.Add("q", "q is too long")
+	}
+	if q.CreatedFrom != nil && q.CreatedTo != nil && q.CreatedTo.Before(*q.CreatedFrom) {
+		errs.Add("created_to", "created_to must not be before created_from")
+	}
+	if q.SortBy != "" && !q.SortBy.IsValid() {
+		errs.Add("sort", fmt.Sprintf("invalid sort field %q", q.SortBy))
+	}
+	if q.Count != UserCountNone && q.Count != UserCountExact && q.Count != UserCountEstimated {
+		errs.Add("count", fmt.Sprintf("invalid count mode %q", q.Count))
+	}
+	return errs.OrNil()
+}
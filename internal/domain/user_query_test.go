@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserQueryValidate(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	before := from.Add(-time.Hour)
+	cases := []struct {
+		name    string
+		query   UserQuery
+		wantErr bool
+	}{
+		{name: "default", query: UserQuery{Limit: 50, SortBy: UserSortCreatedAt, SortDesc: true}},
+		{name: "all filters", query: UserQuery{Limit: 10, Statuses: []UserStatus{UserStatusActive}, Provider: ProviderGoogle, SortBy: UserSortEmail, CreatedFrom: &from}},
+		{name: "zero limit", query: UserQuery{}, wantErr: true},
+		{name: "bad status", query: UserQuery{Limit: 10, Statuses: []UserStatus{"GONE"}}, wantErr: true},
+		{name: "deleted status", query: UserQuery{Limit: 10, Statuses: []UserStatus{UserStatusDeleted}}, wantErr: true},
+		{name: "long search", query: UserQuery{Limit: 10, Search: strings.Repeat("a", MaxUserSearchLength+1)}, wantErr: true},
+		{name: "bad provider", query: UserQuery{Limit: 10, Provider: "myspace"}, wantErr: true},
+		{name: "bad sort", query: UserQuery{Limit: 10, SortBy: "password_hash"}, wantErr: true},
+		{name: "inverted range", query: UserQuery{Limit: 10, CreatedFrom: &from, CreatedTo: &before}, wantErr: true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := tc.query.Validate(); (err != nil) != tc.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
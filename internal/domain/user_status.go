@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidStatusTransition = Conflict("invalid_status_transition", "invalid status transition")
+	ErrStatusReasonRequired    = Invalid("reason", "status change reason is required")
+)
+
+// statusTransitions lists the allowed target statuses for each status.
+// Any status may move to BLOCKED; leaving BLOCKED requires a reason.
+var statusTransitions = map[UserStatus][]UserStatus{
+	UserStatusNew:       {UserStatusActive, UserStatusBlocked, UserStatusSuspended},
+	UserStatusActive:    {UserStatusInactive, UserStatusBlocked, UserStatusSuspended},
+	UserStatusInactive:  {UserStatusActive, UserStatusBlocked, UserStatusSuspended},
+	UserStatusSuspended: {UserStatusActive, UserStatusBlocked},
+	UserStatusBlocked:   {UserStatusActive},
+}
+
+// CanTransition reports whether a user may move from one status to another.
+func CanTransition(from, to UserStatus, reason string) error {
+	if !to.IsValid() {
+		return Invalid("status", "invalid user status")
+	}
+	for _, allowed := range statusTransitions[from] {
+		if allowed != to {
+			continue
+		}
+		if from == UserStatusBlocked && strings.TrimSpace(reason) == "" {
+			return ErrStatusReasonRequired
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, from, to)
+}
+
+// TransitionTo changes the status following the transition table.
+func (u *User) TransitionTo(status UserStatus, reason string) error {
+	if err := CanTransition(u.StatusOrDefault(), status, reason); err != nil {
+		return err
+	}
+	u.setStatus(status)
+	return nil
+}
+
+// UserStatusHistory records a single status change.
+type UserStatusHistory struct {
+	ID         string     `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	UserID     string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	FromStatus UserStatus `gorm:"column:from_status;type:text;not null" json:"from_status"`
+	ToStatus   UserStatus `gorm:"column:to_status;type:text;not null" json:"to_status"`
+	Reason     string     `gorm:"column:reason;not null" json:"reason"`
+	ChangedBy  *string    `gorm:"column:changed_by;type:uuid" json:"changed_by,omitempty"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (UserStatusHistory) TableName() string {
+	return "user_status_history"
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		from    UserStatus
+		to      UserStatus
+		reason  string
+		wantErr error
+	}{
+		{name: "new to active", from: UserStatusNew, to: UserStatusActive},
+		{name: "active to inactive", from: UserStatusActive, to: UserStatusInactive},
+		{name: "inactive to active", from: UserStatusInactive, to: UserStatusActive},
+		{name: "any to blocked", from: UserStatusNew, to: UserStatusBlocked},
+		{name: "blocked to active with reason", from: UserStatusBlocked, to: UserStatusActive, reason: "appeal"},
+		{name: "blocked to active without reason", from: UserStatusBlocked, to: UserStatusActive, wantErr: ErrStatusReasonRequired},
+		{name: "blocked to new", from: UserStatusBlocked, to: UserStatusNew, reason: "x", wantErr: ErrInvalidStatusTransition},
+		{name: "active to new", from: UserStatusActive, to: UserStatusNew, wantErr: ErrInvalidStatusTransition},
+		{name: "same status", from: UserStatusActive, to: UserStatusActive, wantErr: ErrInvalidStatusTransition},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CanTransition(tc.from, tc.to, tc.reason)
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUserTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	u := &User{Status: UserStatusActive, IsActive: true}
+	if err := u.TransitionTo(UserStatusBlocked, ""); err != nil {
+		t.Fatalf("TransitionTo error: %v", err)
+	}
+	if u.Status != UserStatusBlocked || u.IsActive {
+		t.Fatalf("unexpected state: %+v", u)
+	}
+}
+
+func TestNewUserRejectsTransitionOnlyStatuses(t *testing.T) {
+	t.Parallel()
+
+	u, err := NewUser("user@example.com", UserStatusNew)
+	if err != nil {
+		t.Fatalf("NewUser error: %v", err)
+	}
+	if u.Status != UserStatusNew || !u.IsActive {
+		t.Fatalf("unexpected state: %+v", u)
+	}
+	for _, status := range []UserStatus{UserStatusSuspended, UserStatusDeleted, "UNKNOWN"} {
+		if _, err := NewUser("user@example.com", status); !errors.Is(err, ErrValidation) {
+			t.Fatalf("NewUser(%s): expected validation error, got %v", status, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrVersionConflict is returned when a user or profile changed since it was
+// read, either by a concurrent write or according to an If-Match header.
+var ErrVersionConflict = Conflict("precondition_failed", "user was modified concurrently")
+
+// ETag identifies the current version of a user together with its profile.
+func (u *User) ETag() string {
+	var profileVersion int64
+	if u.Profile != nil {
+		profileVersion = u.Profile.Version
+	}
+	return fmt.Sprintf(`"%d.%d"`, u.Version, profileVersion)
+}
+
+// CheckIfMatch returns ErrVersionConflict unless ifMatch is empty, "*" or
+// lists etag.
+func CheckIfMatch(ifMatch, etag string) error {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return nil
+	}
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return nil
+		}
+	}
+	return ErrVersionConflict
+}
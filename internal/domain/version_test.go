@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	t.Parallel()
+
+	user := &User{Version: 3, Profile: &UserProfile{Version: 7}}
+	etag := user.ETag()
+	if etag != `"3.7"` {
+		t.Fatalf("etag = %s", etag)
+	}
+	cases := []struct {
+		ifMatch string
+		wantErr error
+	}{
+		{ifMatch: ""},
+		{ifMatch: "*"},
+		{ifMatch: `"3.7"`},
+		{ifMatch: `"1.1", "3.7"`},
+		{ifMatch: `"3.6"`, wantErr: ErrVersionConflict},
+		{ifMatch: `W/"3.7"`, wantErr: ErrVersionConflict},
+	}
+	for _, tc := range cases {
+		if err := CheckIfMatch(tc.ifMatch, etag); !errors.Is(err, tc.wantErr) {
+			t.Errorf("CheckIfMatch(%q) = %v, want %v", tc.ifMatch, err, tc.wantErr)
+		}
+	}
+}
@@ -2,6 +2,19 @@ package events
 
 import "time"
 
+const (
+	UserCreated        = "created"
+	UserUpdated        = "updated"
+	UserStatusChanged  = "status_changed"
+	UserRoleChanged    = "role_changed"
+	UserProfileUpdated = "profile_updated"
+	IdentityAttached   = "identity_attached"
+	IdentityDetached   = "identity_detached"
+	UserDeleted        = "deleted"
+	UserRestored       = "restored"
+	UserPurged         = "purged"
+)
+
 type UserEvent struct {
 	Event      string    `json:"event"`
 	UserID     string    `json:"user_id"`
@@ -19,3 +32,12 @@ func NewUserEvent(event, userID, email, traceID string) UserEvent {
 		TraceID:    traceID,
 	}
 }
+
+// Subject builds the broker subject for the event under prefix, e.g.
+// "user.events" + "created" -> "user.events.created".
+func (e UserEvent) Subject(prefix string) string {
+	if prefix == "" {
+		return e.Event
+	}
+	return prefix + "." + e.Event
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+)
+
+const purgeBatch = 100
+
+// AccountDeletionService implements the soft delete, restore and purge
+// lifecycle shared by self-service and admin endpoints.
+type AccountDeletionService interface {
+	Delete(ctx context.Context, userID, actorID string) (*domain.User, error)
+	Restore(ctx context.Context, userID, actorID string) (*domain.User, error)
+	PurgeExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type accountDeletionService struct {
+	users      repo.UserRepository
+	profiles   repo.UserProfileRepository
+	identities repo.UserIdentityRepository
+	providers  repo.UserProviderRepository
+	history    repo.UserStatusHistoryRepository
+	tx         repo.TxManager
+	outbox     repo.OutboxRepository
+	audit      repo.AuditLogRepository
+	grace      time.Duration
+}
+
+func NewAccountDeletionService(users repo.UserRepository, profiles repo.UserProfileRepository, identities repo.UserIdentityRepository, providers repo.UserProviderRepository, history repo.UserStatusHistoryRepository, tx repo.TxManager, outbox repo.OutboxRepository, audit repo.AuditLogRepository, grace time.Duration) AccountDeletionService {
+	return &accountDeletionService{
+		users:      users,
+		profiles:   profiles,
+		identities: identities,
+		providers:  providers,
+		history:    history,
+		tx:         tx,
+		outbox:     outbox,
+		audit:      audit,
+		grace:      grace,
+	}
+}
+
+func (s *accountDeletionService) Delete(ctx context.Context, userID, actorID string) (*domain.User, error) {
+	user, err := s.users.FindByIDIncludingDeleted(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	from := user.StatusOrDefault()
+	before := auditSnapshot(user)
+	if err := user.MarkDeleted(time.Now(), s.grace, actorID); err != nil {
+		return nil, err
+	}
+	reason := "account deleted by user"
+	if actorID != "" && actorID != userID {
+		reason = "account deleted by admin"
+	}
+	err = withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.users.Update(ctx, user); err != nil {
+			return err
+		}
+		if err := s.recordHistory(ctx, user, from, reason, actorID); err != nil {
+			return err
+		}
+		if err := s.recordAdminAudit(ctx, domain.AuditUserDelete, user, before, actorID); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.outbox, events.UserDeleted, user.ID, user.Email)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *accountDeletionService) Restore(ctx context.Context, userID, actorID string) (*domain.User, error) {
+	user, err := s.users.FindByIDIncludingDeleted(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// The email may have been registered again while the account was deleted.
+	if existing, err := s.users.FindByEmail(ctx, user.Email); err == nil && existing.ID != user.ID {
+		return nil, domain.ErrEmailInUse
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	before := auditSnapshot(user)
+	if err := user.Restore(time.Now()); err != nil {
+		return nil, err
+	}
+	err = withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.users.Update(ctx, user); err != nil {
+			return err
+		}
+		if err := s.recordHistory(ctx, user, domain.UserStatusDeleted, "account restored", actorID); err != nil {
+			return err
+		}
+		if err := s.recordAdminAudit(ctx, domain.AuditUserRestore, user, before, actorID); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.outbox, events.UserRestored, user.ID, user.Email)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// PurgeExpired anonymises every deleted account whose grace period ended
+// and returns how many were purged.
+// recordAdminAudit audits deletes and restores made by someone other than
+// the user; like the other admin actions, self-service changes are left to
+// the status history.
+func (s *accountDeletionService) recordAdminAudit(ctx context.Context, action domain.AuditAction, user *domain.User, before map[string]interface{}, actorID string) error {
+	if actorID == "" || actorID == user.ID {
+		return nil
+	}
+	return recordAudit(ctx, s.audit, action, user.ID, before, auditSnapshot(user))
+}
+
+func (s *accountDeletionService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	purged := 0
+	for {
+		users, err := s.users.ListPurgeable(ctx, now, purgeBatch)
+		if err != nil {
+			return purged, err
+		}
+		for idx := range users {
+			if err := s.purge(ctx, &users[idx], now); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(users) < purgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+func (s *accountDeletionService) purge(ctx context.Context, user *domain.User, now time.Time) error {
+	if err := user.Anonymize(now); err != nil {
+		return err
+	}
+	return withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.identities.DeleteByUser(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := s.providers.DeleteByUser(ctx, user.ID); err != nil {
+			return err
+		}
+		if user.Profile != nil {
+			if err := s.profiles.Update(ctx, user.Profile); err != nil {
+				return err
+			}
+		}
+		profile := user.Profile
+		user.Profile = nil
+		err := s.users.Update(ctx, user)
+		user.Profile = profile
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.outbox, events.UserPurged, user.ID, "")
+	})
+}
+
+func (s *accountDeletionService) recordHistory(ctx context.Context, user *domain.User, from domain.UserStatus, reason, actorID string) error {
+	if s.history == nil {
+		return nil
+	}
+	return s.history.Create(ctx, &domain.UserStatusHistory{
+		UserID:     user.ID,
+		FromStatus: from,
+		ToStatus:   user.Status,
+		Reason:     reason,
+		ChangedBy:  optionalString(actorID),
+	})
+}
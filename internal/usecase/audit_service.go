@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/domain"
+)
+
+const auditVerifyBatch = 500
+
+type auditActorKey struct{}
+
+// WithAuditActor attaches the caller recorded by audited changes made with
+// the returned context.
+func WithAuditActor(ctx context.Context, actor domain.AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func auditActorFrom(ctx context.Context) domain.AuditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(domain.AuditActor)
+	return actor
+}
+
+// AuditService reads and verifies the admin audit log.
+type AuditService interface {
+	List(ctx context.Context, query domain.AuditQuery) ([]domain.AuditEntry, int64, error)
+	Verify(ctx context.Context) (int, error)
+}
+
+type auditService struct {
+	entries repo.AuditLogRepository
+}
+
+func NewAuditService(entries repo.AuditLogRepository) AuditService {
+	return &auditService{entries: entries}
+}
+
+func (s *auditService) List(ctx context.Context, query domain.AuditQuery) ([]domain.AuditEntry, int64, error) {
+	return s.entries.List(ctx, query)
+}
+
+// Verify walks the whole chain and returns how many entries it checked. The
+// error wraps domain.ErrAuditChainBroken at the first entry that does not
+// match.
+func (s *auditService) Verify(ctx context.Context) (int, error) {
+	checked := 0
+	prevHash := ""
+	var afterID int64
+	for {
+		entries, err := s.entries.ListAfter(ctx, afterID, auditVerifyBatch)
+		if err != nil {
+			return checked, err
+		}
+		for idx := range entries {
+			if err := entries[idx].VerifyAfter(prevHash); err != nil {
+				return checked, err
+			}
+			prevHash = entries[idx].Hash
+			afterID = entries[idx].ID
+			checked++
+		}
+		if len(entries) < auditVerifyBatch {
+			return checked, nil
+		}
+	}
+}
+
+// recordAudit appends an entry for a change to target. Call it inside the
+// same transaction as the change; only fields that differ between before and
+// after are stored.
+func recordAudit(ctx context.Context, audit repo.AuditLogRepository, action domain.AuditAction, target string, before, after map[string]interface{}) error {
+	if audit == nil {
+		return nil
+	}
+	changes := map[string]domain.AuditChange{}
+	for key, value := range after {
+		if previous, ok := before[key]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[key] = domain.AuditChange{Before: previous, After: value}
+		}
+	}
+	for key, previous := range before {
+		if _, ok := after[key]; !ok {
+			changes[key] = domain.AuditChange{Before: previous}
+		}
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	actor := auditActorFrom(ctx)
+	return audit.Append(ctx, &domain.AuditEntry{
+		ActorID:      optionalString(actor.ID),
+		TargetUserID: optionalString(target),
+		Action:       action,
+		Changes:      encoded,
+		RequestID:    optionalString(actor.RequestID),
+		IP:           optionalString(actor.IP),
+		UserAgent:    optionalString(actor.UserAgent),
+	})
+}
+
+// auditSnapshot captures the audited fields of a user. Unset optional
+// fields are left out.
+func auditSnapshot(user *domain.User) map[string]interface{} {
+	snapshot := map[string]interface{}{
+		"email":     user.Email,
+		"status":    string(user.StatusOrDefault()),
+		"is_active": user.IsActive,
+	}
+	if user.SuspendedUntil != nil {
+		snapshot["suspended_until"] = *user.SuspendedUntil
+	}
+	if user.SuspensionReason != nil {
+		snapshot["suspension_reason"] = string(*user.SuspensionReason)
+	}
+	if profile := user.Profile; profile != nil {
+		if profile.DisplayName != nil {
+			snapshot["display_name"] = *profile.DisplayName
+		}
+		if profile.AvatarFileID != nil {
+			snapshot["avatar_file_id"] = *profile.AvatarFileID
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/domain"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+// MaxBulkItems caps how many users a single bulk job may target.
+const MaxBulkItems = 500
+
+// errInvalidBulkUserID fails the items whose ID is not a UUID; they cannot
+// match a user, and the other items of the job still run.
+var errInvalidBulkUserID = domain.Invalid("user_ids", "invalid user id")
+
+const (
+	bulkBatch      = 5
+	bulkStaleAfter = 10 * time.Minute
+)
+
+// BulkService runs admin actions over many users as tracked jobs. Each item
+// goes through UserManageService or AccountDeletionService so bulk changes
+// follow the same rules, history and RBAC assignment as single-user calls.
+type BulkService interface {
+	Submit(ctx context.Context, req BulkRequest) (*domain.BulkJob, error)
+	Get(ctx context.Context, jobID string) (*domain.BulkJob, error)
+	ProcessPending(ctx context.Context) (int, error)
+}
+
+// BulkRequest targets either explicit UserIDs or every user matching Filter,
+// which takes the list filters inline; there is no store of saved filters.
+// DryRun validates each item without applying anything.
+type BulkRequest struct {
+	Action  domain.BulkAction
+	UserIDs []string
+	Filter  *domain.UserQuery
+	Status  domain.UserStatus
+	Reason  string
+	Role    string
+	DryRun  bool
+	ActorID string
+}
+
+type bulkService struct {
+	jobs     repo.BulkJobRepository
+	users    repo.UserRepository
+	manage   UserManageService
+	deletion AccountDeletionService
+	tx       repo.TxManager
+}
+
+func NewBulkService(jobs repo.BulkJobRepository, users repo.UserRepository, manage UserManageService, deletion AccountDeletionService, tx repo.TxManager) BulkService {
+	return &bulkService{jobs: jobs, users: users, manage: manage, deletion: deletion, tx: tx}
+}
+
+// Submit stores the job. Dry runs are evaluated immediately and come back
+// finished; real jobs are left PENDING for ProcessPending.
+func (s *bulkService) Submit(ctx context.Context, req BulkRequest) (*domain.BulkJob, error) {
+	job := &domain.BulkJob{
+		Action: req.Action,
+		Status: domain.BulkJobPending,
+		DryRun: req.DryRun,
+	}
+	switch req.Action {
+	case domain.BulkActionChangeStatus:
+		status := domain.UserStatus(strings.ToUpper(strings.TrimSpace(string(req.Status))))
+		job.TargetStatus = &status
+		job.Reason = optionalString(strings.TrimSpace(req.Reason))
+	case domain.BulkActionAssignRole:
+		job.Role = optionalString(strings.TrimSpace(req.Role))
+	}
+	job.CreatedBy = optionalString(req.ActorID)
+	if err := job.Validate(); err != nil {
+		return nil, err
+	}
+	if job.Role != nil {
+		// Apply the ChangeRole rules up front, so dry runs report them too.
+		if _, err := validateRole(*job.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	userIDs, err := s.resolveTargets(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	job.Total = len(userIDs)
+	job.Items = make([]domain.BulkJobItem, 0, len(userIDs))
+	for _, id := range userIDs {
+		item := domain.BulkJobItem{UserID: id, Status: domain.BulkItemPending}
+		if _, err := uuid.Parse(id); err != nil {
+			job.Record(&item, errInvalidBulkUserID)
+		}
+		job.Items = append(job.Items, item)
+	}
+
+	if req.DryRun {
+		for idx := range job.Items {
+			if job.Items[idx].Status == domain.BulkItemPending {
+				job.Record(&job.Items[idx], bulkItemError(s.check(ctx, job, job.Items[idx].UserID)))
+			}
+		}
+		job.Finish(time.Now())
+	}
+	if err := s.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *bulkService) Get(ctx context.Context, jobID string) (*domain.BulkJob, error) {
+	return s.jobs.FindByID(ctx, jobID)
+}
+
+// ProcessPending claims runnable jobs and applies their pending items. Items
+// already finished by a crashed worker are not repeated.
+func (s *bulkService) ProcessPending(ctx context.Context) (int, error) {
+	var claimed []domain.BulkJob
+	err := withinTx(ctx, s.tx, func(ctx context.Context) error {
+		jobs, err := s.jobs.LockRunnable(ctx, time.Now().UTC().Add(-bulkStaleAfter), bulkBatch)
+		if err != nil {
+			return err
+		}
+		for idx := range jobs {
+			jobs[idx].Status = domain.BulkJobRunning
+			if err := s.jobs.Update(ctx, &jobs[idx]); err != nil {
+				return err
+			}
+		}
+		claimed = jobs
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for idx := range claimed {
+		if err := s.run(ctx, &claimed[idx]); err != nil {
+			return idx, err
+		}
+	}
+	return len(claimed), nil
+}
+
+func (s *bulkService) run(ctx context.Context, job *domain.BulkJob) error {
+	logger := pkglog.FromContext(ctx)
+	for idx := range job.Items {
+		item := &job.Items[idx]
+		if item.Status != domain.BulkItemPending {
+			continue
+		}
+		err := bulkItemError(s.apply(ctx, job, item.UserID))
+		if err != nil {
+			logger.Warn().Err(err).Str("job_id", job.ID).Str("user_id", item.UserID).Msg("bulk item failed")
+		}
+		job.Record(item, err)
+		if err := s.jobs.UpdateItem(ctx, item); err != nil {
+			return err
+		}
+		// Touch the job so a long run is not mistaken for a stale one.
+		if err := s.jobs.Update(ctx, job); err != nil {
+			return err
+		}
+	}
+	job.Finish(time.Now())
+	return s.jobs.Update(ctx, job)
+}
+
+func (s *bulkService) apply(ctx context.Context, job *domain.BulkJob, userID string) error {
+	actorID := ""
+	if job.CreatedBy != nil {
+		actorID = *job.CreatedBy
+	}
+	ctx = WithAuditActor(ctx, domain.AuditActor{ID: actorID, RequestID: "bulk:" + job.ID})
+	switch job.Action {
+	case domain.BulkActionChangeStatus:
+		_, err := s.manage.ChangeStatus(ctx, userID, ChangeStatusRequest{Status: *job.TargetStatus, Reason: *job.Reason, ActorID: actorID})
+		return err
+	case domain.BulkActionAssignRole:
+		return s.manage.ChangeRole(ctx, userID, *job.Role)
+	case domain.BulkActionDelete:
+		if s.deletion == nil {
+			return fmt.Errorf("account deletion not configured")
+		}
+		_, err := s.deletion.Delete(ctx, userID, actorID)
+		return err
+	}
+	return fmt.Errorf("invalid bulk action")
+}
+
+// bulkItemError reports a missing user as not found rather than as an
+// internal error once the item is recorded.
+func bulkItemError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.NotFound("user")
+	}
+	return err
+}
+
+// check reports whether apply would succeed for userID without changing it.
+func (s *bulkService) check(ctx context.Context, job *domain.BulkJob, userID string) error {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	switch job.Action {
+	case domain.BulkActionChangeStatus:
+		if *job.TargetStatus == domain.UserStatusSuspended {
+			return domain.Invalid("target_status", "use suspend to suspend a user")
+		}
+		return domain.CanTransition(user.StatusOrDefault(), *job.TargetStatus, *job.Reason)
+	case domain.BulkActionDelete:
+		if user.IsDeleted() {
+			return domain.ErrAlreadyDeleted
+		}
+	}
+	return nil
+}
+
+func (s *bulkService) resolveTargets(ctx context.Context, req BulkRequest) ([]string, error) {
+	if len(req.UserIDs) > 0 && req.Filter != nil {
+		return nil, domain.Invalid("filter", "user_ids and filter are mutually exclusive")
+	}
+	var ids []string
+	if req.Filter != nil {
+		query := *req.Filter
+		query.Offset = 0
+		query.Limit = MaxBulkItems + 1
+		if query.SortBy == "" {
+			query.SortBy = domain.UserSortCreatedAt
+		}
+		if err := query.Validate(); err != nil {
+			return nil, err
+		}
+		users, _, err := s.users.List(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			ids = append(ids, u.ID)
+		}
+	} else {
+		seen := make(map[string]struct{}, len(req.UserIDs))
+		for _, raw := range req.UserIDs {
+			id := strings.TrimSpace(raw)
+			if id == "" {
+				continue
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, domain.Invalid("user_ids", "no users selected")
+	}
+	if len(ids) > MaxBulkItems {
+		return nil, domain.Invalid("user_ids", fmt.Sprintf("bulk jobs are limited to %d users", MaxBulkItems))
+	}
+	return ids, nil
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/adapters/filestorage"
+	"github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/domain"
+)
+
+// DataExportService builds personal-data exports asynchronously: requests
+// create a job, ProcessPending assembles and uploads the archive.
+type DataExportService interface {
+	RequestExport(ctx context.Context, userID string, format domain.ExportFormat) (*domain.ExportJob, error)
+	GetExport(ctx context.Context, userID, jobID string) (*ExportResult, error)
+	ProcessPending(ctx context.Context) (int, error)
+}
+
+type ExportResult struct {
+	Job         *domain.ExportJob
+	DownloadURL string
+}
+
+type DataExportConfig struct {
+	FileKind   string
+	URLTTL     time.Duration
+	StaleAfter time.Duration
+	BatchSize  int
+}
+
+type dataExportService struct {
+	jobs       repo.ExportJobRepository
+	users      repo.UserRepository
+	identities repo.UserIdentityRepository
+	providers  repo.UserProviderRepository
+	history    repo.UserStatusHistoryRepository
+	storage    filestorage.Client
+	tx         repo.TxManager
+	cfg        DataExportConfig
+}
+
+func NewDataExportService(jobs repo.ExportJobRepository, users repo.UserRepository, identities repo.UserIdentityRepository, providers repo.UserProviderRepository, history repo.UserStatusHistoryRepository, storage filestorage.Client, tx repo.TxManager, cfg DataExportConfig) DataExportService {
+	if cfg.FileKind == "" {
+		cfg.FileKind = "USER_EXPORT"
+	}
+	if cfg.URLTTL <= 0 {
+		cfg.URLTTL = 15 * time.Minute
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 10 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 10
+	}
+	return &dataExportService{
+		jobs:       jobs,
+		users:      users,
+		identities: identities,
+		providers:  providers,
+		history:    history,
+		storage:    storage,
+		tx:         tx,
+		cfg:        cfg,
+	}
+}
+
+func (s *dataExportService) RequestExport(ctx context.Context, userID string, format domain.ExportFormat) (*domain.ExportJob, error) {
+	if format == "" {
+		format = domain.ExportFormatJSON
+	}
+	if !format.IsValid() {
+		return nil, domain.ErrInvalidExportFormat
+	}
+	// Only one export per user runs at a time; repeated requests get the
+	// job that is already queued.
+	if job, err := s.jobs.FindActiveByUser(ctx, userID); err == nil {
+		return job, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	job := &domain.ExportJob{UserID: userID, Status: domain.ExportStatusPending, Format: format}
+	if err := s.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *dataExportService) GetExport(ctx context.Context, userID, jobID string) (*ExportResult, error) {
+	job, err := s.jobs.FindByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	result := &ExportResult{Job: job}
+	if job.Status == domain.ExportStatusCompleted && job.FileID != nil && s.storage != nil {
+		url, err := s.storage.SignedURL(ctx, *job.FileID, int64(s.cfg.URLTTL/time.Minute))
+		if err != nil {
+			return nil, err
+		}
+		result.DownloadURL = url
+	}
+	return result, nil
+}
+
+// ProcessPending claims a batch of runnable jobs and builds their archives.
+// It returns the number of jobs that finished, successfully or not.
+func (s *dataExportService) ProcessPending(ctx context.Context) (int, error) {
+	var claimed []domain.ExportJob
+	err := withinTx(ctx, s.tx, func(ctx context.Context) error {
+		jobs, err := s.jobs.LockRunnable(ctx, time.Now().UTC().Add(-s.cfg.StaleAfter), s.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		for idx := range jobs {
+			jobs[idx].Status = domain.ExportStatusRunning
+			if err := s.jobs.Update(ctx, &jobs[idx]); err != nil {
+				return err
+			}
+		}
+		claimed = jobs
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for idx := range claimed {
+		job := &claimed[idx]
+		fileID, err := s.buildAndUpload(ctx, job)
+		if err != nil {
+			job.Fail(err, time.Now())
+		} else {
+			job.Complete(fileID, time.Now())
+		}
+		if err := s.jobs.Update(ctx, job); err != nil {
+			return idx, err
+		}
+	}
+	return len(claimed), nil
+}
+
+func (s *dataExportService) buildAndUpload(ctx context.Context, job *domain.ExportJob) (string, error) {
+	if s.storage == nil {
+		return "", fmt.Errorf("file storage not configured")
+	}
+	export, err := s.collect(ctx, job.UserID)
+	if err != nil {
+		return "", err
+	}
+	document, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	data, contentType := document, "application/json"
+	if job.Format == domain.ExportFormatZIP {
+		if data, err = zipDocument("user-data.json", document); err != nil {
+			return "", err
+		}
+		contentType = "application/zip"
+	}
+	uploaded, err := s.storage.Upload(ctx, filestorage.UploadRequest{
+		OwnerID:     job.UserID,
+		FileKind:    s.cfg.FileKind,
+		FileName:    fmt.Sprintf("user-export-%s.%s", job.ID, job.Format),
+		ContentType: contentType,
+		Data:        data,
+	})
+	if err != nil {
+		return "", err
+	}
+	return uploaded.ID, nil
+}
+
+func (s *dataExportService) collect(ctx context.Context, userID string) (*domain.UserDataExport, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := s.identities.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := s.providers.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var history []domain.UserStatusHistory
+	if s.history != nil {
+		if history, err = s.history.ListByUser(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	profile := user.Profile
+	user.Profile = nil
+	return &domain.UserDataExport{
+		ExportedAt:    time.Now().UTC(),
+		User:          user,
+		Profile:       profile,
+		Identities:    nonNil(identities),
+		Providers:     nonNil(providers),
+		StatusHistory: nonNil(history),
+	}, nil
+}
+
+func zipDocument(name string, document []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	w, err := archive.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(document); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
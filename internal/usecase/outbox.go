@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/events"
+	"github.com/example/user-service/pkg/tracing"
+)
+
+func withinTx(ctx context.Context, tx repo.TxManager, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.WithinTx(ctx, fn)
+}
+
+// recordEvent stores the event in the outbox; call it inside the same
+// transaction as the change it describes.
+func recordEvent(ctx context.Context, outbox repo.OutboxRepository, event, userID, email string) error {
+	if outbox == nil {
+		return nil
+	}
+	return outbox.Enqueue(ctx, events.NewUserEvent(event, userID, email, tracing.TraceID(ctx)))
+}
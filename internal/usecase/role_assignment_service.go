@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/adapters/rbac"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+const (
+	// roleAssignmentGrace keeps the worker away from a queued role while the
+	// request that queued it makes the first attempt after commit.
+	roleAssignmentGrace = 30 * time.Second
+	// roleAssignmentLease keeps other workers away from a claimed role while
+	// RBAC is called.
+	roleAssignmentLease   = time.Minute
+	maxRoleAssignmentWait = 5 * time.Minute
+)
+
+// RoleAssignmentService retries RBAC role assignments that could not be
+// made right after their transaction committed.
+type RoleAssignmentService interface {
+	ProcessPending(ctx context.Context) (int, error)
+	// CountDead counts the assignments that ran out of attempts.
+	CountDead(ctx context.Context) (int64, error)
+}
+
+type RoleAssignmentConfig struct {
+	BatchSize    int
+	MaxAttempts  int
+	RetryBackoff time.Duration
+}
+
+type roleAssignmentService struct {
+	assigner roleAssigner
+	cfg      RoleAssignmentConfig
+}
+
+func NewRoleAssignmentService(roles repo.RoleAssignmentRepository, rbacClient rbac.Client, users repo.UserRepository, outbox repo.OutboxRepository, cfg RoleAssignmentConfig) RoleAssignmentService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 5 * time.Second
+	}
+	return &roleAssignmentService{assigner: roleAssigner{roles: roles, rbac: rbacClient, users: users, outbox: outbox}, cfg: cfg}
+}
+
+// ProcessPending assigns one batch of due roles and returns how many were
+// picked up. The batch is claimed with a lease instead of row locks, so no
+// transaction is open while RBAC is called. Failed assignments are retried
+// with exponential backoff until MaxAttempts is reached.
+func (s *roleAssignmentService) ProcessPending(ctx context.Context) (int, error) {
+	pending, err := s.assigner.roles.Claim(ctx, s.cfg.BatchSize, s.cfg.MaxAttempts, time.Now().UTC().Add(roleAssignmentLease))
+	if err != nil {
+		return 0, err
+	}
+	for i := range pending {
+		a := &pending[i]
+		if err := s.assigner.assign(ctx, a, time.Now().UTC().Add(s.backoff(a.Attempts+1))); err != nil {
+			return len(pending), err
+		}
+	}
+	return len(pending), nil
+}
+
+func (s *roleAssignmentService) CountDead(ctx context.Context) (int64, error) {
+	if s.cfg.MaxAttempts <= 0 {
+		return 0, nil
+	}
+	return s.assigner.roles.CountDead(ctx, s.cfg.MaxAttempts)
+}
+
+func (s *roleAssignmentService) backoff(attempt int) time.Duration {
+	delay := s.cfg.RetryBackoff
+	for i := 1; i < attempt && delay < maxRoleAssignmentWait; i++ {
+		delay *= 2
+	}
+	if delay > maxRoleAssignmentWait {
+		delay = maxRoleAssignmentWait
+	}
+	return delay
+}
+
+// roleAssigner sends queued roles to RBAC outside any transaction.
+type roleAssigner struct {
+	roles  repo.RoleAssignmentRepository
+	rbac   rbac.Client
+	users  repo.UserRepository
+	outbox repo.OutboxRepository
+}
+
+// assign sends a claimed role to RBAC. An RBAC failure is recorded on the row
+// with retryAt as its next attempt and is not returned. role_changed is only
+// recorded once RBAC has the role, so caches evicted by it reload the new
+// one; that includes the first role of a new user, which may have been
+// looked up before it was assigned.
+func (r roleAssigner) assign(ctx context.Context, a *domain.RoleAssignment, retryAt time.Time) error {
+	logger := pkglog.FromContext(ctx)
+	if err := r.rbac.AssignRole(ctx, a.UserID, a.Role); err != nil {
+		logger.Warn().Err(err).Str("assignment_id", a.ID).Int("attempts", a.Attempts+1).Msg("role assignment failed")
+		return r.roles.MarkFailed(ctx, a.ID, err, retryAt)
+	}
+	latest, err := r.roles.MarkAssigned(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	if !latest {
+		// A newer role was queued while this one was sent and may have
+		// reached RBAC first; send it again so it wins.
+		logger.Info().Str("assignment_id", a.ID).Msg("role assignment superseded")
+		return r.roles.RequeueLatest(ctx, a.UserID)
+	}
+	email := ""
+	if r.users != nil {
+		if user, err := r.users.FindByIDIncludingDeleted(ctx, a.UserID); err == nil {
+			email = user.Email
+		}
+	}
+	return recordEvent(ctx, r.outbox, events.UserRoleChanged, a.UserID, email)
+}
+
+// assignRole queues role for the user in the current transaction and tries
+// to assign it once that transaction has committed. An RBAC failure then
+// leaves the user change in place and the role to RoleAssignmentService.
+// Without a queue the role is assigned directly, so a failure rolls the
+// transaction back.
+func (s *userManageService) assignRole(ctx context.Context, userID, role string) error {
+	if s.roles == nil {
+		return s.rbac.AssignRole(ctx, userID, role)
+	}
+	assignment, err := s.roles.Enqueue(ctx, userID, role, time.Now().UTC().Add(roleAssignmentGrace))
+	if err != nil {
+		return err
+	}
+	repo.AfterCommit(ctx, func(ctx context.Context) {
+		s.attemptAssignment(ctx, assignment)
+	})
+	return nil
+}
+
+// attemptAssignment leaves the row to the worker whenever something fails;
+// the grace period already delays its next attempt. A role replaced before
+// this runs is skipped, so it cannot reach RBAC after the newer one.
+func (s *userManageService) attemptAssignment(ctx context.Context, assignment *domain.RoleAssignment) {
+	logger := pkglog.FromContext(ctx)
+	claimed, err := s.roles.ClaimOne(ctx, assignment.ID, assignment.NextAttemptAt)
+	if err != nil {
+		logger.Error().Err(err).Str("assignment_id", assignment.ID).Msg("claim role assignment")
+		return
+	}
+	if !claimed {
+		return
+	}
+	assigner := roleAssigner{roles: s.roles, rbac: s.rbac, users: s.users, outbox: s.outbox}
+	if err := assigner.assign(ctx, assignment, assignment.NextAttemptAt); err != nil {
+		logger.Error().Err(err).Str("assignment_id", assignment.ID).Msg("record role assignment")
+	}
+}
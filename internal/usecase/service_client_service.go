@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/domain"
+)
+
+// apiKeyPrefix marks keys issued by this service, so leaked keys are easy to
+// recognise in logs and secret scanners.
+const apiKeyPrefix = "usk"
+
+// lastUsedResolution limits last_used_at writes to one per client per
+// interval instead of one per request.
+const lastUsedResolution = time.Minute
+
+// ServiceClientService manages API keys of internal callers. Plain keys are
+// only returned by Issue and Rotate; afterwards only their hash is known.
+type ServiceClientService interface {
+	Issue(ctx context.Context, req IssueServiceClientRequest) (*domain.ServiceClient, string, error)
+	Rotate(ctx context.Context, id string) (*domain.ServiceClient, string, error)
+	Revoke(ctx context.Context, id string) (*domain.ServiceClient, error)
+	List(ctx context.Context) ([]domain.ServiceClient, error)
+	Authenticate(ctx context.Context, key string) (*domain.ServiceClient, error)
+}
+
+type IssueServiceClientRequest struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+	ActorID   string
+}
+
+type serviceClientService struct {
+	clients repo.ServiceClientRepository
+	now     func() time.Time
+}
+
+func NewServiceClientService(clients repo.ServiceClientRepository) ServiceClientService {
+	return &serviceClientService{clients: clients, now: time.Now}
+}
+
+func (s *serviceClientService) Issue(ctx context.Context, req IssueServiceClientRequest) (*domain.ServiceClient, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", domain.Invalid("name", "name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, "", domain.Invalid("scopes", "at least one scope is required")
+	}
+	scopes := make(domain.StringList, 0, len(req.Scopes))
+	seen := map[string]bool{}
+	for _, scope := range req.Scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !domain.IsValidScope(scope) {
+			return nil, "", fmt.Errorf("%w: %q", domain.ErrInvalidScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, "", domain.Invalid("expires_at", "expires_at must be in the future")
+	}
+	client := &domain.ServiceClient{
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: optionalString(req.ActorID),
+	}
+	key, err := assignAPIKey(client)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.clients.Create(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, key, nil
+}
+
+// Rotate replaces the key of a client; the previous key stops working
+// immediately.
+func (s *serviceClientService) Rotate(ctx context.Context, id string) (*domain.ServiceClient, string, error) {
+	client, err := s.clients.FindByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if client.RevokedAt != nil {
+		return nil, "", domain.ErrServiceClientRevoked
+	}
+	key, err := assignAPIKey(client)
+	if err != nil {
+		return nil, "", err
+	}
+	client.LastUsedAt = nil
+	if err := s.clients.Update(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, key, nil
+}
+
+func (s *serviceClientService) Revoke(ctx context.Context, id string) (*domain.ServiceClient, error) {
+	client, err := s.clients.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if client.RevokedAt == nil {
+		now := s.now().UTC()
+		client.RevokedAt = &now
+		if err := s.clients.Update(ctx, client); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+func (s *serviceClientService) List(ctx context.Context) ([]domain.ServiceClient, error) {
+	return s.clients.List(ctx)
+}
+
+// Authenticate resolves a key to its client. Unknown, revoked and expired keys
+// all return domain.ErrInvalidAPIKey.
+func (s *serviceClientService) Authenticate(ctx context.Context, key string) (*domain.ServiceClient, error) {
+	prefix, ok := apiKeyLookupPrefix(key)
+	if !ok {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	client, err := s.clients.FindByKeyPrefix(ctx, prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(client.KeyHash)) != 1 {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	now := s.now().UTC()
+	if !client.Usable(now) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if client.LastUsedAt == nil || now.Sub(*client.LastUsedAt) >= lastUsedResolution {
+		// Usage tracking is best effort and must not fail the request.
+		if err := s.clients.TouchLastUsed(ctx, client.ID, now); err == nil {
+			client.LastUsedAt = &now
+		}
+	}
+	return client, nil
+}
+
+// assignAPIKey generates a key of the form usk_<prefix>_<secret> and stores
+// its prefix and hash on the client.
+func assignAPIKey(client *domain.ServiceClient) (string, error) {
+	prefix := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	client.KeyPrefix = hex.EncodeToString(prefix)
+	key := apiKeyPrefix + "_" + client.KeyPrefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	client.KeyHash = hashAPIKey(key)
+	return key, nil
+}
+
+func apiKeyLookupPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashAPIKey needs no salt or stretching: keys carry 256 random bits.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
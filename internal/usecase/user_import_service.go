@@ -0,0 +1,297 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/adapters/postgres"
+	"github.com/example/user-service/internal/adapters/rbac"
+	"github.com/example/user-service/internal/domain"
+)
+
+// importStatusReason is stored in the status history for changes made by an
+// import.
+const importStatusReason = "user import"
+
+// maxImportLine bounds a single NDJSON record.
+const maxImportLine = 64 * 1024
+
+// UserImportService upserts users by email from an uploaded file. Rows are
+// read and reported one at a time so the file is never held in memory.
+type UserImportService interface {
+	Import(ctx context.Context, rows ImportReader, opts ImportOptions, report func(domain.ImportResult) error) error
+}
+
+type ImportOptions struct {
+	DryRun  bool
+	ActorID string
+}
+
+// ImportReader yields rows until io.EOF. Errors wrapping
+// domain.ErrMalformedImportRow only affect the returned row.
+type ImportReader interface {
+	Next() (domain.ImportRow, error)
+}
+
+type userImportService struct {
+	manage *userManageService
+}
+
+// NewUserImportService validates and stores rows with the same rules and side
+// effects (history, outbox events, audit log, RBAC) as UserManageService.
+func NewUserImportService(users repo.UserRepository, profiles repo.UserProfileRepository, history repo.UserStatusHistoryRepository, rbacClient rbac.Client, tx repo.TxManager, outbox repo.OutboxRepository, audit repo.AuditLogRepository, roles repo.RoleAssignmentRepository) UserImportService {
+	return &userImportService{manage: &userManageService{users: users, profiles: profiles, history: history, rbac: rbacClient, tx: tx, outbox: outbox, audit: audit, roles: roles}}
+}
+
+// Import processes every row and passes its result to report. Row failures
+// are reported and skipped; only read errors, a failing report callback or a
+// cancelled context stop the import.
+func (s *userImportService) Import(ctx context.Context, rows ImportReader, opts ImportOptions, report func(domain.ImportResult) error) error {
+	seen := make(map[string]int)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		result := domain.ImportResult{Line: row.Line, Email: row.Email}
+		switch {
+		case errors.Is(err, domain.ErrMalformedImportRow):
+			// Decoding errors only describe the client's own file.
+			result.Outcome = domain.ImportFailed
+			result.Error = err.Error()
+		case err != nil:
+			return err
+		default:
+			result = s.importRow(ctx, row, opts, seen)
+		}
+		if err := report(result); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *userImportService) importRow(ctx context.Context, row domain.ImportRow, opts ImportOptions, seen map[string]int) domain.ImportResult {
+	result := domain.ImportResult{Line: row.Line, Email: strings.TrimSpace(row.Email)}
+	requested := domain.UserStatus(strings.ToUpper(strings.TrimSpace(row.Status)))
+	email, role, status, err := validateNewUser(row.Email, row.Role, requested)
+	if err != nil {
+		result.Fail(err)
+		return result
+	}
+	result.Email = email
+	if first, ok := seen[email]; ok {
+		result.Fail(domain.Invalid("email", fmt.Sprintf("duplicate email, first seen on line %d", first)))
+		return result
+	}
+	seen[email] = row.Line
+
+	user, err := s.manage.users.FindByEmail(ctx, email)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = s.create(ctx, &result, email, role, status, row.DisplayName, opts)
+		if errors.Is(err, domain.ErrEmailInUse) {
+			// Another request created the user since the lookup; the
+			// unique email index keeps it single, so update it instead.
+			if user, err = s.manage.users.FindByEmail(ctx, email); err == nil {
+				result.UserID = user.ID
+				err = s.update(ctx, &result, user, role, requested, row.DisplayName, opts)
+			}
+		}
+	case err != nil:
+	default:
+		result.UserID = user.ID
+		err = s.update(ctx, &result, user, role, requested, row.DisplayName, opts)
+	}
+	if err != nil {
+		result.Fail(err)
+	}
+	return result
+}
+
+func (s *userImportService) create(ctx context.Context, result *domain.ImportResult, email, role string, status domain.UserStatus, displayName string, opts ImportOptions) error {
+	result.Outcome = domain.ImportCreated
+	user, err := domain.NewUser(email, status)
+	if err != nil || opts.DryRun {
+		return err
+	}
+	profile := &domain.UserProfile{DisplayName: optionalString(displayName)}
+	if err := s.manage.insert(ctx, user, profile, role); err != nil {
+		return err
+	}
+	result.UserID = user.ID
+	return nil
+}
+
+// update brings an existing user in line with the row. Empty status and
+// display name columns leave the current values alone.
+func (s *userImportService) update(ctx context.Context, result *domain.ImportResult, user *domain.User, role string, status domain.UserStatus, displayName string, opts ImportOptions) error {
+	displayName = strings.TrimSpace(displayName)
+	renamed := false
+	if displayName != "" {
+		profile, err := s.manage.profiles.FindByUserID(ctx, user.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		renamed = profile == nil || profile.DisplayName == nil || *profile.DisplayName != displayName
+	}
+	restatus := status != "" && status != user.StatusOrDefault()
+	if restatus {
+		if err := domain.CanTransition(user.StatusOrDefault(), status, importStatusReason); err != nil {
+			return err
+		}
+	}
+	if s.manage.rbac == nil {
+		return fmt.Errorf("rbac client not configured")
+	}
+	current, err := s.manage.rbac.GetRoleByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	reassign := !strings.EqualFold(current, role)
+
+	if !renamed && !restatus && !reassign {
+		result.Outcome = domain.ImportUnchanged
+		return nil
+	}
+	result.Outcome = domain.ImportUpdated
+	if opts.DryRun {
+		return nil
+	}
+	return withinTx(ctx, s.manage.tx, func(ctx context.Context) error {
+		if renamed {
+			if _, err := s.manage.UpdateUser(ctx, user.ID, UpdateUserRequest{DisplayName: &displayName}); err != nil {
+				return err
+			}
+		}
+		if restatus {
+			if _, err := s.manage.ChangeStatus(ctx, user.ID, ChangeStatusRequest{Status: status, Reason: importStatusReason, ActorID: opts.ActorID}); err != nil {
+				return err
+			}
+		}
+		if reassign {
+			// The role was looked up above; ChangeRole would ask RBAC again
+			// inside the transaction.
+			return s.manage.writeRoleChange(ctx, user, current, role)
+		}
+		return nil
+	})
+}
+
+// NewImportReader decodes rows of the given format from r. CSV input must
+// start with a header naming its columns; the header is checked here so a
+// bad file is rejected before anything is imported.
+func NewImportReader(r io.Reader, format domain.ImportFormat) (ImportReader, error) {
+	switch format {
+	case domain.ImportFormatCSV:
+		reader, err := newCSVImportReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return reader, nil
+	case domain.ImportFormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 4096), maxImportLine)
+		return &ndjsonImportReader{scanner: scanner}, nil
+	}
+	return nil, domain.ErrInvalidImportFormat
+}
+
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, domain.Invalid("file", "csv header is missing")
+	}
+	if err != nil {
+		return nil, domain.Invalid("file", fmt.Sprintf("invalid csv header: %v", err))
+	}
+	columns := make(map[string]int, len(header))
+	for idx, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "email", "display_name", "role", "status":
+		default:
+			return nil, domain.Invalid("file", fmt.Sprintf("unknown csv column %q", name))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, domain.Invalid("file", fmt.Sprintf("duplicate csv column %q", name))
+		}
+		columns[name] = idx
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, domain.Invalid("file", "csv header must include email")
+	}
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvImportReader) Next() (domain.ImportRow, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return domain.ImportRow{Line: parseErr.StartLine}, fmt.Errorf("%w: %v", domain.ErrMalformedImportRow, parseErr.Err)
+		}
+		return domain.ImportRow{}, err
+	}
+	line, _ := r.reader.FieldPos(0)
+	field := func(name string) string {
+		if idx, ok := r.columns[name]; ok && idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+		return ""
+	}
+	return domain.ImportRow{
+		Line:        line,
+		Email:       field("email"),
+		DisplayName: field("display_name"),
+		Role:        field("role"),
+		Status:      field("status"),
+	}, nil
+}
+
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonImportReader) Next() (domain.ImportRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		row := domain.ImportRow{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			return domain.ImportRow{Line: r.line}, fmt.Errorf("%w: %v", domain.ErrMalformedImportRow, err)
+		}
+		row.Line = r.line
+		return row, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return domain.ImportRow{Line: r.line + 1}, err
+	}
+	return domain.ImportRow{}, io.EOF
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -12,6 +14,8 @@ import (
 	"github.com/example/user-service/internal/adapters/postgres"
 	"github.com/example/user-service/internal/adapters/rbac"
 	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+	pkglog "github.com/example/user-service/pkg/log"
 )
 
 type (
@@ -20,9 +24,16 @@ type (
 		GetUser(ctx context.Context, userID string) (*domain.User, error)
 		CreateUser(ctx context.Context, req CreateUserRequest) (*domain.User, error)
 		UpdateUser(ctx context.Context, userID string, req UpdateUserRequest) (*domain.User, error)
-		ChangeStatus(ctx context.Context, userID string, status domain.UserStatus) (*domain.User, error)
+		UpdateUserForService(ctx context.Context, userID string, req UpdateUserRequest) (*domain.User, error)
+		ChangeStatus(ctx context.Context, userID string, req ChangeStatusRequest) (*domain.User, error)
+		StatusHistory(ctx context.Context, userID string) ([]domain.UserStatusHistory, error)
+		Suspend(ctx context.Context, userID string, req SuspendRequest) (*domain.User, error)
+		LiftSuspension(ctx context.Context, userID string, req LiftSuspensionRequest) (*domain.User, error)
+		ReactivateExpiredSuspensions(ctx context.Context, now time.Time) (int, error)
 		ChangeRole(ctx context.Context, userID, role string) error
-		ListUsers(ctx context.Context, offset, limit int) ([]domain.User, int64, error)
+		ListUsers(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error)
+		ListUsersByCursor(ctx context.Context, query domain.UserQuery) (*domain.UserPage, error)
+		StreamUsers(ctx context.Context, query domain.UserQuery, fn func(*domain.User) error) error
 	}
 
 	CreateUserRequest struct {
@@ -39,17 +50,53 @@ type (
 		Password     *string
 		DisplayName  *string
 		AvatarFileID *string
+		// IfMatch, when set, must list the current ETag of the user.
+		IfMatch string
+	}
+
+	ChangeStatusRequest struct {
+		Status  domain.UserStatus
+		Reason  string
+		ActorID string
+		IfMatch string
+	}
+
+	SuspendRequest struct {
+		Until   time.Time
+		Reason  domain.SuspensionReason
+		Note    string
+		ActorID string
+	}
+
+	LiftSuspensionRequest struct {
+		Reason  string
+		ActorID string
 	}
 )
 
+const (
+	expiredSuspensionBatch = 100
+	streamBatch            = 500
+)
+
 type userManageService struct {
 	users    repo.UserRepository
 	profiles repo.UserProfileRepository
+	history  repo.UserStatusHistoryRepository
 	rbac     rbac.Client
+	tx       repo.TxManager
+	outbox   repo.OutboxRepository
+	audit    repo.AuditLogRepository
+	roles    repo.RoleAssignmentRepository
 }
 
-func NewUserManageService(users repo.UserRepository, profiles repo.UserProfileRepository, rbacClient rbac.Client) UserManageService {
-	return &userManageService{users: users, profiles: profiles, rbac: rbacClient}
+// NewUserManageService builds the admin service. Every mutation is written to
+// the audit log with the actor attached via WithAuditActor. With a roles
+// queue, RBAC roles are assigned after commit and retried by
+// RoleAssignmentService; without one they are assigned inside the
+// transaction.
+func NewUserManageService(users repo.UserRepository, profiles repo.UserProfileRepository, history repo.UserStatusHistoryRepository, rbacClient rbac.Client, tx repo.TxManager, outbox repo.OutboxRepository, audit repo.AuditLogRepository, roles repo.RoleAssignmentRepository) UserManageService {
+	return &userManageService{users: users, profiles: profiles, history: history, rbac: rbacClient, tx: tx, outbox: outbox, audit: audit, roles: roles}
 }
 
 func (s *userManageService) GetUser(ctx context.Context, userID string) (*domain.User, error) {
@@ -57,26 +104,15 @@ func (s *userManageService) GetUser(ctx context.Context, userID string) (*domain
 }
 
 func (s *userManageService) CreateUser(ctx context.Context, req CreateUserRequest) (*domain.User, error) {
-	email := strings.ToLower(strings.TrimSpace(req.Email))
-	if err := validateEmail(email); err != nil {
+	email, role, status, err := validateNewUser(req.Email, req.Role, req.Status)
+	if err != nil {
 		return nil, err
 	}
 	if err := validatePassword(req.Password); err != nil {
 		return nil, err
 	}
-	status := req.Status
-	if status == "" {
-		status = domain.UserStatusActive
-	}
-	if !status.IsValid() {
-		return nil, fmt.Errorf("invalid status")
-	}
-	role := strings.TrimSpace(req.Role)
-	if role == "" {
-		return nil, fmt.Errorf("role is required")
-	}
 	if _, err := s.users.FindByEmail(ctx, email); err == nil {
-		return nil, fmt.Errorf("user already exists")
+		return nil, domain.Conflict("user_exists", "user already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
@@ -85,36 +121,51 @@ func (s *userManageService) CreateUser(ctx context.Context, req CreateUserReques
 	if err != nil {
 		return nil, err
 	}
-	user := &domain.User{Email: email}
-	if err := user.SetStatus(status); err != nil {
+	user, err := domain.NewUser(email, status)
+	if err != nil {
 		return nil, err
 	}
 	user.SetPasswordHash(string(hash))
-	if err := s.users.Create(ctx, user); err != nil {
-		return nil, err
-	}
 
 	profile := &domain.UserProfile{
-		UserID:       user.ID,
 		DisplayName:  req.DisplayName,
 		AvatarFileID: req.AvatarFileID,
 	}
-	if err := s.profiles.Create(ctx, profile); err != nil {
-		_ = s.users.Delete(ctx, user.ID)
+	if err := s.insert(ctx, user, profile, role); err != nil {
 		return nil, err
 	}
+	return user, nil
+}
 
+// insert stores a new user with its profile and assigns the initial role.
+func (s *userManageService) insert(ctx context.Context, user *domain.User, profile *domain.UserProfile, role string) error {
 	if s.rbac == nil {
-		_ = s.users.Delete(ctx, user.ID)
-		return nil, fmt.Errorf("rbac client not configured")
+		return fmt.Errorf("rbac client not configured")
 	}
-	if err := s.rbac.AssignRole(ctx, user.ID, role); err != nil {
-		_ = s.users.Delete(ctx, user.ID)
-		return nil, err
+	err := withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.users.Create(ctx, user); err != nil {
+			return err
+		}
+		profile.UserID = user.ID
+		if err := s.profiles.Create(ctx, profile); err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, s.outbox, events.UserCreated, user.ID, user.Email); err != nil {
+			return err
+		}
+		user.Profile = profile
+		created := auditSnapshot(user)
+		created["role"] = role
+		if err := recordAudit(ctx, s.audit, domain.AuditUserCreate, user.ID, nil, created); err != nil {
+			return err
+		}
+		return s.assignRole(ctx, user.ID, role)
+	})
+	if err != nil {
+		user.Profile = nil
+		return err
 	}
-
-	user.Profile = profile
-	return user, nil
+	return nil
 }
 
 func (s *userManageService) UpdateUser(ctx context.Context, userID string, req UpdateUserRequest) (*domain.User, error) {
@@ -122,6 +173,10 @@ func (s *userManageService) UpdateUser(ctx context.Context, userID string, req U
 	if err != nil {
 		return nil, err
 	}
+	if err := domain.CheckIfMatch(req.IfMatch, user.ETag()); err != nil {
+		return nil, err
+	}
+	before := auditSnapshot(user)
 
 	if req.Email != nil {
 		email := strings.ToLower(strings.TrimSpace(*req.Email))
@@ -133,7 +188,7 @@ func (s *userManageService) UpdateUser(ctx context.Context, userID string, req U
 			return nil, err
 		}
 		if existing != nil && existing.ID != userID {
-			return nil, fmt.Errorf("email already in use")
+			return nil, domain.ErrEmailInUse
 		}
 		user.Email = email
 	}
@@ -149,67 +204,381 @@ func (s *userManageService) UpdateUser(ctx context.Context, userID string, req U
 		user.SetPasswordHash(string(hash))
 	}
 
-	if req.DisplayName != nil || req.AvatarFileID != nil {
-		profile, err := s.profiles.FindByUserID(ctx, userID)
-		if err != nil {
-			return nil, err
+	err = withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if req.DisplayName != nil || req.AvatarFileID != nil {
+			// The preloaded profile carries the version the ETag was
+			// checked against.
+			profile := user.Profile
+			if profile == nil {
+				if profile, err = s.profiles.FindByUserID(ctx, userID); err != nil {
+					return err
+				}
+			}
+			profile.Update(req.DisplayName, req.AvatarFileID)
+			if err := s.profiles.Update(ctx, profile); err != nil {
+				return err
+			}
+			user.Profile = profile
 		}
-		profile.Update(req.DisplayName, req.AvatarFileID)
-		if err := s.profiles.Update(ctx, profile); err != nil {
-			return nil, err
+		if err := s.users.Update(ctx, user); err != nil {
+			return err
 		}
-		user.Profile = profile
+		if err := recordEvent(ctx, s.outbox, events.UserUpdated, user.ID, user.Email); err != nil {
+			return err
+		}
+		after := auditSnapshot(user)
+		if req.Password != nil {
+			after["password_changed"] = true
+		}
+		return recordAudit(ctx, s.audit, domain.AuditUserUpdate, user.ID, before, after)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return user, nil
+}
 
-	if err := s.users.Update(ctx, user); err != nil {
+// UpdateUserForService is UpdateUser for service clients. They may only
+// change the profile, and not of users whose role opens the admin API, so an
+// API key cannot be used to take over an admin account.
+func (s *userManageService) UpdateUserForService(ctx context.Context, userID string, req UpdateUserRequest) (*domain.User, error) {
+	if req.Email != nil || req.Password != nil {
+		return nil, domain.Forbidden("field_not_allowed", "service clients may not change the email or password")
+	}
+	if s.rbac == nil {
+		return nil, fmt.Errorf("rbac client not configured")
+	}
+	role, err := s.rbac.GetRoleByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if domain.IsPrivilegedRole(role) {
+		return nil, domain.Forbidden("user_not_allowed", "service clients may not update users with the "+strings.ToLower(role)+" role")
+	}
+	return s.UpdateUser(ctx, userID, req)
+}
+
+func (s *userManageService) ChangeStatus(ctx context.Context, userID string, req ChangeStatusRequest) (*domain.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status == domain.UserStatusSuspended {
+		return nil, domain.Invalid("status", "use suspend to suspend a user")
+	}
+	if err := domain.CheckIfMatch(req.IfMatch, user.ETag()); err != nil {
+		return nil, err
+	}
+	from := user.StatusOrDefault()
+	before := auditSnapshot(user)
+	reason := strings.TrimSpace(req.Reason)
+	if err := user.TransitionTo(req.Status, reason); err != nil {
+		return nil, err
+	}
+	if err := s.saveAuditedStatusChange(ctx, domain.AuditUserStatusChange, user, before, from, reason, req.ActorID); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *userManageService) ChangeStatus(ctx context.Context, userID string, status domain.UserStatus) (*domain.User, error) {
+func (s *userManageService) Suspend(ctx context.Context, userID string, req SuspendRequest) (*domain.User, error) {
 	user, err := s.users.FindByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	if err := user.SetStatus(status); err != nil {
+	from := user.StatusOrDefault()
+	before := auditSnapshot(user)
+	if err := user.Suspend(req.Until, req.Reason, req.ActorID, time.Now().UTC()); err != nil {
 		return nil, err
 	}
-	if err := s.users.Update(ctx, user); err != nil {
+	reason := string(req.Reason)
+	if note := strings.TrimSpace(req.Note); note != "" {
+		reason += ": " + note
+	}
+	if err := s.saveAuditedStatusChange(ctx, domain.AuditUserSuspend, user, before, from, reason, req.ActorID); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
+func (s *userManageService) LiftSuspension(ctx context.Context, userID string, req LiftSuspensionRequest) (*domain.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "suspension lifted"
+	}
+	before := auditSnapshot(user)
+	if err := user.LiftSuspension(reason); err != nil {
+		return nil, err
+	}
+	if err := s.saveAuditedStatusChange(ctx, domain.AuditUserSuspensionLift, user, before, domain.UserStatusSuspended, reason, req.ActorID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ReactivateExpiredSuspensions lifts every suspension that ended before now
+// and returns how many users were reactivated. A user that cannot be
+// reactivated is logged and skipped so it does not hold up the others; the
+// sweep then reports how many failed and the next sweep retries them.
+func (s *userManageService) ReactivateExpiredSuspensions(ctx context.Context, now time.Time) (int, error) {
+	logger := pkglog.FromContext(ctx)
+	reactivated := 0
+	var failed []string
+	for {
+		users, err := s.users.ListExpiredSuspensions(ctx, now, expiredSuspensionBatch, failed)
+		if err != nil {
+			return reactivated, err
+		}
+		for idx := range users {
+			user := &users[idx]
+			if err := s.reactivate(ctx, user); err != nil {
+				logger.Error().Err(err).Str("user_id", user.ID).Msg("lift expired suspension")
+				failed = append(failed, user.ID)
+				continue
+			}
+			reactivated++
+		}
+		if len(users) < expiredSuspensionBatch {
+			break
+		}
+	}
+	if len(failed) > 0 {
+		return reactivated, fmt.Errorf("%d expired suspensions could not be lifted", len(failed))
+	}
+	return reactivated, nil
+}
+
+func (s *userManageService) reactivate(ctx context.Context, user *domain.User) error {
+	if err := user.LiftSuspension("suspension expired"); err != nil {
+		return err
+	}
+	return s.saveStatusChange(ctx, user, domain.UserStatusSuspended, "suspension expired", "")
+}
+
+func (s *userManageService) saveStatusChange(ctx context.Context, user *domain.User, from domain.UserStatus, reason, actorID string) error {
+	return withinTx(ctx, s.tx, func(ctx context.Context) error {
+		return s.writeStatusChange(ctx, user, from, reason, actorID)
+	})
+}
+
+// writeStatusChange stores the user, its status history and the outbox
+// event. Callers provide the transaction.
+func (s *userManageService) writeStatusChange(ctx context.Context, user *domain.User, from domain.UserStatus, reason, actorID string) error {
+	if err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+	if s.history != nil {
+		entry := &domain.UserStatusHistory{
+			UserID:     user.ID,
+			FromStatus: from,
+			ToStatus:   user.Status,
+			Reason:     reason,
+			ChangedBy:  optionalString(actorID),
+		}
+		if err := s.history.Create(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return recordEvent(ctx, s.outbox, events.UserStatusChanged, user.ID, user.Email)
+}
+
+// saveAuditedStatusChange is saveStatusChange for admin actions: the change
+// and its audit entry are committed together.
+func (s *userManageService) saveAuditedStatusChange(ctx context.Context, action domain.AuditAction, user *domain.User, before map[string]interface{}, from domain.UserStatus, reason, actorID string) error {
+	return withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.writeStatusChange(ctx, user, from, reason, actorID); err != nil {
+			return err
+		}
+		after := auditSnapshot(user)
+		after["reason"] = reason
+		return recordAudit(ctx, s.audit, action, user.ID, before, after)
+	})
+}
+
 func (s *userManageService) ChangeRole(ctx context.Context, userID, role string) error {
-	role = strings.TrimSpace(role)
-	if role == "" {
-		return fmt.Errorf("role is required")
+	role, err := validateRole(role)
+	if err != nil {
+		return err
 	}
 	if s.rbac == nil {
 		return fmt.Errorf("rbac client not configured")
 	}
-	if _, err := s.users.FindByID(ctx, userID); err != nil {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
 		return err
 	}
-	return s.rbac.AssignRole(ctx, userID, role)
+	current, _ := s.rbac.GetRoleByUserID(ctx, userID)
+	return withinTx(ctx, s.tx, func(ctx context.Context) error {
+		return s.writeRoleChange(ctx, user, current, role)
+	})
 }
 
-func (s *userManageService) ListUsers(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
-	return s.users.List(ctx, offset, limit)
+// writeRoleChange audits and assigns role in place of current, which the
+// caller looked up before the transaction so RBAC is not called while it
+// holds the audit lock. Callers provide the transaction.
+func (s *userManageService) writeRoleChange(ctx context.Context, user *domain.User, current, role string) error {
+	before := map[string]interface{}{}
+	if current != "" {
+		before["role"] = current
+	}
+	if err := recordAudit(ctx, s.audit, domain.AuditUserRoleChange, user.ID, before, map[string]interface{}{"role": role}); err != nil {
+		return err
+	}
+	if err := s.assignRole(ctx, user.ID, role); err != nil || s.roles != nil {
+		// Queued roles record role_changed once RBAC has them.
+		return err
+	}
+	return recordEvent(ctx, s.outbox, events.UserRoleChanged, user.ID, user.Email)
+}
+
+func (s *userManageService) StatusHistory(ctx context.Context, userID string) ([]domain.UserStatusHistory, error) {
+	if _, err := s.users.FindByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if s.history == nil {
+		return nil, nil
+	}
+	return s.history.ListByUser(ctx, userID)
+}
+
+func (s *userManageService) ListUsers(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
+	if query.SortBy == "" {
+		query.SortBy = domain.UserSortCreatedAt
+		query.SortDesc = true
+	}
+	if err := query.Validate(); err != nil {
+		return nil, 0, err
+	}
+	return s.users.List(ctx, query)
+}
+
+// ListUsersByCursor returns a keyset page on (created_at, id). One extra row
+// is fetched to tell whether another page follows in the walking direction.
+func (s *userManageService) ListUsersByCursor(ctx context.Context, query domain.UserQuery) (*domain.UserPage, error) {
+	if query.SortBy == "" {
+		query.SortBy = domain.UserSortCreatedAt
+		query.SortDesc = true
+	}
+	if query.SortBy != domain.UserSortCreatedAt {
+		return nil, domain.Invalid("sort", "cursor pagination only supports sorting by created_at")
+	}
+	query.Offset = 0
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	query.Limit = limit + 1
+	users, err := s.users.ListByCursor(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	hasMore := len(users) > limit
+	if hasMore {
+		users = users[:limit]
+	}
+	backward := query.Cursor != nil && query.Cursor.Backward
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	page := &domain.UserPage{Users: users}
+	if len(users) > 0 {
+		first, last := users[0], users[len(users)-1]
+		// Walking forward there is a previous page whenever we started from
+		// a cursor; walking backward there is always a next page.
+		if (backward && hasMore) || (!backward && query.Cursor != nil) {
+			page.Prev = &domain.UserCursor{CreatedAt: first.CreatedAt, ID: first.ID, Backward: true}
+		}
+		if (!backward && hasMore) || backward {
+			page.Next = &domain.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
+	if query.Count != domain.UserCountNone {
+		total, err := s.users.Count(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalCount = &total
+	}
+	return page, nil
+}
+
+// StreamUsers passes every user matching the query's filters to fn in list
+// order. Offset and limit are ignored.
+func (s *userManageService) StreamUsers(ctx context.Context, query domain.UserQuery, fn func(*domain.User) error) error {
+	if query.SortBy == "" {
+		query.SortBy = domain.UserSortCreatedAt
+	}
+	return s.users.Stream(ctx, query, streamBatch, func(users []domain.User) error {
+		for idx := range users {
+			if err := fn(&users[idx]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// validateNewUser applies the rules every newly created user must satisfy and
+// returns the normalised email, role and initial status. An empty status
+// defaults to ACTIVE.
+func validateNewUser(email, role string, status domain.UserStatus) (string, string, domain.UserStatus, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := validateEmail(email); err != nil {
+		return "", "", "", err
+	}
+	if status == "" {
+		status = domain.UserStatusActive
+	}
+	if !status.IsValid() || status == domain.UserStatusSuspended || status == domain.UserStatusDeleted {
+		return "", "", "", domain.Invalid("status", "invalid status")
+	}
+	role, err := validateRole(role)
+	if err != nil {
+		return "", "", "", err
+	}
+	return email, role, status, nil
+}
+
+// rolePattern matches the role names used by the RBAC service.
+var rolePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]{0,63}$`)
+
+func validateRole(role string) (string, error) {
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return "", domain.Invalid("role", "role is required")
+	}
+	if !rolePattern.MatchString(role) {
+		return "", domain.Invalid("role", "invalid role")
+	}
+	return role, nil
 }
 
 func validateEmail(email string) error {
 	email = strings.TrimSpace(email)
 	if email == "" || !strings.Contains(email, "@") {
-		return fmt.Errorf("invalid email")
+		return domain.Invalid("email", "invalid email")
 	}
 	return nil
 }
 
 func validatePassword(pwd string) error {
 	if len(pwd) < 6 {
-		return fmt.Errorf("password too short")
+		return domain.Invalid("password", "password too short")
 	}
 	return nil
 }
+
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -2,18 +2,19 @@ package service
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"strings"
 
 	"github.com/example/user-service/internal/adapters/postgres"
 	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
 )
 
 type UserService interface {
 	GetMe(ctx context.Context, userID string) (*domain.User, error)
 	GetByID(ctx context.Context, requesterID, targetID string) (*domain.User, error)
-	UpdateProfile(ctx context.Context, userID string, displayName *string) (*domain.UserProfile, error)
+	// UpdateProfile returns the user with the updated profile. A non-empty
+	// ifMatch must list the current ETag of the user.
+	UpdateProfile(ctx context.Context, userID string, displayName *string, ifMatch string) (*domain.User, error)
 	SetAvatarFileID(ctx context.Context, userID, avatarFileID string) (*domain.UserProfile, error)
 	AttachIdentity(ctx context.Context, userID string, provider domain.IdentityProvider, providerUserID, email string, displayName, avatarURL *string) (*domain.UserIdentity, *domain.UserProfile, error)
 	ListIdentities(ctx context.Context, userID string) ([]domain.UserIdentity, error)
@@ -24,10 +25,12 @@ type userService struct {
 	users      repo.UserRepository
 	profiles   repo.UserProfileRepository
 	identities repo.UserIdentityRepository
+	tx         repo.TxManager
+	outbox     repo.OutboxRepository
 }
 
-func NewUserService(users repo.UserRepository, profiles repo.UserProfileRepository, identities repo.UserIdentityRepository) UserService {
-	return &userService{users: users, profiles: profiles, identities: identities}
+func NewUserService(users repo.UserRepository, profiles repo.UserProfileRepository, identities repo.UserIdentityRepository, tx repo.TxManager, outbox repo.OutboxRepository) UserService {
+	return &userService{users: users, profiles: profiles, identities: identities, tx: tx, outbox: outbox}
 }
 
 func (s *userService) GetMe(ctx context.Context, userID string) (*domain.User, error) {
@@ -38,16 +41,24 @@ func (s *userService) GetByID(ctx context.Context, requesterID, targetID string)
 	return s.users.FindByID(ctx, targetID)
 }
 
-func (s *userService) UpdateProfile(ctx context.Context, userID string, displayName *string) (*domain.UserProfile, error) {
-	profile, err := s.profiles.FindByUserID(ctx, userID)
+func (s *userService) UpdateProfile(ctx context.Context, userID string, displayName *string, ifMatch string) (*domain.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	profile.Update(displayName, nil)
-	if err := s.profiles.Update(ctx, profile); err != nil {
+	if err := domain.CheckIfMatch(ifMatch, user.ETag()); err != nil {
 		return nil, err
 	}
-	return profile, nil
+	if user.Profile == nil {
+		if user.Profile, err = s.profiles.FindByUserID(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	user.Profile.Update(displayName, nil)
+	if err := s.saveProfile(ctx, user.Profile); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
 func (s *userService) SetAvatarFileID(ctx context.Context, userID, avatarFileID string) (*domain.UserProfile, error) {
@@ -57,7 +68,7 @@ func (s *userService) SetAvatarFileID(ctx context.Context, userID, avatarFileID
 	}
 
 	profile.Update(nil, &avatarFileID)
-	if err := s.profiles.Update(ctx, profile); err != nil {
+	if err := s.saveProfile(ctx, profile); err != nil {
 		return nil, err
 	}
 
@@ -66,18 +77,18 @@ func (s *userService) SetAvatarFileID(ctx context.Context, userID, avatarFileID
 
 func (s *userService) AttachIdentity(ctx context.Context, userID string, provider domain.IdentityProvider, providerUserID, email string, displayName, avatarURL *string) (*domain.UserIdentity, *domain.UserProfile, error) {
 	if !provider.IsValid() {
-		return nil, nil, fmt.Errorf("unsupported provider")
+		return nil, nil, domain.Invalid("provider", "unsupported provider")
 	}
 	user, err := s.users.FindByID(ctx, userID)
 	if err != nil {
 		return nil, nil, err
 	}
 	if !strings.EqualFold(user.Email, email) {
-		return nil, nil, errors.New("email does not match user")
+		return nil, nil, domain.Invalid("email", "email does not match user")
 	}
 	if existing, err := s.identities.FindByProviderUserID(ctx, provider, providerUserID); err == nil {
 		if existing.UserID != userID {
-			return nil, nil, errors.New("identity already linked to another user")
+			return nil, nil, domain.Conflict("identity_linked", "identity already linked to another user")
 		}
 		return existing, user.Profile, nil
 	}
@@ -90,36 +101,56 @@ func (s *userService) AttachIdentity(ctx context.Context, userID string, provide
 		DisplayName:    displayName,
 		AvatarURL:      avatarURL,
 	}
-	if err := s.identities.Create(ctx, identity); err != nil {
-		return nil, nil, err
-	}
-
-	profile, err := s.profiles.FindByUserID(ctx, userID)
+	var profile *domain.UserProfile
+	err = withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.identities.Create(ctx, identity); err != nil {
+			return err
+		}
+		profile, err = s.profiles.FindByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		profile.Update(displayName, nil)
+		if err := s.profiles.Update(ctx, profile); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.outbox, events.IdentityAttached, userID, user.Email)
+	})
 	if err != nil {
 		return nil, nil, err
 	}
-	profile.Update(displayName, nil)
-	if err := s.profiles.Update(ctx, profile); err != nil {
-		return nil, nil, err
-	}
 
 	return identity, profile, nil
 }
 
 func (s *userService) RemoveIdentity(ctx context.Context, userID string, provider domain.IdentityProvider, providerUserID string) error {
 	if !provider.IsValid() {
-		return fmt.Errorf("unsupported provider")
+		return domain.Invalid("provider", "unsupported provider")
 	}
 	identity, err := s.identities.FindByProviderUserID(ctx, provider, providerUserID)
 	if err != nil {
 		return err
 	}
 	if identity.UserID != userID {
-		return errors.New("identity does not belong to user")
+		return domain.Forbidden("identity_not_owned", "identity does not belong to user")
 	}
-	return s.identities.Delete(ctx, identity)
+	return withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.identities.Delete(ctx, identity); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.outbox, events.IdentityDetached, userID, "")
+	})
 }
 
 func (s *userService) ListIdentities(ctx context.Context, userID string) ([]domain.UserIdentity, error) {
 	return s.identities.ListByUser(ctx, userID)
 }
+
+func (s *userService) saveProfile(ctx context.Context, profile *domain.UserProfile) error {
+	return withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.profiles.Update(ctx, profile); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.outbox, events.UserProfileUpdated, profile.UserID, "")
+	})
+}
@@ -1,6 +1,4 @@
 -- Reset seeded users to deterministic UUIDs and backfill profiles.
-BEGIN;
-
 DELETE FROM user_profile
 WHERE user_id IN (SELECT id FROM "user" WHERE email IN (
     'admin@example.com',
@@ -42,5 +40,3 @@ VALUES
     ('00000000-0000-0000-0000-0000000000b2', 'Student Two', NULL),
     ('00000000-0000-0000-0000-0000000000b3', 'Student Three', NULL),
     ('00000000-0000-0000-0000-0000000000c1', 'User', NULL);
-
-COMMIT;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS user_outbox (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subject text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    published_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_outbox_pending ON user_outbox(next_attempt_at, created_at) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_user_outbox_published_at ON user_outbox(published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_status_history (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+    from_status text NOT NULL,
+    to_status text NOT NULL,
+    reason text NOT NULL,
+    changed_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_status_history_user_id ON user_status_history(user_id, created_at DESC);
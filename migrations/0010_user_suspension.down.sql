@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_user_suspended_until;
+
+UPDATE "user" SET status = 'BLOCKED', is_active = false WHERE status = 'SUSPENDED';
+
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS suspended_by,
+    DROP COLUMN IF EXISTS suspension_reason,
+    DROP COLUMN IF EXISTS suspended_until;
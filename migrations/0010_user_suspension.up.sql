@@ -0,0 +1,6 @@
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS suspended_until timestamptz,
+    ADD COLUMN IF NOT EXISTS suspension_reason text,
+    ADD COLUMN IF NOT EXISTS suspended_by uuid;
+
+CREATE INDEX IF NOT EXISTS idx_user_suspended_until ON "user"(suspended_until) WHERE status = 'SUSPENDED';
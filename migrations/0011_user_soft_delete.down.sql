@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_user_purge_after;
+
+UPDATE "user"
+SET status = COALESCE(status_before_delete, 'INACTIVE'), is_active = false
+WHERE status = 'DELETED' AND purged_at IS NULL;
+
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS purged_at,
+    DROP COLUMN IF EXISTS status_before_delete,
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS purge_after,
+    DROP COLUMN IF EXISTS deleted_at;
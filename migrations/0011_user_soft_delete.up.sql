@@ -0,0 +1,8 @@
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS purge_after timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_by uuid,
+    ADD COLUMN IF NOT EXISTS status_before_delete text,
+    ADD COLUMN IF NOT EXISTS purged_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_user_purge_after ON "user"(purge_after) WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
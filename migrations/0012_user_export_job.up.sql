@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS user_export_job (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+    status text NOT NULL,
+    format text NOT NULL,
+    file_id text,
+    error text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    completed_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_export_job_user_id ON user_export_job(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_export_job_pending ON user_export_job(created_at) WHERE status = 'PENDING';
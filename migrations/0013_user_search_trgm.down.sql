@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_identity_provider_user;
+DROP INDEX IF EXISTS idx_user_status_created_at;
+DROP INDEX IF EXISTS idx_user_profile_display_name_trgm;
+DROP INDEX IF EXISTS idx_user_email_trgm;
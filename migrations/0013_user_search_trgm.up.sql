@@ -0,0 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_user_email_trgm ON "user" USING gin (email gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_profile_display_name_trgm ON user_profile USING gin (display_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_status_created_at ON "user"(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_identity_provider_user ON user_identity(provider, user_id);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_user_created_at_id ON "user"(created_at, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_bulk_job_item;
+DROP TABLE IF EXISTS user_bulk_job;
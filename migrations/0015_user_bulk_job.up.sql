@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS user_bulk_job (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action text NOT NULL,
+    status text NOT NULL,
+    target_status text,
+    role text,
+    reason text,
+    dry_run boolean NOT NULL DEFAULT false,
+    total integer NOT NULL DEFAULT 0,
+    succeeded integer NOT NULL DEFAULT 0,
+    failed integer NOT NULL DEFAULT 0,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    completed_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_bulk_job_pending ON user_bulk_job(created_at) WHERE status IN ('PENDING', 'RUNNING');
+
+CREATE TABLE IF NOT EXISTS user_bulk_job_item (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_id uuid NOT NULL REFERENCES user_bulk_job(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    status text NOT NULL,
+    error text,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_bulk_job_item_job_id ON user_bulk_job_item(job_id);
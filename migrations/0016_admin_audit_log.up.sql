@@ -0,0 +1,29 @@
+-- changes is json rather than jsonb so the stored text, which is part of the
+-- row hash, is kept byte for byte.
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id bigserial PRIMARY KEY,
+    actor_id text,
+    target_user_id uuid,
+    action text NOT NULL,
+    changes json NOT NULL,
+    request_id text,
+    ip text,
+    user_agent text,
+    created_at timestamptz NOT NULL,
+    prev_hash text NOT NULL,
+    hash text NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_actor ON admin_audit_log(actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_target ON admin_audit_log(target_user_id, id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_action ON admin_audit_log(action, id);
+
+CREATE OR REPLACE FUNCTION admin_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'admin_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER admin_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON admin_audit_log
+    FOR EACH ROW EXECUTE FUNCTION admin_audit_log_append_only();
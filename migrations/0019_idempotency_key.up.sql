@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    principal text NOT NULL,
+    key text NOT NULL,
+    fingerprint text NOT NULL,
+    response_status integer,
+    response_headers jsonb,
+    response_body bytea,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_key_principal_key ON idempotency_key(principal, key);
+CREATE INDEX IF NOT EXISTS idx_idempotency_key_expires_at ON idempotency_key(expires_at);
@@ -0,0 +1,2 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE user_profile ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
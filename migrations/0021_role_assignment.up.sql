@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS role_assignment (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    role text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    next_attempt_at timestamptz NOT NULL,
+    assigned_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_assignment_pending ON role_assignment(next_attempt_at) WHERE assigned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_role_assignment_user ON role_assignment(user_id);
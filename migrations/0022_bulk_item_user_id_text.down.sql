@@ -0,0 +1,2 @@
+DELETE FROM user_bulk_job_item WHERE user_id !~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$';
+ALTER TABLE user_bulk_job_item ALTER COLUMN user_id TYPE uuid USING user_id::uuid;
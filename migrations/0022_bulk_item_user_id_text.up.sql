@@ -0,0 +1,2 @@
+-- Items keep the IDs as submitted, so malformed ones can be reported per item.
+ALTER TABLE user_bulk_job_item ALTER COLUMN user_id TYPE text USING user_id::text;
@@ -0,0 +1,4 @@
+-- Emails are unique among live users, regardless of case. Deleted users keep
+-- their email until they are purged, so it may be reused in the meantime.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_email_live_unique ON "user"(lower(email))
+    WHERE deleted_at IS NULL AND email IS NOT NULL AND email <> '';
@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL schema migrations so the service binary
+// can apply them itself. Files are named NNNN_name.up.sql and
+// NNNN_name.down.sql; each file runs in its own transaction.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,66 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type position struct {
+	ID   string `json:"i"`
+	Desc bool   `json:"d"`
+}
+
+func newSigner(t *testing.T, key string) *Signer {
+	t.Helper()
+	signer, err := NewSigner([]byte(key))
+	require.NoError(t, err)
+	return signer
+}
+
+func TestSignerRoundTrip(t *testing.T) {
+	signer := newSigner(t, "key-1")
+	token, err := signer.Encode(position{ID: "user-1", Desc: true})
+	require.NoError(t, err)
+
+	var got position
+	require.NoError(t, signer.Decode(token, &got))
+	require.Equal(t, position{ID: "user-1", Desc: true}, got)
+
+	// Another signer with the same key, e.g. another replica, accepts it.
+	require.NoError(t, newSigner(t, "key-1").Decode(token, &got))
+}
+
+func TestSignerRejectsTamperedTokens(t *testing.T) {
+	signer := newSigner(t, "key-1")
+	token, err := signer.Encode(position{ID: "user-1"})
+	require.NoError(t, err)
+	forged, err := newSigner(t, "key-2").Encode(position{ID: "user-2"})
+	require.NoError(t, err)
+	body, sig, _ := strings.Cut(token, ".")
+	forgedBody, _, _ := strings.Cut(forged, ".")
+	flipped := "A" + sig[1:]
+	if sig[0] == 'A' {
+		flipped = "B" + sig[1:]
+	}
+
+	for name, tampered := range map[string]string{
+		"wrong key":      forged,
+		"swapped body":   forgedBody + "." + sig,
+		"flipped sig":    body + "." + flipped,
+		"missing sig":    body,
+		"not base64 sig": body + ".!!!",
+		"empty":          "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var got position
+			require.ErrorIs(t, signer.Decode(tampered, &got), ErrInvalidCursor)
+		})
+	}
+}
+
+func TestNewSignerRequiresKey(t *testing.T) {
+	_, err := NewSigner(nil)
+	require.ErrorIs(t, err, ErrEmptyKey)
+}
@@ -0,0 +1,164 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level says what a failing check means for readiness.
+type Level string
+
+const (
+	// LevelCritical checks make the service not ready when they fail.
+	LevelCritical Level = "critical"
+	// LevelOptional checks only mark the service degraded.
+	LevelOptional Level = "optional"
+)
+
+const (
+	StatusReady        = "ready"
+	StatusDegraded     = "degraded"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+
+	defaultTimeout = 2 * time.Second
+)
+
+type Check struct {
+	Name  string
+	Level Level
+	// Timeout bounds a single run; it defaults to two seconds.
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+type Result struct {
+	Name       string    `json:"name"`
+	Level      Level     `json:"level"`
+	OK         bool      `json:"ok"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready reports whether traffic should be routed to the service.
+func (r Report) Ready() bool {
+	return r.Status == StatusReady || r.Status == StatusDegraded
+}
+
+// Registry runs registered checks concurrently and reuses each result for
+// the cache TTL, so frequent probes do not load the dependencies.
+type Registry struct {
+	ttl          time.Duration
+	checks       []*entry
+	shuttingDown atomic.Bool
+}
+
+type entry struct {
+	check  Check
+	mu     sync.Mutex
+	result Result
+}
+
+func NewRegistry(ttl time.Duration) *Registry {
+	return &Registry{ttl: ttl}
+}
+
+// Register adds a check. It is not safe to call once probes are served.
+func (r *Registry) Register(check Check) {
+	if check.Timeout <= 0 {
+		check.Timeout = defaultTimeout
+	}
+	if check.Level == "" {
+		check.Level = LevelCritical
+	}
+	r.checks = append(r.checks, &entry{check: check})
+}
+
+// SetShuttingDown makes every later report not ready, so load balancers stop
+// routing new requests before the server closes.
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+func (r *Registry) Check(ctx context.Context) Report {
+	report := Report{Status: StatusReady, Checks: make([]Result, len(r.checks))}
+	var wg sync.WaitGroup
+	for i, e := range r.checks {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			report.Checks[i] = e.run(ctx, r.ttl)
+		}(i, e)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.OK {
+			continue
+		}
+		if result.Level == LevelCritical {
+			report.Status = StatusNotReady
+		} else if report.Status == StatusReady {
+			report.Status = StatusDegraded
+		}
+	}
+	if r.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+func (e *entry) run(ctx context.Context, ttl time.Duration) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.result.CheckedAt.IsZero() && time.Since(e.result.CheckedAt) < ttl {
+		return e.result
+	}
+
+	// A probe client hanging up must not leave a failure in the cache.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.check.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := e.check.Run(ctx)
+	e.result = Result{
+		Name:       e.check.Name,
+		Level:      e.check.Level,
+		OK:         err == nil,
+		DurationMS: time.Since(start).Milliseconds(),
+		CheckedAt:  start,
+	}
+	if err != nil {
+		e.result.Error = err.Error()
+	}
+	return e.result
+}
+
+// HTTPReachable checks that url answers at all; any HTTP status counts,
+// since only the connection is of interest.
+func HTTPReachable(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if url == "" {
+			return errors.New("url not configured")
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+}
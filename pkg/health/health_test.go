@@ -0,0 +1,71 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryLevelsAndShutdown(t *testing.T) {
+	var optionalErr atomic.Value
+	optionalErr.Store(errors.New("unreachable"))
+	var criticalErr error
+
+	r := NewRegistry(0)
+	r.Register(Check{Name: "db", Run: func(context.Context) error { return criticalErr }})
+	r.Register(Check{Name: "files", Level: LevelOptional, Run: func(context.Context) error {
+		return optionalErr.Load().(error)
+	}})
+
+	report := r.Check(context.Background())
+	require.Equal(t, StatusDegraded, report.Status)
+	require.True(t, report.Ready())
+	require.Equal(t, LevelCritical, report.Checks[0].Level)
+	require.True(t, report.Checks[0].OK)
+	require.Equal(t, "unreachable", report.Checks[1].Error)
+
+	criticalErr = errors.New("connection refused")
+	report = r.Check(context.Background())
+	require.Equal(t, StatusNotReady, report.Status)
+	require.False(t, report.Ready())
+
+	criticalErr = nil
+	r.SetShuttingDown()
+	report = r.Check(context.Background())
+	require.Equal(t, StatusShuttingDown, report.Status)
+	require.False(t, report.Ready())
+}
+
+func TestRegistryCachesAndTimesOut(t *testing.T) {
+	var calls atomic.Int32
+	r := NewRegistry(time.Minute)
+	r.Register(Check{Name: "slow", Timeout: 20 * time.Millisecond, Run: func(ctx context.Context) error {
+		calls.Add(1)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	first := r.Check(context.Background())
+	require.Equal(t, StatusNotReady, first.Status)
+	require.Contains(t, first.Checks[0].Error, "deadline exceeded")
+
+	second := r.Check(context.Background())
+	require.Equal(t, first.Checks[0], second.Checks[0])
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestHTTPReachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	check := HTTPReachable(srv.Client(), srv.URL)
+	require.NoError(t, check(context.Background()), "any status means reachable")
+
+	srv.Close()
+	require.Error(t, check(context.Background()))
+	require.Error(t, HTTPReachable(http.DefaultClient, "")(context.Background()))
+}
@@ -1,6 +1,17 @@
 package http
 
-import "github.com/labstack/echo/v4"
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEProblemJSON is the media type of error responses (RFC 7807).
+const MIMEProblemJSON = "application/problem+json"
+
+// problemTypePrefix turns an error code into the problem type URI, so
+// clients can switch on either.
+const problemTypePrefix = "urn:user-service:problem:"
 
 type Error struct {
 	Code    string      `json:"code"`
@@ -8,9 +19,16 @@ type Error struct {
 	Details interface{} `json:"details,omitempty"`
 }
 
+// ErrorResponse is an RFC 7807 problem document. The error object and
+// trace_id are extension members kept for clients that predate it.
 type ErrorResponse struct {
-	Error   Error  `json:"error"`
-	TraceID string `json:"trace_id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Error    Error  `json:"error"`
+	TraceID  string `json:"trace_id"`
 }
 
 type Response struct {
@@ -21,6 +39,18 @@ func JSON(c echo.Context, status int, data interface{}) error {
 	return c.JSON(status, Response{Data: data})
 }
 
+// ErrorJSON writes a problem document. code identifies the problem type and
+// should come from the error catalog documented in the README.
 func ErrorJSON(c echo.Context, status int, code, message, traceID string, details interface{}) error {
-	return c.JSON(status, ErrorResponse{Error: Error{Code: code, Message: message, Details: details}, TraceID: traceID})
+	problem := ErrorResponse{
+		Type:     problemTypePrefix + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   message,
+		Instance: c.Request().URL.Path,
+		Error:    Error{Code: code, Message: message, Details: details},
+		TraceID:  traceID,
+	}
+	c.Response().Header().Set(echo.HeaderContentType, MIMEProblemJSON)
+	return c.JSON(status, problem)
 }
@@ -1,7 +1,9 @@
 package log
 
 import (
+	"context"
 	"os"
+	"regexp"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -16,7 +18,10 @@ func New(env string) Logger {
 	if env == "local" {
 		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
 	}
-	return log.Level(level)
+	logger := log.Level(level)
+	// Code running outside a request still gets a working FromContext.
+	zerolog.DefaultContextLogger = &logger
+	return logger
 }
 
 func With(logger Logger, fields Fields) Logger {
@@ -26,3 +31,22 @@ func With(logger Logger, fields Fields) Logger {
 	}
 	return event
 }
+
+// FromContext returns the request-scoped logger stored in ctx, or the logger
+// built by New when there is none.
+func FromContext(ctx context.Context) *Logger {
+	return zerolog.Ctx(ctx)
+}
+
+// WithContext returns a copy of ctx carrying logger.
+func WithContext(ctx context.Context, logger Logger) context.Context {
+	return logger.WithContext(ctx)
+}
+
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*(@|%40)([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// RedactEmails masks the local part of every email address in s, keeping
+// its first character and the domain; URL-encoded addresses are masked too.
+func RedactEmails(s string) string {
+	return emailPattern.ReplaceAllString(s, "$1****$2$3")
+}
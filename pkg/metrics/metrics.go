@@ -0,0 +1,196 @@
+// Package metrics holds the Prometheus collectors of the service. Adapters
+// record into them through the Observe helpers; Handler serves them.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "user_service"
+
+// Registry holds every collector of the service plus the Go runtime and
+// process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by route and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	natsRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "nats_requests_total",
+		Help:      "NATS RPC requests handled, by subject.",
+	}, []string{"subject"})
+	natsDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "nats_request_duration_seconds",
+		Help:      "NATS RPC handler latency, by subject.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"subject"})
+
+	clientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_requests_total",
+		Help:      "Calls to other services, by client, operation and outcome.",
+	}, []string{"client", "operation", "outcome"})
+	clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "client_request_duration_seconds",
+		Help:      "Latency of calls to other services including retries.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"client", "operation"})
+	clientRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_retries_total",
+		Help:      "Retries of calls to other services after a failed attempt.",
+	}, []string{"client", "operation"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "GORM statement latency, by operation, table and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "outcome"})
+
+	deadLetters = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "dead_letters",
+		Help:      "Queued rows that ran out of attempts, by queue.",
+	}, []string{"queue"})
+
+	authCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_cache_hits_total",
+		Help:      "Bearer tokens resolved from the principal cache.",
+	})
+	authCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_cache_misses_total",
+		Help:      "Bearer tokens that had to be verified.",
+	})
+	authCacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_cache_evictions_total",
+		Help:      "Principals dropped from the cache because it was full or the user changed.",
+	})
+
+	rbacCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rbac_cache_hits_total",
+		Help:      "RBAC lookups answered from the local cache.",
+	})
+	rbacCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rbac_cache_misses_total",
+		Help:      "RBAC lookups that went to the RBAC service.",
+	})
+	rbacCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rbac_cache_entries",
+		Help:      "Entries held in the RBAC cache.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		natsRequests, natsDuration,
+		clientRequests, clientDuration, clientRetries,
+		dbQueryDuration,
+		deadLetters,
+		authCacheHits, authCacheMisses, authCacheEvictions,
+		rbacCacheHits, rbacCacheMisses, rbacCacheEntries,
+	)
+}
+
+// Handler serves Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTP records a served request. route is the route template, not the
+// raw path, to keep label cardinality bounded.
+func ObserveHTTP(method, route string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+func ObserveNATS(subject string, elapsed time.Duration) {
+	natsRequests.WithLabelValues(subject).Inc()
+	natsDuration.WithLabelValues(subject).Observe(elapsed.Seconds())
+}
+
+// ObserveClient records one call to another service, retries included.
+func ObserveClient(client, operation string, err error, elapsed time.Duration) {
+	clientRequests.WithLabelValues(client, operation, outcome(err)).Inc()
+	clientDuration.WithLabelValues(client, operation).Observe(elapsed.Seconds())
+}
+
+// RetryNotifier returns a backoff.Notify func that counts the retries of a
+// call to another service.
+func RetryNotifier(client, operation string) func(error, time.Duration) {
+	retries := clientRetries.WithLabelValues(client, operation)
+	return func(error, time.Duration) {
+		retries.Inc()
+	}
+}
+
+// ObserveQuery records one database statement.
+func ObserveQuery(operation, table string, err error, elapsed time.Duration) {
+	dbQueryDuration.WithLabelValues(operation, table, outcome(err)).Observe(elapsed.Seconds())
+}
+
+// SetDeadLetters reports how many rows of a queue ran out of attempts and
+// need attention.
+func SetDeadLetters(queue string, n int64) {
+	deadLetters.WithLabelValues(queue).Set(float64(n))
+}
+
+func AuthCacheHit() {
+	authCacheHits.Inc()
+}
+
+func AuthCacheMiss() {
+	authCacheMisses.Inc()
+}
+
+func AuthCacheEvicted(n int) {
+	authCacheEvictions.Add(float64(n))
+}
+
+func RBACCacheHit() {
+	rbacCacheHits.Inc()
+}
+
+func RBACCacheMiss() {
+	rbacCacheMisses.Inc()
+}
+
+func SetRBACCacheEntries(n int) {
+	rbacCacheEntries.Set(float64(n))
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
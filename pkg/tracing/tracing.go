@@ -0,0 +1,174 @@
+// Package tracing sets up OpenTelemetry tracing with W3C trace context
+// propagation and carries it over HTTP and NATS.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	natsgo "github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/example/user-service"
+
+type Config struct {
+	ServiceName string
+	Environment string
+	// Exporter is "otlp" or "none". With "none" spans are sampled and
+	// propagated but not exported.
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector base URL, e.g.
+	// http://otel-collector:4318; spans are sent to its /v1/traces path.
+	Endpoint    string
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and propagator. The returned
+// func flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.DeploymentEnvironment(cfg.Environment),
+	))
+	if err != nil {
+		return nil, err
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	switch cfg.Exporter {
+	case "", "none":
+	case "otlp":
+		exporterOpts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			endpoint, err := tracesURL(cfg.Endpoint)
+			if err != nil {
+				return nil, err
+			}
+			exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("create otlp exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// tracesURL appends the signal path to the base URL like the OTLP
+// specification does for OTEL_EXPORTER_OTLP_ENDPOINT, keeping any path
+// prefix of a collector behind a proxy.
+func tracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("parse otlp endpoint %q: want scheme://host[:port][/path]", endpoint)
+	}
+	return u.JoinPath("v1", "traces").String(), nil
+}
+
+// Tracer returns the tracer of the service from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// TraceID returns the trace ID of the span in ctx, or "" without one.
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// InjectNATS writes the trace context of ctx into the message headers.
+func InjectNATS(ctx context.Context, msg *natsgo.Msg) {
+	if msg.Header == nil {
+		msg.Header = natsgo.Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+}
+
+// ExtractNATS returns ctx carrying the trace context of the message headers.
+func ExtractNATS(ctx context.Context, msg *natsgo.Msg) context.Context {
+	if msg.Header == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
+}
+
+// StartNATSRequest starts a client span for a NATS request on subject and
+// returns a message carrying its trace context.
+func StartNATSRequest(ctx context.Context, subject string, data []byte) (context.Context, trace.Span, *natsgo.Msg) {
+	ctx, span := Tracer().Start(ctx, subject, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.MessagingSystemKey.String("nats"),
+		semconv.MessagingDestinationName(subject),
+	))
+	msg := natsgo.NewMsg(subject)
+	msg.Data = data
+	InjectNATS(ctx, msg)
+	return ctx, span, msg
+}
+
+// Transport wraps base so every outgoing request gets a client span named
+// after client and carries its trace context.
+func Transport(client string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{client: client, base: base}
+}
+
+type transport struct {
+	client string
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Tracer().Start(req.Context(), t.client+" "+req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFull(req.URL.Redacted()),
+		semconv.ServerAddress(req.URL.Hostname()),
+		attribute.String("peer.service", t.client),
+	))
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, res.Status)
+	}
+	span.End()
+	return res, nil
+}
@@ -0,0 +1,24 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracesURLKeepsPathPrefix(t *testing.T) {
+	for endpoint, want := range map[string]string{
+		"http://otel-collector:4318":       "http://otel-collector:4318/v1/traces",
+		"http://otel-collector:4318/":      "http://otel-collector:4318/v1/traces",
+		"https://gateway.example.com/otlp": "https://gateway.example.com/otlp/v1/traces",
+	} {
+		got, err := tracesURL(endpoint)
+		require.NoError(t, err)
+		require.Equal(t, want, got, endpoint)
+	}
+
+	for _, endpoint := range []string{"otel-collector:4318", "://bad", "/v1/traces"} {
+		_, err := tracesURL(endpoint)
+		require.Error(t, err, endpoint)
+	}
+}
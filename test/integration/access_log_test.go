@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/config"
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/log"
+)
+
+func accessLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(raw), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestAccessLogRecordsRequestAndCaller(t *testing.T) {
+	var buf bytes.Buffer
+	users := &userRepoStub{users: map[string]*domain.User{"user-1": {ID: "user-1", Status: domain.UserStatusActive, IsActive: true}}}
+	verifier := func(ctx context.Context, token string) (string, string, string, error) {
+		return "user-1", "student", "", nil
+	}
+	authMW := middleware.NewAuthMiddlewareWithVerifier(&config.Config{}, log.New("local"), &rbacStub{}, users, nil, verifier)
+
+	e := echo.New()
+	e.Use(echomw.RequestID(), middleware.AccessLog(zerolog.New(&buf), 1))
+	e.GET("/api/v1/users/:id", func(c echo.Context) error {
+		log.FromContext(c.Request().Context()).Info().Msg("handler")
+		return echo.NewHTTPError(http.StatusNotFound, "no user jane.doe@example.com")
+	}, authMW.Handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1?email=jane.doe%40example.com", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret-token")
+	req.Header.Set("X-Forwarded-Email", "jane.doe@example.com")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := accessLogLines(t, &buf)
+	require.Len(t, lines, 2)
+	handler, access := lines[0], lines[1]
+	require.Equal(t, "handler", handler["message"])
+	require.Equal(t, "user-1", handler["user_id"])
+	require.NotEmpty(t, handler["request_id"])
+
+	require.Equal(t, "warn", access["level"])
+	require.Equal(t, handler["request_id"], access["request_id"])
+	require.Equal(t, "user-1", access["user_id"])
+	require.Equal(t, "STUDENT", access["role"])
+	require.Equal(t, "/api/v1/users/:id", access["route"])
+	require.EqualValues(t, http.StatusNotFound, access["status"])
+	require.Contains(t, access, "latency")
+	require.Contains(t, access, "bytes_out")
+
+	raw := buf.String()
+	require.NotContains(t, raw, "secret-token")
+	require.NotContains(t, raw, "jane.doe")
+	headers := access["headers"].(map[string]interface{})
+	require.Equal(t, "[REDACTED]", headers[echo.HeaderAuthorization])
+	require.Equal(t, "j****@example.com", headers["X-Forwarded-Email"])
+	require.Equal(t, "/api/v1/users/1?email=j****%40example.com", access["uri"])
+}
+
+func TestAccessLogSamplesSuccessfulRequests(t *testing.T) {
+	var buf bytes.Buffer
+	e := echo.New()
+	e.Use(middleware.AccessLog(zerolog.New(&buf), 3))
+	e.GET("/ok", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+
+	for i := 0; i < 6; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	lines := accessLogLines(t, &buf)
+	require.Len(t, lines, 3)
+	require.Equal(t, "info", lines[0]["level"])
+	require.Equal(t, "unmatched", lines[2]["route"])
+	require.EqualValues(t, http.StatusNotFound, lines[2]["status"])
+	require.NotContains(t, lines[0], "headers")
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
@@ -34,15 +35,15 @@ func TestAdminUserListAuthAndRBAC(t *testing.T) {
 	rbacMW := middleware.NewRBACMiddleware(rbac)
 
 	stub := &manageServiceStub{}
-	stub.listUsersFn = func(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
-		require.Equal(t, 0, offset)
-		require.Equal(t, 10, limit)
+	stub.listUsersFn = func(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
+		require.Equal(t, 0, query.Offset)
+		require.Equal(t, 10, query.Limit)
 		return []domain.User{{ID: "user-1"}}, 1, nil
 	}
 
 	e := echo.New()
 	group := e.Group("/admin/v1/users", authMW.Handler, rbacMW.RequireAnyRole("admin", "moderator"))
-	handler := adminv1.NewHandler(stub, nil)
+	handler := adminv1.NewHandler(stub, nil, nil, nil, nil, nil)
 	handler.RegisterRoutes(group)
 
 	unauthorized := httptest.NewRequest(http.MethodGet, "/admin/v1/users", nil)
@@ -67,6 +68,147 @@ func TestAdminUserListAuthAndRBAC(t *testing.T) {
 	require.Len(t, resp.Data.Users, 1)
 }
 
+func TestAdminUserExportMasksEmailsWithoutPIIPermission(t *testing.T) {
+	users := &userRepoStub{users: map[string]*domain.User{"user-1": {ID: "user-1", Email: "admin@example.com"}}}
+	verifier := func(ctx context.Context, token string) (string, string, string, error) {
+		return "user-1", "admin", "admin@example.com", nil
+	}
+	stub := &manageServiceStub{}
+	stub.streamUsersFn = func(ctx context.Context, query domain.UserQuery, fn func(*domain.User) error) error {
+		require.Equal(t, []domain.UserStatus{domain.UserStatusActive}, query.Statuses)
+		return fn(&domain.User{ID: "user-7", Email: "jane@example.com", Status: domain.UserStatusActive})
+	}
+	handler := adminv1.NewHandler(stub, nil, nil, nil, nil, nil)
+
+	for _, tc := range []struct {
+		permissions []string
+		email       string
+	}{
+		{nil, "j****@***e.com"},
+		{[]string{adminv1.PermissionExportPII}, "jane@example.com"},
+	} {
+		rbac := &rbacStub{permissions: tc.permissions}
+		authMW := middleware.NewAuthMiddlewareWithVerifier(&config.Config{}, log.New("local"), rbac, users, nil, verifier)
+		rbacMW := middleware.NewRBACMiddleware(rbac)
+		e := echo.New()
+		group := e.Group("/admin/v1/users", authMW.Handler, rbacMW.RequireAnyRole("admin", "moderator"))
+		group.GET("/export", handler.ExportUsers, rbacMW.LoadPermission(adminv1.PermissionExportPII))
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/v1/users/export?format=ndjson&columns=id,email&status=active", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, `{"id":"user-7","email":"`+tc.email+`"}`+"\n", rec.Body.String())
+	}
+}
+
+func TestAuthMiddlewareRejectsSuspendedUser(t *testing.T) {
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	reason := domain.SuspensionReasonSpam
+	users := &userRepoStub{users: map[string]*domain.User{"user-1": {
+		ID:         "user-1",
+		Status:     domain.UserStatusSuspended,
+		Suspension: domain.Suspension{SuspendedUntil: &until, SuspensionReason: &reason},
+	}}}
+	verifier := func(ctx context.Context, token string) (string, string, string, error) {
+		return "user-1", "student", "", nil
+	}
+	authMW := middleware.NewAuthMiddlewareWithVerifier(&config.Config{}, log.New("local"), &rbacStub{}, users, nil, verifier)
+
+	e := echo.New()
+	e.GET("/api/v1/users/me", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, authMW.Handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	var resp struct {
+		Error struct {
+			Code    string `json:"code"`
+			Details struct {
+				SuspendedUntil time.Time `json:"suspended_until"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "user_suspended", resp.Error.Code)
+	require.True(t, until.Equal(resp.Error.Details.SuspendedUntil))
+
+	expired := time.Now().Add(-time.Minute)
+	users.users["user-1"].SuspendedUntil = &expired
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthMiddlewareRejectsBlockedUsers(t *testing.T) {
+	users := &userRepoStub{users: map[string]*domain.User{}}
+	verifier := func(ctx context.Context, token string) (string, string, string, error) {
+		return "user-1", "student", "", nil
+	}
+	authMW := middleware.NewAuthMiddlewareWithVerifier(&config.Config{}, log.New("local"), &rbacStub{}, users, nil, verifier)
+
+	e := echo.New()
+	e.GET("/api/v1/users/me", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, authMW.Handler)
+
+	call := func(status domain.UserStatus) *httptest.ResponseRecorder {
+		users.users["user-1"] = &domain.User{ID: "user-1", Status: status}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call(domain.UserStatusBlocked)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"user_blocked"`)
+
+	// INACTIVE does not restrict sign-in.
+	require.Equal(t, http.StatusOK, call(domain.UserStatusInactive).Code)
+}
+
+func TestAuthMiddlewareRejectsDeletedUser(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Hour)
+	purgeAfter := time.Now().Add(time.Hour)
+	users := &userRepoStub{users: map[string]*domain.User{"user-1": {
+		ID:       "user-1",
+		Status:   domain.UserStatusDeleted,
+		Deletion: domain.Deletion{DeletedAt: &deletedAt, PurgeAfter: &purgeAfter},
+	}}}
+	verifier := func(ctx context.Context, token string) (string, string, string, error) {
+		return "user-1", "student", "", nil
+	}
+	authMW := middleware.NewAuthMiddlewareWithVerifier(&config.Config{}, log.New("local"), &rbacStub{}, users, nil, verifier)
+
+	e := echo.New()
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/api/v1/users/me", ok, authMW.Handler)
+	e.POST("/api/v1/users/me/restore", ok, authMW.HandlerAllowDeleted)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), "account_deleted")
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/users/me/restore", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	purgedAt := time.Now()
+	users.users["user-1"].PurgedAt = &purgedAt
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
 type userRepoStub struct {
 	users map[string]*domain.User
 }
@@ -91,11 +233,37 @@ func (r *userRepoStub) FindByID(ctx context.Context, id string) (*domain.User, e
 
 func (r *userRepoStub) Delete(ctx context.Context, id string) error { return nil }
 
-func (r *userRepoStub) List(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
+func (r *userRepoStub) List(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
 	return nil, 0, nil
 }
 
-type rbacStub struct{}
+func (r *userRepoStub) FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error) {
+	return r.FindByID(ctx, id)
+}
+
+func (r *userRepoStub) ListPurgeable(ctx context.Context, now time.Time, limit int) ([]domain.User, error) {
+	return nil, nil
+}
+
+func (r *userRepoStub) Stream(ctx context.Context, query domain.UserQuery, batchSize int, fn func([]domain.User) error) error {
+	return nil
+}
+
+func (r *userRepoStub) ListByCursor(ctx context.Context, query domain.UserQuery) ([]domain.User, error) {
+	return nil, nil
+}
+
+func (r *userRepoStub) Count(ctx context.Context, query domain.UserQuery) (int64, error) {
+	return 0, nil
+}
+
+func (r *userRepoStub) ListExpiredSuspensions(ctx context.Context, now time.Time, limit int, exclude []string) ([]domain.User, error) {
+	return nil, nil
+}
+
+type rbacStub struct {
+	permissions []string
+}
 
 func (r *rbacStub) GetRoleByUserID(ctx context.Context, userID string) (string, error) {
 	return "admin", nil
@@ -106,6 +274,11 @@ func (r *rbacStub) GetPermissionsByUserID(ctx context.Context, userID string) ([
 }
 
 func (r *rbacStub) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	for _, p := range r.permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
 	return false, nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
@@ -18,14 +19,14 @@ import (
 
 func TestAdminUserListRoute(t *testing.T) {
 	stub := &manageServiceStub{}
-	stub.listUsersFn = func(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
-		require.Equal(t, 20, offset)
-		require.Equal(t, 10, limit)
+	stub.listUsersFn = func(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
+		require.Equal(t, 20, query.Offset)
+		require.Equal(t, 10, query.Limit)
 		return []domain.User{{ID: "user-1"}, {ID: "user-2"}}, 42, nil
 	}
 
 	e := echo.New()
-	handler := adminv1.NewHandler(stub, nil)
+	handler := adminv1.NewHandler(stub, nil, nil, nil, nil, nil)
 	group := e.Group("/admin/v1/users")
 	handler.RegisterRoutes(group)
 
@@ -48,14 +49,23 @@ func TestAdminUserListRoute(t *testing.T) {
 }
 
 type manageServiceStub struct {
-	listUsersFn func(ctx context.Context, offset, limit int) ([]domain.User, int64, error)
+	getUserFn     func(ctx context.Context, userID string) (*domain.User, error)
+	createUserFn  func(ctx context.Context, req service.CreateUserRequest) (*domain.User, error)
+	listUsersFn   func(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error)
+	streamUsersFn func(ctx context.Context, query domain.UserQuery, fn func(*domain.User) error) error
 }
 
 func (s *manageServiceStub) GetUser(ctx context.Context, userID string) (*domain.User, error) {
+	if s.getUserFn != nil {
+		return s.getUserFn(ctx, userID)
+	}
 	return nil, errors.New("not implemented")
 }
 
 func (s *manageServiceStub) CreateUser(ctx context.Context, req service.CreateUserRequest) (*domain.User, error) {
+	if s.createUserFn != nil {
+		return s.createUserFn(ctx, req)
+	}
 	return nil, errors.New("not implemented")
 }
 
@@ -63,17 +73,48 @@ func (s *manageServiceStub) UpdateUser(ctx context.Context, userID string, req s
 	return nil, errors.New("not implemented")
 }
 
-func (s *manageServiceStub) ChangeStatus(ctx context.Context, userID string, status domain.UserStatus) (*domain.User, error) {
+func (s *manageServiceStub) UpdateUserForService(ctx context.Context, userID string, req service.UpdateUserRequest) (*domain.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *manageServiceStub) ChangeStatus(ctx context.Context, userID string, req service.ChangeStatusRequest) (*domain.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *manageServiceStub) StatusHistory(ctx context.Context, userID string) ([]domain.UserStatusHistory, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *manageServiceStub) Suspend(ctx context.Context, userID string, req service.SuspendRequest) (*domain.User, error) {
 	return nil, errors.New("not implemented")
 }
 
+func (s *manageServiceStub) LiftSuspension(ctx context.Context, userID string, req service.LiftSuspensionRequest) (*domain.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *manageServiceStub) ReactivateExpiredSuspensions(ctx context.Context, now time.Time) (int, error) {
+	return 0, errors.New("not implemented")
+}
+
 func (s *manageServiceStub) ChangeRole(ctx context.Context, userID, role string) error {
 	return errors.New("not implemented")
 }
 
-func (s *manageServiceStub) ListUsers(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
+func (s *manageServiceStub) ListUsersByCursor(ctx context.Context, query domain.UserQuery) (*domain.UserPage, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *manageServiceStub) StreamUsers(ctx context.Context, query domain.UserQuery, fn func(*domain.User) error) error {
+	if s.streamUsersFn != nil {
+		return s.streamUsersFn(ctx, query, fn)
+	}
+	return errors.New("not implemented")
+}
+
+func (s *manageServiceStub) ListUsers(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
 	if s.listUsersFn != nil {
-		return s.listUsersFn(ctx, offset, limit)
+		return s.listUsersFn(ctx, query)
 	}
 	return nil, 0, nil
 }
@@ -0,0 +1,74 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/config"
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/log"
+)
+
+func TestAuthMiddlewareHidesVerifierAndRBACErrors(t *testing.T) {
+	conn := startEmbeddedNATS(t)
+	users := &userRepoStub{users: map[string]*domain.User{"user-1": {ID: "user-1", Status: domain.UserStatusActive, IsActive: true}}}
+	cfg := &config.Config{NATSAuthVerify: "auth.verifyJWT"}
+	authMW := middleware.NewAuthMiddleware(cfg, log.New("local"), &rbacStub{}, users, conn)
+
+	e := echo.New()
+	e.HTTPErrorHandler = middleware.ErrorHandler
+	e.GET("/api/v1/users/me", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, authMW.Handler)
+	call := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	reply := func(subject, data string) func() {
+		sub, err := conn.Subscribe(subject, func(msg *natsgo.Msg) { _ = msg.Respond([]byte(data)) })
+		require.NoError(t, err)
+		require.NoError(t, conn.Flush())
+		return func() { require.NoError(t, sub.Unsubscribe()) }
+	}
+
+	// Nobody answers: the auth service is down, not the token invalid.
+	rec := call()
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Contains(t, rec.Body.String(), "auth is unavailable")
+	require.NotContains(t, rec.Body.String(), "no responders")
+
+	stop := reply("auth.verifyJWT", "not json")
+	require.Equal(t, http.StatusServiceUnavailable, call().Code)
+	stop()
+
+	stop = reply("auth.verifyJWT", `{"ok":false,"error":"jwt: key 3 of tenant acme revoked"}`)
+	rec = call()
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Contains(t, rec.Body.String(), "invalid token")
+	require.NotContains(t, rec.Body.String(), "acme")
+	stop()
+
+	defer reply("auth.verifyJWT", `{"ok":true,"user_id":"user-1","claims":{"role":"student"}}`)()
+	stop = reply("rbac.checkRole", `{"ok":false,"error":"pq: relation role_grant does not exist"}`)
+	rec = call()
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), "role not allowed")
+	require.NotContains(t, rec.Body.String(), "role_grant")
+	stop()
+
+	stop = reply("rbac.checkRole", "not json")
+	rec = call()
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Contains(t, rec.Body.String(), "rbac is unavailable")
+	stop()
+
+	defer reply("rbac.checkRole", `{"ok":true}`)()
+	require.Equal(t, http.StatusOK, call().Code)
+}
@@ -0,0 +1,172 @@
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/config"
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	natsadapter "github.com/example/user-service/internal/adapters/nats"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+	"github.com/example/user-service/pkg/log"
+)
+
+type principalCacheFixture struct {
+	e        *echo.Echo
+	users    *userRepoStub
+	cache    *middleware.PrincipalCache
+	verified int
+}
+
+func newPrincipalCacheFixture(t *testing.T, size int, ttl time.Duration) *principalCacheFixture {
+	t.Helper()
+	f := &principalCacheFixture{
+		users: &userRepoStub{users: map[string]*domain.User{
+			"user-1": {ID: "user-1", Status: domain.UserStatusActive, IsActive: true},
+			"user-2": {ID: "user-2", Status: domain.UserStatusActive, IsActive: true},
+		}},
+		cache: middleware.NewPrincipalCache(size, ttl),
+	}
+	verifier := func(ctx context.Context, token string) (string, string, string, error) {
+		f.verified++
+		switch token {
+		case "token-1":
+			return "user-1", "student", "", nil
+		case "token-2":
+			return "user-2", "student", "", nil
+		}
+		if exp, ok := expiringTokenUser(token); ok {
+			return exp, "student", "", nil
+		}
+		return "", "", "", errors.New("invalid token")
+	}
+	authMW := middleware.NewAuthMiddlewareWithVerifier(&config.Config{}, log.New("local"), &rbacStub{}, f.users, nil, verifier).WithPrincipalCache(f.cache)
+	f.e = echo.New()
+	f.e.GET("/api/v1/users/me", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get("user_id").(string))
+	}, authMW.Handler)
+	return f
+}
+
+func (f *principalCacheFixture) call(token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	f.e.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestPrincipalCacheEvictsOnStatusChange(t *testing.T) {
+	conn := startEmbeddedNATS(t)
+	f := newPrincipalCacheFixture(t, 100, time.Minute)
+	require.NoError(t, natsadapter.SubscribeRevocations(conn, "auth.revoke", "user.events", f.cache))
+	require.NoError(t, conn.Flush())
+
+	require.Equal(t, http.StatusOK, f.call("token-1"))
+	require.Equal(t, http.StatusOK, f.call("token-1"))
+	require.Equal(t, http.StatusOK, f.call("token-2"))
+	require.Equal(t, 2, f.verified)
+	stats := f.cache.Stats()
+	require.Equal(t, 2, stats.Entries)
+	require.EqualValues(t, 1, stats.Hits)
+	require.EqualValues(t, 2, stats.Misses)
+	require.InDelta(t, 1.0/3, stats.HitRatio, 0.001)
+
+	// The user is blocked; until the event arrives the cached principal is
+	// still served.
+	f.users.users["user-1"] = &domain.User{ID: "user-1", Status: domain.UserStatusBlocked}
+	require.Equal(t, http.StatusOK, f.call("token-1"))
+	payload, err := json.Marshal(events.NewUserEvent(events.UserStatusChanged, "user-1", "", ""))
+	require.NoError(t, err)
+	require.NoError(t, conn.Publish("user.events."+events.UserStatusChanged, payload))
+	require.Eventually(t, func() bool { return f.cache.Stats().Entries == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, http.StatusForbidden, f.call("token-1"))
+	require.Equal(t, 3, f.verified)
+	require.Equal(t, http.StatusForbidden, f.call("token-1"), "a rejected user is not cached")
+	require.Equal(t, 4, f.verified)
+
+	// Logout revocations evict as well; unrelated events do not.
+	require.NoError(t, conn.Publish("user.events."+events.UserProfileUpdated, mustJSON(t, events.NewUserEvent(events.UserProfileUpdated, "user-2", "", ""))))
+	require.NoError(t, conn.Publish("auth.revoke", []byte(`{"user_id":"user-2"}`)))
+	require.Eventually(t, func() bool { return f.cache.Stats().Entries == 0 }, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, http.StatusOK, f.call("token-2"))
+	require.Equal(t, 5, f.verified)
+}
+
+func TestPrincipalCacheEvictsOnLocalCommit(t *testing.T) {
+	f := newPrincipalCacheFixture(t, 100, time.Minute)
+	outbox := natsadapter.EvictOnCommit(newMemoryOutbox("user.events"), f.cache)
+
+	require.Equal(t, http.StatusOK, f.call("token-1"))
+	require.Equal(t, http.StatusOK, f.call("token-2"))
+	f.users.users["user-1"] = &domain.User{ID: "user-1", Status: domain.UserStatusBlocked}
+
+	// No relay or subscriber runs; the local change alone evicts the user.
+	require.NoError(t, outbox.Enqueue(context.Background(), events.NewUserEvent(events.UserProfileUpdated, "user-2", "", "")))
+	require.NoError(t, outbox.Enqueue(context.Background(), events.NewUserEvent(events.UserStatusChanged, "user-1", "", "")))
+	require.Equal(t, 1, f.cache.Stats().Entries)
+	require.Equal(t, http.StatusForbidden, f.call("token-1"))
+}
+
+func TestPrincipalCacheBoundsAndExpiry(t *testing.T) {
+	f := newPrincipalCacheFixture(t, 1, time.Minute)
+
+	require.Equal(t, http.StatusOK, f.call("token-1"))
+	require.Equal(t, http.StatusOK, f.call("token-2"))
+	require.Equal(t, http.StatusOK, f.call("token-1"))
+	require.Equal(t, 3, f.verified, "the oldest entry is dropped when full")
+	require.EqualValues(t, 2, f.cache.Stats().Evictions)
+
+	// A token expiring before the TTL is only cached until it expires.
+	short := expiringToken("user-2", time.Now().Add(time.Second))
+	require.Equal(t, http.StatusOK, f.call(short))
+	require.Equal(t, http.StatusOK, f.call(short))
+	require.Equal(t, 4, f.verified)
+	time.Sleep(1100 * time.Millisecond)
+	require.Equal(t, http.StatusOK, f.call(short))
+	require.Equal(t, 5, f.verified)
+}
+
+func expiringToken(userID string, exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	claims, _ := json.Marshal(map[string]interface{}{"sub": userID, "exp": float64(exp.UnixNano()) / float64(time.Second)})
+	return header + "." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+}
+
+func expiringTokenUser(token string) (string, bool) {
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(data, &claims) != nil || claims.Sub == "" {
+		return "", false
+	}
+	return claims.Sub, true
+}
+
+func mustJSON(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	return data
+}
+
+func timePtr(value time.Time) *time.Time {
+	return &value
+}
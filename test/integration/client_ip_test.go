@@ -0,0 +1,38 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/internal/adapters/http/middleware"
+)
+
+func TestIPExtractorOnlyTrustsConfiguredProxies(t *testing.T) {
+	realIP := func(extractor echo.IPExtractor, remoteAddr, forwardedFor string) string {
+		e := echo.New()
+		e.IPExtractor = extractor
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		}
+		return e.NewContext(req, httptest.NewRecorder()).RealIP()
+	}
+
+	direct := middleware.IPExtractor(nil)
+	require.Equal(t, "203.0.113.9", realIP(direct, "203.0.113.9:4000", "198.51.100.1"))
+	require.Equal(t, "10.0.0.5", realIP(direct, "10.0.0.5:4000", "198.51.100.1"))
+
+	proxied := middleware.IPExtractor([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")})
+	require.Equal(t, "198.51.100.1", realIP(proxied, "10.0.0.5:4000", "198.51.100.1"))
+	// A client prepending its own entry does not get past the proxy hop.
+	require.Equal(t, "198.51.100.1", realIP(proxied, "10.0.0.5:4000", "1.2.3.4, 198.51.100.1"))
+	// Headers from untrusted peers, private ones included, are ignored.
+	require.Equal(t, "203.0.113.9", realIP(proxied, "203.0.113.9:4000", "1.2.3.4"))
+	require.Equal(t, "192.168.1.7", realIP(proxied, "192.168.1.7:4000", "1.2.3.4"))
+}
@@ -0,0 +1,32 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/config"
+	httpadapter "github.com/example/user-service/internal/adapters/http"
+	"github.com/example/user-service/internal/adapters/http/middleware"
+)
+
+func TestCORSPreflightAllowsConditionalAndIdempotentRequests(t *testing.T) {
+	e := echo.New()
+	router := httpadapter.NewRouter(&config.Config{CORSAllowOrigins: "https://app.example.com"}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, func(next echo.HandlerFunc) echo.HandlerFunc { return next })
+	router.Setup(e)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/users/me", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://app.example.com")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPatch)
+	req.Header.Set(echo.HeaderAccessControlRequestHeaders, "If-Match, Idempotency-Key")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	allowed := rec.Header().Get(echo.HeaderAccessControlAllowHeaders)
+	require.Contains(t, allowed, middleware.HeaderIfMatch)
+	require.Contains(t, allowed, middleware.HeaderIdempotencyKey)
+}
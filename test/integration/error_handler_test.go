@@ -0,0 +1,136 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/domain"
+	res "github.com/example/user-service/pkg/http"
+	pkglog "github.com/example/user-service/pkg/log"
+)
+
+func TestErrorHandlerWritesProblemDetails(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = middleware.ErrorHandler
+	routes := map[string]error{
+		"/validation": func() error {
+			errs := &domain.ValidationError{}
+			errs.Add("per", "invalid per")
+			errs.Add("sort", "invalid sort")
+			return errs.OrNil()
+		}(),
+		"/not-found":   domain.NotFound("user"),
+		"/gorm":        fmt.Errorf("load user: %w", gorm.ErrRecordNotFound),
+		"/conflict":    domain.ErrEmailInUse,
+		"/version":     fmt.Errorf("update: %w", domain.ErrVersionConflict),
+		"/gone":        domain.ErrRestoreWindowClosed,
+		"/unavailable": domain.Unavailable("rbac", errors.New("dial tcp 10.0.0.7:80: connection refused")),
+		"/internal":    errors.New(`pq: duplicate key value violates unique constraint "users_pkey"`),
+	}
+	for path, err := range routes {
+		err := err
+		e.GET(path, func(c echo.Context) error { return err })
+	}
+
+	for _, tc := range []struct {
+		path    string
+		status  int
+		code    string
+		message string
+	}{
+		{"/validation", http.StatusBadRequest, "validation_failed", "request is invalid"},
+		{"/not-found", http.StatusNotFound, "not_found", "user not found"},
+		{"/gorm", http.StatusNotFound, "not_found", "resource not found"},
+		{"/conflict", http.StatusConflict, "email_in_use", "email already in use"},
+		{"/version", http.StatusPreconditionFailed, "precondition_failed", "user was modified concurrently"},
+		{"/gone", http.StatusGone, "restore_window_closed", "restore window has closed"},
+		{"/unavailable", http.StatusServiceUnavailable, "dependency_unavailable", "rbac is unavailable"},
+		{"/internal", http.StatusInternalServerError, "internal_error", "internal server error"},
+		{"/no-such-route", http.StatusNotFound, "not_found", "Not Found"},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set(echo.HeaderXRequestID, "req-1")
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.status, rec.Code)
+			require.Equal(t, res.MIMEProblemJSON, rec.Header().Get(echo.HeaderContentType))
+			require.NotContains(t, rec.Body.String(), "10.0.0.7")
+			require.NotContains(t, rec.Body.String(), "users_pkey")
+
+			var problem res.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			require.Equal(t, "urn:user-service:problem:"+tc.code, problem.Type)
+			require.Equal(t, http.StatusText(tc.status), problem.Title)
+			require.Equal(t, tc.status, problem.Status)
+			require.Equal(t, tc.message, problem.Detail)
+			require.Equal(t, tc.path, problem.Instance)
+			require.Equal(t, tc.code, problem.Error.Code)
+			require.Equal(t, "req-1", problem.TraceID)
+		})
+	}
+}
+
+func TestErrorHandlerListsInvalidFields(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = middleware.ErrorHandler
+	e.GET("/users", func(c echo.Context) error {
+		return domain.UserQuery{Offset: -1, Limit: 10, SortBy: "age", Count: "sometimes"}.Validate()
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var problem struct {
+		Error struct {
+			Details []domain.FieldError `json:"details"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.Equal(t, []domain.FieldError{
+		{Field: "page", Message: "invalid pagination"},
+		{Field: "sort", Message: `invalid sort field "age"`},
+		{Field: "count", Message: `invalid count mode "sometimes"`},
+	}, problem.Error.Details)
+}
+
+func TestErrorHandlerLogsErrorsAfterCommit(t *testing.T) {
+	var logs bytes.Buffer
+	e := echo.New()
+	e.HTTPErrorHandler = middleware.ErrorHandler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := pkglog.WithContext(c.Request().Context(), zerolog.New(&logs))
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	})
+	e.GET("/stream", func(c echo.Context) error {
+		c.Response().WriteHeader(http.StatusOK)
+		_, _ = c.Response().Write([]byte("partial"))
+		return errors.New("stream users: connection reset")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "partial", rec.Body.String())
+	require.Contains(t, logs.String(), "stream users: connection reset")
+	require.Contains(t, logs.String(), `"request_id":"req-1"`)
+}
@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/config"
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/log"
+)
+
+func TestAuthMiddlewareGatewayHeaders(t *testing.T) {
+	cfg := &config.Config{
+		GatewayHeaderSecret: "gateway-secret",
+		GatewayReplayWindow: time.Minute,
+		GatewayTrustedCIDRs: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")},
+	}
+	users := &userRepoStub{users: map[string]*domain.User{"admin-1": {ID: "admin-1", Status: domain.UserStatusActive, IsActive: true}}}
+	verifier := func(ctx context.Context, token string) (string, string, string, error) {
+		return "", "", "", errors.New("no bearer token expected")
+	}
+	authMW := middleware.NewAuthMiddlewareWithVerifier(cfg, log.New("local"), &rbacStub{}, users, nil, verifier)
+
+	e := echo.New()
+	e.GET("/admin/v1/users", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get("user_id").(string))
+	}, authMW.Handler)
+
+	call := func(remoteAddr string, headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/v1/users", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-User-Id", "admin-1")
+		req.Header.Set("X-User-Role", "admin")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	signed := func(requestID string, at time.Time) map[string]string {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		return map[string]string{
+			echo.HeaderXRequestID:          requestID,
+			middleware.HeaderAuthTimestamp: timestamp,
+			middleware.HeaderAuthSignature: middleware.GatewaySignature("gateway-secret", "admin-1", "admin", timestamp, requestID),
+		}
+	}
+
+	const outside = "203.0.113.5:41000"
+	require.Equal(t, http.StatusUnauthorized, call(outside, nil), "unsigned")
+	require.Equal(t, http.StatusOK, call("10.20.3.4:41000", nil), "trusted network")
+
+	fresh := signed("req-1", time.Now())
+	require.Equal(t, http.StatusOK, call(outside, fresh), "signed")
+	require.Equal(t, http.StatusUnauthorized, call(outside, fresh), "replayed")
+	require.Equal(t, http.StatusUnauthorized, call(outside, signed("req-2", time.Now().Add(-2*time.Minute))), "stale")
+
+	forged := signed("req-3", time.Now())
+	forged["X-User-Role"] = "superadmin"
+	require.Equal(t, http.StatusUnauthorized, call(outside, forged), "role changed after signing")
+
+	unsignedCfg := &config.Config{}
+	strict := middleware.NewAuthMiddlewareWithVerifier(unsignedCfg, log.New("local"), &rbacStub{}, users, nil, verifier)
+	e.GET("/strict", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, strict.Handler)
+	req := httptest.NewRequest(http.MethodGet, "/strict", nil)
+	req.Header.Set("X-User-Id", "admin-1")
+	req.Header.Set("X-User-Role", "admin")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code, "no secret and no allowlist")
+}
@@ -0,0 +1,252 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/log"
+)
+
+type idempotencyRepoStub struct {
+	mu      sync.Mutex
+	records map[string]*domain.IdempotencyRecord
+	nextID  int
+}
+
+func newIdempotencyRepoStub() *idempotencyRepoStub {
+	return &idempotencyRepoStub{records: map[string]*domain.IdempotencyRecord{}}
+}
+
+func (s *idempotencyRepoStub) Reserve(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.records {
+		if existing.Principal == record.Principal && existing.Key == record.Key && existing.ExpiresAt.After(time.Now()) {
+			copied := *existing
+			return &copied, nil
+		}
+	}
+	s.nextID++
+	record.ID = strconv.Itoa(s.nextID)
+	copied := *record
+	s.records[record.ID] = &copied
+	return nil, nil
+}
+
+func (s *idempotencyRepoStub) Complete(ctx context.Context, id string, status int, headers domain.JSONMap, body []byte, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[id]
+	record.ResponseStatus = &status
+	record.ResponseHeaders = headers
+	record.ResponseBody = body
+	record.ExpiresAt = expiresAt
+	return nil
+}
+
+func (s *idempotencyRepoStub) Release(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, id)
+	return nil
+}
+
+func (s *idempotencyRepoStub) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+type idempotencyFixture struct {
+	e          *echo.Echo
+	created    int
+	imported   int
+	release    chan struct{}
+	disconnect context.CancelFunc
+}
+
+func newIdempotencyFixture() *idempotencyFixture {
+	f := &idempotencyFixture{}
+	idempotency := middleware.NewIdempotencyMiddleware(newIdempotencyRepoStub(), time.Hour, log.New("local")).SkipRoutes("/admin/v1/users/import")
+	identify := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", c.Request().Header.Get("X-Test-User"))
+			return next(c)
+		}
+	}
+	f.e = echo.New()
+	g := f.e.Group("/admin/v1/users", identify, idempotency.Handler)
+	g.POST("", func(c echo.Context) error {
+		if f.release != nil {
+			<-f.release
+		}
+		f.created++
+		if f.disconnect != nil {
+			f.disconnect()
+		}
+		if strings.Contains(c.Request().Header.Get(echo.HeaderContentType), "multipart") {
+			if _, err := c.FormFile("file"); err != nil {
+				return c.NoContent(http.StatusBadRequest)
+			}
+		}
+		c.Response().Header().Set(echo.HeaderLocation, "/admin/v1/users/user-1")
+		return c.JSON(http.StatusCreated, map[string]int{"created": f.created})
+	})
+	report := func(c echo.Context) error {
+		f.imported++
+		return c.String(http.StatusOK, strings.Repeat("row\n", 1<<19))
+	}
+	g.POST("/import", report)
+	g.POST("/bulk", report)
+	g.DELETE("/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusServiceUnavailable)
+	})
+	return f
+}
+
+func (f *idempotencyFixture) send(method, path, userID, key, contentType string, body []byte) *httptest.ResponseRecorder {
+	return f.sendContext(context.Background(), method, path, userID, key, contentType, body)
+}
+
+func (f *idempotencyFixture) sendContext(ctx context.Context, method, path, userID, key, contentType string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(ctx, method, path, bytes.NewReader(body))
+	req.Header.Set("X-Test-User", userID)
+	if key != "" {
+		req.Header.Set(middleware.HeaderIdempotencyKey, key)
+	}
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	rec := httptest.NewRecorder()
+	f.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyKeyReplaysResponse(t *testing.T) {
+	f := newIdempotencyFixture()
+	body := []byte(`{"email":"a@example.com"}`)
+
+	first := f.send(http.MethodPost, "/admin/v1/users", "admin-1", "key-1", echo.MIMEApplicationJSON, body)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Empty(t, first.Header().Get(middleware.HeaderIdempotentReplayed))
+
+	retry := f.send(http.MethodPost, "/admin/v1/users", "admin-1", "key-1", echo.MIMEApplicationJSON, body)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(middleware.HeaderIdempotentReplayed))
+	require.Equal(t, first.Body.String(), retry.Body.String())
+	require.Equal(t, "/admin/v1/users/user-1", retry.Header().Get(echo.HeaderLocation))
+	require.Equal(t, first.Header().Get(echo.HeaderContentType), retry.Header().Get(echo.HeaderContentType))
+	require.Equal(t, 1, f.created)
+
+	// Same key with another body is rejected; other callers and requests
+	// without a key are unaffected.
+	reused := f.send(http.MethodPost, "/admin/v1/users", "admin-1", "key-1", echo.MIMEApplicationJSON, []byte(`{"email":"b@example.com"}`))
+	require.Equal(t, http.StatusConflict, reused.Code)
+	require.Contains(t, reused.Body.String(), "idempotency_key_reused")
+	require.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/admin/v1/users", "admin-2", "key-1", echo.MIMEApplicationJSON, body).Code)
+	require.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/admin/v1/users", "admin-1", "", echo.MIMEApplicationJSON, body).Code)
+	require.Equal(t, 3, f.created)
+}
+
+func TestIdempotencyKeyMultipartRetries(t *testing.T) {
+	f := newIdempotencyFixture()
+	multipartBody := func() ([]byte, string) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, err := writer.CreateFormFile("file", "avatar.png")
+		require.NoError(t, err)
+		_, _ = part.Write([]byte("png-bytes"))
+		require.NoError(t, writer.Close())
+		return buf.Bytes(), writer.FormDataContentType()
+	}
+
+	// Each attempt uses a fresh random boundary.
+	body, contentType := multipartBody()
+	require.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/admin/v1/users", "user-1", "upload-1", contentType, body).Code)
+	body, contentType = multipartBody()
+	retry := f.send(http.MethodPost, "/admin/v1/users", "user-1", "upload-1", contentType, body)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(middleware.HeaderIdempotentReplayed))
+	require.Equal(t, 1, f.created)
+}
+
+func TestIdempotencyKeyInFlightAndFailures(t *testing.T) {
+	f := newIdempotencyFixture()
+	f.release = make(chan struct{})
+	body := []byte(`{"email":"a@example.com"}`)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- f.send(http.MethodPost, "/admin/v1/users", "admin-1", "key-1", echo.MIMEApplicationJSON, body)
+	}()
+	require.Eventually(t, func() bool {
+		rec := f.send(http.MethodPost, "/admin/v1/users", "admin-1", "key-1", echo.MIMEApplicationJSON, body)
+		return rec.Code == http.StatusConflict && strings.Contains(rec.Body.String(), "request_in_progress")
+	}, 2*time.Second, 10*time.Millisecond)
+	close(f.release)
+	require.Equal(t, http.StatusCreated, (<-done).Code)
+	require.Equal(t, 1, f.created)
+
+	// Server errors are not stored, so the retry runs the handler again.
+	require.Equal(t, http.StatusServiceUnavailable, f.send(http.MethodDelete, "/admin/v1/users/user-1", "admin-1", "key-2", "", nil).Code)
+	retry := f.send(http.MethodDelete, "/admin/v1/users/user-1", "admin-1", "key-2", "", nil)
+	require.Equal(t, http.StatusServiceUnavailable, retry.Code)
+	require.Empty(t, retry.Header().Get(middleware.HeaderIdempotentReplayed))
+}
+
+func TestIdempotencyKeyStoredAfterClientDisconnects(t *testing.T) {
+	f := newIdempotencyFixture()
+	body := []byte(`{"email":"a@example.com"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.disconnect = cancel
+	require.Equal(t, http.StatusCreated, f.sendContext(ctx, http.MethodPost, "/admin/v1/users", "admin-1", "key-1", echo.MIMEApplicationJSON, body).Code)
+	f.disconnect = nil
+
+	retry := f.send(http.MethodPost, "/admin/v1/users", "admin-1", "key-1", echo.MIMEApplicationJSON, body)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(middleware.HeaderIdempotentReplayed))
+	require.Equal(t, 1, f.created)
+}
+
+func TestIdempotencyKeyBoundsBufferedBodies(t *testing.T) {
+	f := newIdempotencyFixture()
+
+	tooLarge := f.send(http.MethodPost, "/admin/v1/users", "admin-1", "key-1", echo.MIMEApplicationJSON, bytes.Repeat([]byte("a"), 10<<20+1))
+	require.Equal(t, http.StatusRequestEntityTooLarge, tooLarge.Code)
+	require.Contains(t, tooLarge.Body.String(), "payload_too_large")
+	require.Equal(t, 0, f.created)
+
+	// Skipped streaming routes run every time and are never stored.
+	for i := 1; i <= 2; i++ {
+		rec := f.send(http.MethodPost, "/admin/v1/users/import", "admin-1", "key-2", "text/csv", bytes.Repeat([]byte("a"), 10<<20+1))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Empty(t, rec.Header().Get(middleware.HeaderIdempotentReplayed))
+		require.Equal(t, i, f.imported)
+	}
+
+	// Large responses elsewhere are replayed without their body.
+	require.Equal(t, http.StatusOK, f.send(http.MethodPost, "/admin/v1/users/bulk", "admin-1", "key-3", "", nil).Code)
+	retry := f.send(http.MethodPost, "/admin/v1/users/bulk", "admin-1", "key-3", "", nil)
+	require.Equal(t, http.StatusOK, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(middleware.HeaderIdempotentReplayed))
+	require.Empty(t, retry.Body.String())
+	require.Equal(t, 3, f.imported)
+}
@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/config"
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/adapters/jwt"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/pkg/log"
+)
+
+func TestAuthMiddlewareLocalVerifierFallsBackOnUnknownKey(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := jwt.NewStaticKeySet(jwt.Key{ID: "current", Algorithm: jwt.AlgEdDSA, Public: public})
+	verifier := jwt.NewVerifier(keys, jwt.VerifierConfig{Issuer: "auth", Audience: "frontend", ClockSkew: time.Minute})
+
+	fallbackCalls := 0
+	fallback := func(ctx context.Context, token string) (string, string, string, error) {
+		fallbackCalls++
+		return "user-2", "student", "", nil
+	}
+	users := &userRepoStub{users: map[string]*domain.User{
+		"user-1": {ID: "user-1", Status: domain.UserStatusActive, IsActive: true},
+		"user-2": {ID: "user-2", Status: domain.UserStatusActive, IsActive: true},
+	}}
+	authMW := middleware.NewAuthMiddlewareWithVerifier(&config.Config{}, log.New("local"), &rbacStub{}, users, nil, middleware.LocalTokenVerifier(verifier, fallback))
+
+	e := echo.New()
+	e.GET("/api/v1/users/me", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get("user_id").(string)+" "+c.Get("role").(string))
+	}, authMW.Handler)
+	call := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	claims := map[string]interface{}{"sub": "user-1", "role": "admin", "iss": "auth", "aud": "frontend", "exp": time.Now().Add(time.Hour).Unix()}
+
+	rec := call(signEdDSA(t, "current", private, claims))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "user-1 ADMIN", rec.Body.String())
+	require.Zero(t, fallbackCalls)
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rec = call(signEdDSA(t, "current", otherKey, claims))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Zero(t, fallbackCalls)
+
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	rec = call(signEdDSA(t, "current", private, claims))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Zero(t, fallbackCalls)
+
+	rec = call(signEdDSA(t, "rotated", otherKey, claims))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "user-2 STUDENT", rec.Body.String())
+	require.Equal(t, 1, fallbackCalls)
+}
+
+func signEdDSA(t *testing.T, kid string, key ed25519.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	encode := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(map[string]string{"alg": jwt.AlgEdDSA, "kid": kid}) + "." + encode(claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(signed)))
+}
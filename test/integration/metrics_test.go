@@ -0,0 +1,74 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	natsadapter "github.com/example/user-service/internal/adapters/nats"
+	"github.com/example/user-service/pkg/metrics"
+)
+
+func scrapeMetrics(t *testing.T, e *echo.Echo) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestMetricsRecordRoutesAndStatuses(t *testing.T) {
+	e := echo.New()
+	e.Use(middleware.Metrics, echomw.RecoverWithConfig(echomw.RecoverConfig{DisablePrintStack: true}))
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	e.GET("/metrics-test/users/:id", func(c echo.Context) error {
+		if c.Param("id") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.GET("/metrics-test/panic", func(c echo.Context) error {
+		panic("boom")
+	})
+
+	for _, path := range []string{"/metrics-test/users/1", "/metrics-test/users/2", "/metrics-test/users/missing", "/metrics-test/panic", "/no-such-route-for-metrics"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrapeMetrics(t, e)
+	require.Contains(t, body, `user_service_http_requests_total{method="GET",route="/metrics-test/users/:id",status="204"} 2`)
+	require.Contains(t, body, `user_service_http_requests_total{method="GET",route="/metrics-test/users/:id",status="404"} 1`)
+	require.Contains(t, body, `user_service_http_requests_total{method="GET",route="/metrics-test/panic",status="500"} 1`)
+	require.Contains(t, body, `user_service_http_request_duration_seconds_count{method="GET",route="/metrics-test/users/:id",status="204"} 2`)
+	require.Contains(t, body, `route="unmatched",status="404"`)
+	require.NotContains(t, body, "no-such-route-for-metrics")
+	require.Contains(t, body, "go_goroutines")
+}
+
+func TestMetricsRecordNATSHandlers(t *testing.T) {
+	conn := startEmbeddedNATS(t)
+	server := natsadapter.Server{Conn: conn}
+	require.NoError(t, server.Subscribe("metrics.test.echo", "metrics-test", func(ctx context.Context, msg *natsgo.Msg) {
+		natsadapter.Respond(msg, map[string]bool{"ok": true})
+	}))
+	require.NoError(t, conn.Flush())
+
+	_, err := conn.Request("metrics.test.echo", []byte("{}"), 2*time.Second)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	// The reply can arrive before the handler wrapper records the call.
+	require.Eventually(t, func() bool {
+		return strings.Contains(scrapeMetrics(t, e), `user_service_nats_requests_total{subject="metrics.test.echo"} 1`)
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,263 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	natsadapter "github.com/example/user-service/internal/adapters/nats"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+	"github.com/example/user-service/pkg/log"
+	"github.com/example/user-service/pkg/metrics"
+)
+
+func TestOutboxRelay_PublishesToNATS(t *testing.T) {
+	conn := startEmbeddedNATS(t)
+
+	sub, err := conn.SubscribeSync("user.events.>")
+	require.NoError(t, err)
+	require.NoError(t, conn.Flush())
+
+	outbox := newMemoryOutbox("user.events")
+	require.NoError(t, outbox.Enqueue(context.Background(), events.NewUserEvent(events.UserCreated, "user-1", "user@example.com", "")))
+
+	relay := natsadapter.NewOutboxRelay(conn, outbox, passthroughTx{}, natsadapter.OutboxRelayConfig{BatchSize: 10}, log.New("test"))
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	msg, err := sub.NextMsg(2 * time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "user.events.created", msg.Subject)
+	require.Equal(t, outbox.msgs[0].ID, msg.Header.Get(natsgo.MsgIdHdr))
+
+	var event events.UserEvent
+	require.NoError(t, json.Unmarshal(msg.Data, &event))
+	require.Equal(t, "user-1", event.UserID)
+	require.NotNil(t, outbox.msgs[0].PublishedAt)
+
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestOutboxRelay_RetriesFailedPublish(t *testing.T) {
+	outbox := newMemoryOutbox("user.events")
+	require.NoError(t, outbox.Enqueue(context.Background(), events.NewUserEvent(events.UserStatusChanged, "user-1", "", "")))
+
+	pub := &flakyPublisher{err: errors.New("nats unavailable")}
+	relay := natsadapter.NewOutboxRelay(pub, outbox, passthroughTx{}, natsadapter.OutboxRelayConfig{BatchSize: 10, RetryBackoff: time.Millisecond}, log.New("test"))
+
+	_, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, outbox.msgs[0].Attempts)
+	require.Nil(t, outbox.msgs[0].PublishedAt)
+	require.NotNil(t, outbox.msgs[0].LastError)
+
+	pub.err = nil
+	time.Sleep(5 * time.Millisecond)
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.NotNil(t, outbox.msgs[0].PublishedAt)
+	require.Equal(t, 1, pub.published)
+}
+
+func TestOutboxRelay_CleanupRemovesOldPublishedRows(t *testing.T) {
+	outbox := newMemoryOutbox("user.events")
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	outbox.msgs = append(outbox.msgs, &domain.OutboxMessage{ID: "old", PublishedAt: &old}, &domain.OutboxMessage{ID: "pending"})
+
+	relay := natsadapter.NewOutboxRelay(&flakyPublisher{}, outbox, passthroughTx{}, natsadapter.OutboxRelayConfig{Retention: 24 * time.Hour}, log.New("test"))
+	deleted, err := relay.Cleanup(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+	require.Len(t, outbox.msgs, 1)
+	require.Equal(t, "pending", outbox.msgs[0].ID)
+}
+
+func TestOutboxRelay_CleanupReportsAndPurgesDeadLetters(t *testing.T) {
+	outbox := newMemoryOutbox("user.events")
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	outbox.msgs = append(outbox.msgs,
+		&domain.OutboxMessage{ID: "dead-old", Attempts: 3, CreatedAt: old},
+		&domain.OutboxMessage{ID: "dead-new", Attempts: 3, CreatedAt: time.Now().UTC()},
+		&domain.OutboxMessage{ID: "retrying", Attempts: 2, CreatedAt: old},
+	)
+
+	relay := natsadapter.NewOutboxRelay(&flakyPublisher{}, outbox, passthroughTx{}, natsadapter.OutboxRelayConfig{Retention: 24 * time.Hour, MaxAttempts: 3}, log.New("test"))
+	deleted, err := relay.Cleanup(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+	require.Len(t, outbox.msgs, 2)
+	e := echo.New()
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	require.Contains(t, scrapeMetrics(t, e), `user_service_dead_letters{queue="outbox"} 1`)
+}
+
+func startEmbeddedNATS(t *testing.T) *natsgo.Conn {
+	t.Helper()
+	srv, err := natsserver.NewServer(&natsserver.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	require.NoError(t, err)
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("embedded nats server not ready")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	conn, err := natsgo.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	return conn
+}
+
+type passthroughTx struct{}
+
+func (passthroughTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type flakyPublisher struct {
+	err       error
+	published int
+}
+
+func (p *flakyPublisher) PublishMsg(msg *natsgo.Msg) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published++
+	return nil
+}
+
+func (p *flakyPublisher) FlushTimeout(timeout time.Duration) error {
+	return nil
+}
+
+type memoryOutbox struct {
+	mu     sync.Mutex
+	prefix string
+	msgs   []*domain.OutboxMessage
+	nextID int
+}
+
+func newMemoryOutbox(prefix string) *memoryOutbox {
+	return &memoryOutbox{prefix: prefix}
+}
+
+func (o *memoryOutbox) Enqueue(_ context.Context, event events.UserEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.nextID++
+	o.msgs = append(o.msgs, &domain.OutboxMessage{
+		ID:            fmt.Sprintf("outbox-%d", o.nextID),
+		Subject:       event.Subject(o.prefix),
+		Payload:       payload,
+		NextAttemptAt: time.Now().UTC(),
+		CreatedAt:     time.Now().UTC(),
+	})
+	return nil
+}
+
+func (o *memoryOutbox) LockPending(_ context.Context, limit, maxAttempts int) ([]domain.OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now().UTC()
+	var out []domain.OutboxMessage
+	for _, m := range o.msgs {
+		if m.PublishedAt != nil || m.NextAttemptAt.After(now) || (maxAttempts > 0 && m.Attempts >= maxAttempts) {
+			continue
+		}
+		out = append(out, *m)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (o *memoryOutbox) MarkPublished(_ context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now().UTC()
+	o.find(id).PublishedAt = &now
+	return nil
+}
+
+func (o *memoryOutbox) MarkFailed(_ context.Context, id string, cause error, nextAttemptAt time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	m := o.find(id)
+	m.Attempts++
+	msg := cause.Error()
+	m.LastError = &msg
+	m.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (o *memoryOutbox) DeletePublishedBefore(_ context.Context, before time.Time) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.msgs[:0]
+	var deleted int64
+	for _, m := range o.msgs {
+		if m.PublishedAt != nil && m.PublishedAt.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, m)
+	}
+	o.msgs = kept
+	return deleted, nil
+}
+
+func (o *memoryOutbox) CountDead(_ context.Context, maxAttempts int) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var count int64
+	for _, m := range o.msgs {
+		if m.PublishedAt == nil && m.Attempts >= maxAttempts {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (o *memoryOutbox) DeleteDeadBefore(_ context.Context, maxAttempts int, before time.Time) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.msgs[:0]
+	var deleted int64
+	for _, m := range o.msgs {
+		if m.PublishedAt == nil && m.Attempts >= maxAttempts && m.CreatedAt.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, m)
+	}
+	o.msgs = kept
+	return deleted, nil
+}
+
+func (o *memoryOutbox) find(id string) *domain.OutboxMessage {
+	for _, m := range o.msgs {
+		if m.ID == id {
+			return m
+		}
+	}
+	return &domain.OutboxMessage{}
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/adapters/ratelimit"
+	"github.com/example/user-service/pkg/log"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func newRateLimitedServer(store ratelimit.Store) *echo.Echo {
+	limiter := middleware.NewRateLimiter(store, log.New("local"))
+	// Stands in for the auth middleware.
+	identify := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if userID := c.Request().Header.Get("X-Test-User"); userID != "" {
+				c.Set("user_id", userID)
+			}
+			return next(c)
+		}
+	}
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	apiLimit := limiter.Limit("api", ratelimit.Limit{PerMinute: 3})
+
+	e := echo.New()
+	g := e.Group("/api/v1/users", identify, apiLimit)
+	g.GET("/me", ok)
+	e.POST("/api/v1/users/me/avatar", ok, identify, apiLimit, limiter.Limit("avatar", ratelimit.Limit{PerMinute: 1}))
+	return e
+}
+
+func rateLimitedCall(e *echo.Echo, method, path, userID, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if userID != "" {
+		req.Header.Set("X-Test-User", userID)
+	}
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiterBudgetsAndHeaders(t *testing.T) {
+	e := newRateLimitedServer(ratelimit.NewMemoryStore())
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		rec := rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "user-1", "10.0.0.1:1000")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Equal(t, "3", rec.Header().Get(middleware.HeaderRateLimitLimit))
+		require.Equal(t, strconv.Itoa(remaining), rec.Header().Get(middleware.HeaderRateLimitRemaining))
+	}
+	rec := rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "user-1", "10.0.0.1:1000")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Contains(t, rec.Body.String(), "rate_limited")
+	require.Equal(t, "20", rec.Header().Get(echo.HeaderRetryAfter))
+	require.Equal(t, "60", rec.Header().Get(middleware.HeaderRateLimitReset))
+
+	// Budgets are per user, even behind the same address.
+	rec = rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "user-2", "10.0.0.1:1000")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Anonymous callers are keyed by IP.
+	require.Equal(t, http.StatusNoContent, rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "", "10.0.0.2:1000").Code)
+	require.Equal(t, "2", rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "", "10.0.0.3:1000").Header().Get(middleware.HeaderRateLimitRemaining))
+
+	// Expensive routes have their own, smaller budget on top.
+	require.Equal(t, http.StatusNoContent, rateLimitedCall(e, http.MethodPost, "/api/v1/users/me/avatar", "user-2", "10.0.0.1:1000").Code)
+	rec = rateLimitedCall(e, http.MethodPost, "/api/v1/users/me/avatar", "user-2", "10.0.0.1:1000")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "60", rec.Header().Get(echo.HeaderRetryAfter))
+	require.Equal(t, "0", rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "user-2", "10.0.0.1:1000").Header().Get(middleware.HeaderRateLimitRemaining))
+}
+
+func TestRateLimiterLimitsByIPBeforeAuth(t *testing.T) {
+	limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), log.New("local"))
+	verified := 0
+	// Stands in for the auth middleware rejecting every token.
+	reject := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			verified++
+			return c.NoContent(http.StatusUnauthorized)
+		}
+	}
+	e := echo.New()
+	e.GET("/api/v1/users/me", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }, limiter.LimitByIP("ip", ratelimit.Limit{PerMinute: 2}), reject)
+
+	require.Equal(t, http.StatusUnauthorized, rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "", "10.0.0.1:1000").Code)
+	require.Equal(t, http.StatusUnauthorized, rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "", "10.0.0.1:1001").Code)
+	require.Equal(t, http.StatusTooManyRequests, rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "", "10.0.0.1:1002").Code)
+	require.Equal(t, 2, verified, "throttled requests never reach token verification")
+	require.Equal(t, http.StatusUnauthorized, rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "", "10.0.0.2:1000").Code)
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	e := newRateLimitedServer(failingRateLimitStore{})
+
+	for i := 0; i < 5; i++ {
+		rec := rateLimitedCall(e, http.MethodGet, "/api/v1/users/me", "user-1", "10.0.0.1:1000")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Empty(t, rec.Header().Get(middleware.HeaderRateLimitLimit))
+	}
+}
@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	adminv1 "github.com/example/user-service/internal/adapters/http/admin/v1"
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/usecase"
+	"github.com/example/user-service/pkg/log"
+)
+
+func TestServiceAPIKeyAuthAndScopes(t *testing.T) {
+	clients := service.NewServiceClientService(newServiceClientRepoStub())
+	reader, readKey, err := clients.Issue(context.Background(), service.IssueServiceClientRequest{Name: "billing", Scopes: []string{domain.ScopeUsersRead}})
+	require.NoError(t, err)
+
+	stub := &manageServiceStub{}
+	var createdBy string
+	stub.getUserFn = func(ctx context.Context, userID string) (*domain.User, error) {
+		return &domain.User{ID: userID, Email: "user@example.com"}, nil
+	}
+	apiKeyMW := middleware.NewAPIKeyMiddleware(clients, log.New("local"))
+	handler := adminv1.NewHandler(stub, nil, nil, nil, nil, nil)
+
+	e := echo.New()
+	group := e.Group("/internal/v1/users", apiKeyMW.Handler)
+	group.GET("/:id", handler.GetUser, middleware.RequireScope(domain.ScopeUsersRead))
+	group.PATCH("/:id", func(c echo.Context) error {
+		createdBy, _ = c.Get("client_id").(string)
+		return c.NoContent(http.StatusNoContent)
+	}, middleware.RequireScope(domain.ScopeUsersWrite))
+
+	var body string
+	call := func(method, authorization string) int {
+		req := httptest.NewRequest(method, "/internal/v1/users/user-1", nil)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		body = rec.Body.String()
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, ""))
+	require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "Bearer "+readKey))
+	require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "ApiKey "+readKey+"x"))
+	require.Contains(t, body, "invalid api key")
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "ApiKey "+readKey))
+	require.Equal(t, http.StatusForbidden, call(http.MethodPatch, "ApiKey "+readKey))
+
+	writer, writeKey, err := clients.Issue(context.Background(), service.IssueServiceClientRequest{Name: "crm", Scopes: []string{domain.ScopeUsersRead, domain.ScopeUsersWrite}})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, call(http.MethodPatch, "ApiKey "+writeKey))
+	require.Equal(t, writer.ID, createdBy)
+
+	_, rotatedKey, err := clients.Rotate(context.Background(), reader.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "ApiKey "+readKey))
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "ApiKey "+rotatedKey))
+
+	_, err = clients.Revoke(context.Background(), reader.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "ApiKey "+rotatedKey))
+}
+
+func TestServiceAPIKeyCannotGrantPrivilegedRoles(t *testing.T) {
+	clients := service.NewServiceClientService(newServiceClientRepoStub())
+	_, writeKey, err := clients.Issue(context.Background(), service.IssueServiceClientRequest{Name: "crm", Scopes: []string{domain.ScopeUsersWrite}})
+	require.NoError(t, err)
+
+	stub := &manageServiceStub{}
+	var created []string
+	stub.createUserFn = func(ctx context.Context, req service.CreateUserRequest) (*domain.User, error) {
+		created = append(created, req.Role)
+		return &domain.User{ID: "user-1", Email: req.Email}, nil
+	}
+	handler := adminv1.NewHandler(stub, nil, nil, nil, nil, nil)
+
+	e := echo.New()
+	e.HTTPErrorHandler = middleware.ErrorHandler
+	group := e.Group("/internal/v1/users", middleware.NewAPIKeyMiddleware(clients, log.New("local")).Handler)
+	group.POST("", handler.CreateUserForService, middleware.RequireScope(domain.ScopeUsersWrite))
+
+	create := func(role string) *httptest.ResponseRecorder {
+		body := `{"email":"user@example.com","password":"secret-password","role":"` + role + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/internal/v1/users", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "ApiKey "+writeKey)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, role := range []string{"admin", " Admin", "moderator"} {
+		rec := create(role)
+		require.Equal(t, http.StatusForbidden, rec.Code, role)
+		require.Contains(t, rec.Body.String(), "role_not_allowed")
+	}
+	require.Empty(t, created)
+
+	require.Equal(t, http.StatusCreated, create("student").Code)
+	require.Equal(t, []string{"student"}, created)
+}
+
+type serviceClientRepoStub struct {
+	clients map[string]*domain.ServiceClient
+}
+
+func newServiceClientRepoStub() *serviceClientRepoStub {
+	return &serviceClientRepoStub{clients: map[string]*domain.ServiceClient{}}
+}
+
+func (r *serviceClientRepoStub) Create(ctx context.Context, client *domain.ServiceClient) error {
+	client.ID = "client-" + client.KeyPrefix
+	copied := *client
+	r.clients[client.ID] = &copied
+	return nil
+}
+
+func (r *serviceClientRepoStub) Update(ctx context.Context, client *domain.ServiceClient) error {
+	copied := *client
+	r.clients[client.ID] = &copied
+	return nil
+}
+
+func (r *serviceClientRepoStub) FindByID(ctx context.Context, id string) (*domain.ServiceClient, error) {
+	if client, ok := r.clients[id]; ok {
+		copied := *client
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *serviceClientRepoStub) FindByKeyPrefix(ctx context.Context, prefix string) (*domain.ServiceClient, error) {
+	for _, client := range r.clients {
+		if client.KeyPrefix == prefix {
+			copied := *client
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *serviceClientRepoStub) List(ctx context.Context) ([]domain.ServiceClient, error) {
+	var out []domain.ServiceClient
+	for _, client := range r.clients {
+		out = append(out, *client)
+	}
+	return out, nil
+}
+
+func (r *serviceClientRepoStub) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	if client, ok := r.clients[id]; ok {
+		client.LastUsedAt = &at
+	}
+	return nil
+}
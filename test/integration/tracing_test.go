@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/user-service/internal/adapters/http/middleware"
+	natsadapter "github.com/example/user-service/internal/adapters/nats"
+	res "github.com/example/user-service/pkg/http"
+	"github.com/example/user-service/pkg/tracing"
+)
+
+const incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// recordSpans sets up tracing without an exporter and records finished spans.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{ServiceName: "user-service-test", Exporter: "none", SampleRatio: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestTracingContinuesIncomingHTTPTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var handlerTraceID string
+	e := echo.New()
+	e.Use(middleware.Tracing)
+	e.GET("/users/:id", func(c echo.Context) error {
+		handlerTraceID = tracing.TraceID(c.Request().Context())
+		return res.ErrorJSON(c, http.StatusNotFound, "not_found", "user not found", middleware.TraceIDFromCtx(c), nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-"+incomingTraceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, incomingTraceID, handlerTraceID)
+	var body res.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, incomingTraceID, body.TraceID)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "GET /users/:id", spans[0].Name())
+	require.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	require.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
+
+func TestTracingPropagatesOverNATSAndHTTPClients(t *testing.T) {
+	recorder := recordSpans(t)
+	conn := startEmbeddedNATS(t)
+
+	traceIDs := make(chan string, 1)
+	server := natsadapter.Server{Conn: conn}
+	require.NoError(t, server.Subscribe("tracing.test.echo", "tracing-test", func(ctx context.Context, msg *natsgo.Msg) {
+		traceIDs <- tracing.TraceID(ctx)
+		natsadapter.Respond(msg, map[string]bool{"ok": true})
+	}))
+	require.NoError(t, conn.Flush())
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "test")
+	defer parent.End()
+	traceID := parent.SpanContext().TraceID().String()
+
+	reqCtx, span, msg := tracing.StartNATSRequest(ctx, "tracing.test.echo", []byte("{}"))
+	_, err := conn.RequestMsgWithContext(reqCtx, msg)
+	tracing.End(span, err)
+	require.NoError(t, err)
+	select {
+	case got := <-traceIDs:
+		require.Equal(t, traceID, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler not called")
+	}
+
+	var traceparent string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer downstream.Close()
+	client := &http.Client{Transport: tracing.Transport("rbac", nil)}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downstream.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Contains(t, traceparent, traceID)
+
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+	}
+	require.Contains(t, names, "tracing.test.echo")
+	require.Contains(t, names, "rbac GET")
+}
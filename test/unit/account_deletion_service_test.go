@@ -0,0 +1,145 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+	"github.com/example/user-service/internal/usecase"
+)
+
+func TestAccountDeletionService_DeleteAndRestore(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	history := &statusHistoryRepo{}
+	outbox := &recordingOutbox{}
+	audit := &auditLogRepo{}
+	svc := service.NewAccountDeletionService(users, newManageProfileRepo(), identityRepoStub{}, &providerRepoStub{}, history, &countingTx{}, outbox, audit, 24*time.Hour)
+
+	u := &domain.User{ID: "user-1", Email: "gone@example.com", Status: domain.UserStatusInactive}
+	require.NoError(t, users.Create(context.Background(), u))
+
+	deleted, err := svc.Delete(context.Background(), u.ID, u.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.UserStatusDeleted, deleted.Status)
+	require.NotNil(t, deleted.PurgeAfter)
+	require.WithinDuration(t, time.Now().Add(24*time.Hour), *deleted.PurgeAfter, time.Minute)
+
+	_, err = users.FindByID(context.Background(), u.ID)
+	require.Error(t, err)
+	_, err = svc.Delete(context.Background(), u.ID, u.ID)
+	require.ErrorIs(t, err, domain.ErrAlreadyDeleted)
+
+	ctx := service.WithAuditActor(context.Background(), domain.AuditActor{ID: "admin-1", RequestID: "req-1"})
+	restored, err := svc.Restore(ctx, u.ID, "admin-1")
+	require.NoError(t, err)
+	require.Equal(t, domain.UserStatusInactive, restored.Status)
+	require.Nil(t, restored.DeletedAt)
+
+	require.Len(t, history.entries, 2)
+	require.Equal(t, domain.UserStatusDeleted, history.entries[0].ToStatus)
+	require.Equal(t, domain.UserStatusInactive, history.entries[1].ToStatus)
+	require.Equal(t, []string{events.UserDeleted, events.UserRestored}, eventNames(outbox.events))
+
+	// The user deleted their own account; only the admin restore is audited.
+	require.Len(t, audit.entries, 1)
+	require.Equal(t, domain.AuditUserRestore, audit.entries[0].Action)
+	require.Equal(t, "admin-1", deref(audit.entries[0].ActorID))
+	require.Equal(t, u.ID, deref(audit.entries[0].TargetUserID))
+}
+
+func TestAccountDeletionService_AuditsAdminDelete(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	audit := &auditLogRepo{}
+	svc := service.NewAccountDeletionService(users, newManageProfileRepo(), identityRepoStub{}, &providerRepoStub{}, nil, &countingTx{}, nil, audit, time.Hour)
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: "user-1", Email: "gone@example.com", Status: domain.UserStatusActive}))
+
+	ctx := service.WithAuditActor(context.Background(), domain.AuditActor{ID: "admin-1", IP: "10.0.0.1"})
+	_, err := svc.Delete(ctx, "user-1", "admin-1")
+	require.NoError(t, err)
+
+	require.Len(t, audit.entries, 1)
+	entry := audit.entries[0]
+	require.Equal(t, domain.AuditUserDelete, entry.Action)
+	require.Equal(t, "10.0.0.1", deref(entry.IP))
+	require.Contains(t, string(entry.Changes), `"status"`)
+}
+
+func TestAccountDeletionService_RestoreRejectsReusedEmail(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	svc := service.NewAccountDeletionService(users, newManageProfileRepo(), identityRepoStub{}, &providerRepoStub{}, nil, nil, nil, nil, time.Hour)
+
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: "user-1", Email: "reuse@example.com", Status: domain.UserStatusActive}))
+	_, err := svc.Delete(context.Background(), "user-1", "user-1")
+	require.NoError(t, err)
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: "user-2", Email: "reuse@example.com", Status: domain.UserStatusActive}))
+
+	_, err = svc.Restore(context.Background(), "user-1", "user-1")
+	require.ErrorIs(t, err, domain.ErrEmailInUse)
+}
+
+func TestAccountDeletionService_PurgeExpired(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	providers := &providerRepoStub{}
+	outbox := &recordingOutbox{}
+	svc := service.NewAccountDeletionService(users, newManageProfileRepo(), identityRepoStub{}, providers, nil, nil, outbox, nil, time.Hour)
+
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: "user-1", Email: "purge@example.com", Status: domain.UserStatusActive}))
+	_, err := svc.Delete(context.Background(), "user-1", "user-1")
+	require.NoError(t, err)
+
+	count, err := svc.PurgeExpired(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	count, err = svc.PurgeExpired(context.Background(), time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	purged, err := users.FindByIDIncludingDeleted(context.Background(), "user-1")
+	require.NoError(t, err)
+	require.Equal(t, "deleted-user-1@users.invalid", purged.Email)
+	require.NotNil(t, purged.PurgedAt)
+	require.Equal(t, []string{"user-1"}, providers.deletedFor)
+
+	_, err = svc.Restore(context.Background(), "user-1", "user-1")
+	require.ErrorIs(t, err, domain.ErrRestoreWindowClosed)
+	require.Equal(t, events.UserPurged, outbox.events[len(outbox.events)-1].Event)
+}
+
+type providerRepoStub struct {
+	deletedFor []string
+}
+
+func (p *providerRepoStub) Create(_ context.Context, provider *domain.UserProvider) error { return nil }
+func (p *providerRepoStub) Update(_ context.Context, provider *domain.UserProvider) error { return nil }
+func (p *providerRepoStub) Delete(_ context.Context, id string) error                     { return nil }
+func (p *providerRepoStub) DeleteByUser(_ context.Context, userID string) error {
+	p.deletedFor = append(p.deletedFor, userID)
+	return nil
+}
+func (p *providerRepoStub) FindByProvider(_ context.Context, providerType, providerUserID string) (*domain.UserProvider, error) {
+	return nil, nil
+}
+func (p *providerRepoStub) FindByUserID(_ context.Context, userID string) ([]domain.UserProvider, error) {
+	return nil, nil
+}
+
+func eventNames(list []events.UserEvent) []string {
+	names := make([]string, 0, len(list))
+	for _, e := range list {
+		names = append(names, e.Event)
+	}
+	return names
+}
@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	adminv1 "github.com/example/user-service/internal/adapters/http/admin/v1"
+	"github.com/example/user-service/internal/domain"
+)
+
+func TestAuditHandler_ListAudit(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubAuditService{entries: []domain.AuditEntry{{ID: 7, Action: domain.AuditUserSuspend, Changes: json.RawMessage(`{}`)}}}
+	handler := adminv1.NewAuditHandler(stub)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/v1/audit?actor_id=admin-1&target_user_id=user-1&action=user.suspend&created_from=2024-01-01T00:00:00Z&page=2&per=10", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	require.NoError(t, handler.ListAudit(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "admin-1", stub.query.ActorID)
+	require.Equal(t, "user-1", stub.query.TargetUserID)
+	require.Equal(t, domain.AuditUserSuspend, stub.query.Action)
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), stub.query.From.UTC())
+	require.Nil(t, stub.query.To)
+	require.Equal(t, 10, stub.query.Offset)
+	require.Equal(t, 10, stub.query.Limit)
+
+	var body struct {
+		Data struct {
+			TotalCount int64               `json:"totalCount"`
+			Entries    []domain.AuditEntry `json:"entries"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.EqualValues(t, 1, body.Data.TotalCount)
+	require.Len(t, body.Data.Entries, 1)
+	require.Equal(t, int64(7), body.Data.Entries[0].ID)
+}
+
+func TestAuditHandler_ListAudit_InvalidParams(t *testing.T) {
+	t.Parallel()
+
+	handler := adminv1.NewAuditHandler(&stubAuditService{})
+	e := echo.New()
+
+	for _, query := range []string{"action=user.delete_everything", "created_to=tomorrow", "page=0", "per=5"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/v1/audit?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		serve(c, handler.ListAudit)
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+type stubAuditService struct {
+	query   domain.AuditQuery
+	entries []domain.AuditEntry
+}
+
+func (s *stubAuditService) List(ctx context.Context, query domain.AuditQuery) ([]domain.AuditEntry, int64, error) {
+	s.query = query
+	return s.entries, int64(len(s.entries)), nil
+}
+
+func (s *stubAuditService) Verify(ctx context.Context) (int, error) {
+	return len(s.entries), nil
+}
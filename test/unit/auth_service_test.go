@@ -65,7 +65,7 @@ func (f *fakeUserRepo) FindByID(ctx context.Context, id string) (*domain.User, e
 }
 
 func (f *fakeUserRepo) Delete(ctx context.Context, id string) error { return nil }
-func (f *fakeUserRepo) List(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
+func (f *fakeUserRepo) List(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
 	return nil, 0, nil
 }
 
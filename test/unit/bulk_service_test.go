@@ -0,0 +1,261 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/usecase"
+)
+
+func TestBulkService_DryRunDoesNotApply(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: bulkUserID(1), Status: domain.UserStatusActive}))
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: bulkUserID(2), Status: domain.UserStatusBlocked}))
+	manage := service.NewUserManageService(users, newManageProfileRepo(), nil, &recordingRBAC{}, nil, nil, nil, nil)
+	jobs := newBulkJobRepo()
+	svc := service.NewBulkService(jobs, users, manage, nil, nil)
+
+	job, err := svc.Submit(context.Background(), service.BulkRequest{
+		Action:  domain.BulkActionChangeStatus,
+		UserIDs: []string{bulkUserID(1), bulkUserID(2), bulkUserID(1), bulkUserID(99)},
+		Status:  "inactive",
+		Reason:  "cleanup",
+		DryRun:  true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.BulkJobPartiallyFailed, job.Status)
+	require.Equal(t, 3, job.Total)
+	require.Equal(t, 1, job.Succeeded)
+	require.Equal(t, 2, job.Failed)
+	require.Equal(t, domain.UserStatusActive, users.users[bulkUserID(1)].Status)
+
+	count, err := svc.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestBulkService_ProcessPendingRecordsPerItemResults(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, users.Create(context.Background(), &domain.User{ID: bulkUserID(i), Status: domain.UserStatusActive}))
+	}
+	rbac := &recordingRBAC{}
+	manage := service.NewUserManageService(users, newManageProfileRepo(), nil, rbac, nil, nil, nil, nil)
+	jobs := newBulkJobRepo()
+	svc := service.NewBulkService(jobs, users, manage, nil, &countingTx{})
+
+	job, err := svc.Submit(context.Background(), service.BulkRequest{
+		Action:  domain.BulkActionAssignRole,
+		UserIDs: []string{bulkUserID(1), bulkUserID(99), bulkUserID(3)},
+		Role:    "moderator",
+		ActorID: "admin-1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.BulkJobPending, job.Status)
+
+	count, err := svc.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	done, err := svc.Get(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.BulkJobPartiallyFailed, done.Status)
+	require.Equal(t, 2, done.Succeeded)
+	require.Equal(t, 1, done.Failed)
+	require.NotNil(t, done.CompletedAt)
+	require.Equal(t, domain.BulkItemFailed, done.Items[1].Status)
+	require.NotNil(t, done.Items[1].Error)
+	require.Equal(t, "user not found", *done.Items[1].Error)
+	require.Equal(t, bulkUserID(3), rbac.assignedUserID)
+	require.Equal(t, "moderator", rbac.assignedRole)
+}
+
+func TestBulkService_ItemErrorsHideInternalDetails(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: bulkUserID(1), Status: domain.UserStatusActive}))
+	rbac := &recordingRBAC{err: errors.New("dial tcp 10.0.0.7:8080: connection refused")}
+	manage := service.NewUserManageService(users, newManageProfileRepo(), nil, rbac, nil, nil, nil, nil)
+	svc := service.NewBulkService(newBulkJobRepo(), users, manage, nil, &countingTx{})
+
+	job, err := svc.Submit(context.Background(), service.BulkRequest{
+		Action:  domain.BulkActionAssignRole,
+		UserIDs: []string{bulkUserID(1)},
+		Role:    "moderator",
+		ActorID: "admin-1",
+	})
+	require.NoError(t, err)
+	_, err = svc.ProcessPending(context.Background())
+	require.NoError(t, err)
+
+	done, err := svc.Get(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.BulkItemFailed, done.Items[0].Status)
+	require.NotContains(t, *done.Items[0].Error, "10.0.0.7")
+}
+
+func TestBulkService_SubmitValidation(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	svc := service.NewBulkService(newBulkJobRepo(), users, nil, nil, nil)
+
+	_, err := svc.Submit(context.Background(), service.BulkRequest{Action: domain.BulkActionChangeStatus, UserIDs: []string{"user-1"}, Status: "BLOCKED"})
+	require.ErrorIs(t, err, domain.ErrStatusReasonRequired)
+
+	_, err = svc.Submit(context.Background(), service.BulkRequest{Action: domain.BulkActionAssignRole, UserIDs: []string{"user-1"}})
+	require.Error(t, err)
+
+	_, err = svc.Submit(context.Background(), service.BulkRequest{Action: domain.BulkActionDelete})
+	require.Error(t, err)
+
+	ids := make([]string, service.MaxBulkItems+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("user-%d", i)
+	}
+	_, err = svc.Submit(context.Background(), service.BulkRequest{Action: domain.BulkActionDelete, UserIDs: ids})
+	require.Error(t, err)
+}
+
+func TestBulkService_ReportsMalformedUserIDsPerItem(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: bulkUserID(1), Status: domain.UserStatusActive}))
+	manage := service.NewUserManageService(users, newManageProfileRepo(), nil, &recordingRBAC{}, nil, nil, nil, nil)
+	svc := service.NewBulkService(newBulkJobRepo(), users, manage, nil, &countingTx{})
+
+	job, err := svc.Submit(context.Background(), service.BulkRequest{
+		Action:  domain.BulkActionAssignRole,
+		UserIDs: []string{bulkUserID(1), "not-a-uuid"},
+		Role:    "moderator",
+		DryRun:  true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.BulkJobPartiallyFailed, job.Status)
+	require.Equal(t, domain.BulkItemSucceeded, job.Items[0].Status)
+	require.Equal(t, domain.BulkItemFailed, job.Items[1].Status)
+	require.Equal(t, "invalid user id", *job.Items[1].Error)
+
+	_, err = svc.Submit(context.Background(), service.BulkRequest{
+		Action:  domain.BulkActionAssignRole,
+		UserIDs: []string{bulkUserID(1)},
+		Role:    "admin; drop",
+		DryRun:  true,
+	})
+	require.ErrorIs(t, err, domain.ErrValidation)
+}
+
+func bulkUserID(n int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", n)
+}
+
+type bulkJobRepo struct {
+	jobs   map[string]*domain.BulkJob
+	order  []string
+	lastID int
+}
+
+func newBulkJobRepo() *bulkJobRepo {
+	return &bulkJobRepo{jobs: map[string]*domain.BulkJob{}}
+}
+
+func (r *bulkJobRepo) Create(_ context.Context, job *domain.BulkJob) error {
+	r.lastID++
+	job.ID = fmt.Sprintf("bulk-%d", r.lastID)
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	for idx := range job.Items {
+		job.Items[idx].ID = fmt.Sprintf("%s-item-%d", job.ID, idx)
+		job.Items[idx].JobID = job.ID
+	}
+	r.jobs[job.ID] = cloneBulkJob(job)
+	r.order = append(r.order, job.ID)
+	return nil
+}
+
+func (r *bulkJobRepo) Update(_ context.Context, job *domain.BulkJob) error {
+	stored, ok := r.jobs[job.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	items := stored.Items
+	*stored = *job
+	stored.Items = items
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *bulkJobRepo) UpdateItem(_ context.Context, item *domain.BulkJobItem) error {
+	for idx, existing := range r.jobs[item.JobID].Items {
+		if existing.ID == item.ID {
+			r.jobs[item.JobID].Items[idx] = *item
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *bulkJobRepo) FindByID(_ context.Context, id string) (*domain.BulkJob, error) {
+	if job, ok := r.jobs[id]; ok {
+		return cloneBulkJob(job), nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *bulkJobRepo) LockRunnable(_ context.Context, staleBefore time.Time, limit int) ([]domain.BulkJob, error) {
+	var out []domain.BulkJob
+	for _, id := range r.order {
+		job := r.jobs[id]
+		if job.Status == domain.BulkJobPending || (job.Status == domain.BulkJobRunning && job.UpdatedAt.Before(staleBefore)) {
+			out = append(out, *cloneBulkJob(job))
+		}
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func cloneBulkJob(job *domain.BulkJob) *domain.BulkJob {
+	clone := *job
+	clone.Items = append([]domain.BulkJobItem(nil), job.Items...)
+	return &clone
+}
+
+func TestBulkService_DeleteIsAudited(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: bulkUserID(1), Email: "bulk@example.com", Status: domain.UserStatusActive}))
+	audit := &auditLogRepo{}
+	deletion := service.NewAccountDeletionService(users, newManageProfileRepo(), identityRepoStub{}, &providerRepoStub{}, nil, &countingTx{}, nil, audit, time.Hour)
+	manage := service.NewUserManageService(users, newManageProfileRepo(), nil, &recordingRBAC{}, nil, nil, nil, nil)
+	svc := service.NewBulkService(newBulkJobRepo(), users, manage, deletion, &countingTx{})
+
+	job, err := svc.Submit(context.Background(), service.BulkRequest{
+		Action:  domain.BulkActionDelete,
+		UserIDs: []string{bulkUserID(1)},
+		ActorID: "admin-1",
+	})
+	require.NoError(t, err)
+	_, err = svc.ProcessPending(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, audit.entries, 1)
+	require.Equal(t, domain.AuditUserDelete, audit.entries[0].Action)
+	require.Equal(t, "admin-1", deref(audit.entries[0].ActorID))
+	require.Equal(t, "bulk:"+job.ID, deref(audit.entries[0].RequestID))
+}
@@ -0,0 +1,156 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/usecase"
+)
+
+func TestDataExportService_JSONExport(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: "user-1", Email: "me@example.com", Status: domain.UserStatusActive}))
+	jobs := newExportJobRepo()
+	fs := &stubFilestorage{}
+	svc := service.NewDataExportService(jobs, users, identityRepoStub{}, &providerRepoStub{}, &statusHistoryRepo{}, fs, &countingTx{}, service.DataExportConfig{})
+
+	job, err := svc.RequestExport(context.Background(), "user-1", "")
+	require.NoError(t, err)
+	require.Equal(t, domain.ExportStatusPending, job.Status)
+	require.Equal(t, domain.ExportFormatJSON, job.Format)
+
+	again, err := svc.RequestExport(context.Background(), "user-1", domain.ExportFormatJSON)
+	require.NoError(t, err)
+	require.Equal(t, job.ID, again.ID)
+
+	result, err := svc.GetExport(context.Background(), "user-1", job.ID)
+	require.NoError(t, err)
+	require.Empty(t, result.DownloadURL)
+
+	count, err := svc.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	result, err = svc.GetExport(context.Background(), "user-1", job.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.ExportStatusCompleted, result.Job.Status)
+	require.Equal(t, "http://filestorage/files/file-123/signed", result.DownloadURL)
+	require.Equal(t, "USER_EXPORT", fs.uploadReq.FileKind)
+	require.Equal(t, "application/json", fs.uploadReq.ContentType)
+
+	var export domain.UserDataExport
+	require.NoError(t, json.Unmarshal(fs.uploadReq.Data, &export))
+	require.Equal(t, "me@example.com", export.User.Email)
+	require.NotNil(t, export.Identities)
+
+	_, err = svc.GetExport(context.Background(), "user-2", job.ID)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestDataExportService_ZIPExportAndFailure(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: "user-1", Email: "me@example.com"}))
+	jobs := newExportJobRepo()
+	fs := &stubFilestorage{}
+	svc := service.NewDataExportService(jobs, users, identityRepoStub{}, &providerRepoStub{}, nil, fs, nil, service.DataExportConfig{})
+
+	zipJob, err := svc.RequestExport(context.Background(), "user-1", domain.ExportFormatZIP)
+	require.NoError(t, err)
+	missing, err := svc.RequestExport(context.Background(), "ghost", domain.ExportFormatJSON)
+	require.NoError(t, err)
+
+	_, err = svc.RequestExport(context.Background(), "user-1", "xml")
+	require.ErrorIs(t, err, domain.ErrInvalidExportFormat)
+
+	count, err := svc.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	require.Equal(t, domain.ExportStatusCompleted, jobs.jobs[zipJob.ID].Status)
+	archive, err := zip.NewReader(bytes.NewReader(fs.uploadReq.Data), int64(len(fs.uploadReq.Data)))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 1)
+	f, err := archive.File[0].Open()
+	require.NoError(t, err)
+	document, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Contains(t, string(document), "me@example.com")
+
+	require.Equal(t, domain.ExportStatusFailed, jobs.jobs[missing.ID].Status)
+	require.NotNil(t, jobs.jobs[missing.ID].Error)
+}
+
+type exportJobRepo struct {
+	jobs   map[string]*domain.ExportJob
+	order  []string
+	lastID int
+}
+
+func newExportJobRepo() *exportJobRepo {
+	return &exportJobRepo{jobs: map[string]*domain.ExportJob{}}
+}
+
+func (r *exportJobRepo) Create(_ context.Context, job *domain.ExportJob) error {
+	r.lastID++
+	job.ID = fmt.Sprintf("job-%d", r.lastID)
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	stored := *job
+	r.jobs[job.ID] = &stored
+	r.order = append(r.order, job.ID)
+	return nil
+}
+
+func (r *exportJobRepo) Update(_ context.Context, job *domain.ExportJob) error {
+	job.UpdatedAt = time.Now()
+	stored := *job
+	r.jobs[job.ID] = &stored
+	return nil
+}
+
+func (r *exportJobRepo) FindByID(_ context.Context, id string) (*domain.ExportJob, error) {
+	if job, ok := r.jobs[id]; ok {
+		found := *job
+		return &found, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *exportJobRepo) FindActiveByUser(_ context.Context, userID string) (*domain.ExportJob, error) {
+	for _, id := range r.order {
+		job := r.jobs[id]
+		if job.UserID == userID && !job.IsFinished() {
+			found := *job
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *exportJobRepo) LockRunnable(_ context.Context, staleBefore time.Time, limit int) ([]domain.ExportJob, error) {
+	var out []domain.ExportJob
+	for _, id := range r.order {
+		job := r.jobs[id]
+		if job.Status == domain.ExportStatusPending || (job.Status == domain.ExportStatusRunning && job.UpdatedAt.Before(staleBefore)) {
+			out = append(out, *job)
+		}
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,243 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
+	"github.com/example/user-service/internal/usecase"
+)
+
+func TestUserManageService_CreateUser_RBACFailureQueuesRole(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	rbac := &recordingRBAC{err: errors.New("rbac down")}
+	roles := newMemoryRoleAssignments()
+	tx := &countingTx{}
+	svc := service.NewUserManageService(users, newManageProfileRepo(), nil, rbac, tx, &recordingOutbox{}, nil, roles)
+
+	user, err := svc.CreateUser(context.Background(), service.CreateUserRequest{
+		Email:    "queued@example.com",
+		Password: "Password1",
+		Role:     "student",
+	})
+	require.NoError(t, err)
+	require.NoError(t, tx.lastErr)
+	require.Contains(t, users.users, user.ID)
+
+	pending := roles.items[0]
+	require.Equal(t, user.ID, pending.UserID)
+	require.Equal(t, "student", pending.Role)
+	require.Nil(t, pending.AssignedAt)
+	require.Equal(t, 1, pending.Attempts)
+	require.Equal(t, "rbac down", *pending.LastError)
+
+	worker := service.NewRoleAssignmentService(roles, rbac, users, nil, service.RoleAssignmentConfig{MaxAttempts: 5})
+	count, err := worker.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, 2, pending.Attempts)
+	require.Nil(t, pending.AssignedAt)
+
+	rbac.err = nil
+	count, err = worker.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.NotNil(t, pending.AssignedAt)
+	require.Equal(t, user.ID, rbac.assignedUserID)
+	require.Equal(t, "student", rbac.assignedRole)
+
+	count, err = worker.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestUserManageService_ChangeRole_ReplacesPendingRole(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	u := &domain.User{ID: "u1", Email: "role@example.com"}
+	users.users[u.ID] = u
+	rbac := &recordingRBAC{err: errors.New("rbac down")}
+	roles := newMemoryRoleAssignments()
+	svc := service.NewUserManageService(users, newManageProfileRepo(), nil, rbac, nil, nil, nil, roles)
+
+	require.NoError(t, svc.ChangeRole(context.Background(), u.ID, "teacher"))
+	require.NoError(t, svc.ChangeRole(context.Background(), u.ID, "admin"))
+
+	require.Len(t, roles.items, 1)
+	require.Equal(t, "admin", roles.items[0].Role)
+}
+
+func TestUserManageService_ChangeRole_RecordsEventOnceAssigned(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	users.users["u1"] = &domain.User{ID: "u1", Email: "role@example.com"}
+	rbac := &recordingRBAC{err: errors.New("rbac down")}
+	roles := newMemoryRoleAssignments()
+	outbox := &recordingOutbox{}
+	svc := service.NewUserManageService(users, newManageProfileRepo(), nil, rbac, &countingTx{}, outbox, nil, roles)
+
+	require.NoError(t, svc.ChangeRole(context.Background(), "u1", "teacher"))
+	require.Empty(t, outbox.events, "caches must not reload the role before RBAC has it")
+
+	rbac.err = nil
+	worker := service.NewRoleAssignmentService(roles, rbac, users, outbox, service.RoleAssignmentConfig{MaxAttempts: 5})
+	_, err := worker.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Len(t, outbox.events, 1)
+	require.Equal(t, events.UserRoleChanged, outbox.events[0].Event)
+	require.Equal(t, "role@example.com", outbox.events[0].Email)
+}
+
+func TestRoleAssignmentService_ResendsRoleOvertakenDuringCall(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	users.users["u1"] = &domain.User{ID: "u1", Email: "role@example.com"}
+	rbac := &recordingRBAC{}
+	roles := newMemoryRoleAssignments()
+	_, err := roles.Enqueue(context.Background(), "u1", "teacher", time.Now())
+	require.NoError(t, err)
+
+	// The newer role is queued and assigned while the older one is sent.
+	rbac.onAssign = func(userID, role string) {
+		rbac.onAssign = nil
+		newer, err := roles.Enqueue(context.Background(), userID, "admin", time.Now())
+		require.NoError(t, err)
+		now := time.Now()
+		newer.AssignedAt = &now
+	}
+	worker := service.NewRoleAssignmentService(roles, rbac, users, nil, service.RoleAssignmentConfig{MaxAttempts: 5})
+	_, err = worker.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "teacher", rbac.assignedRole)
+
+	count, err := worker.ProcessPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, "admin", rbac.assignedRole)
+}
+
+func TestRoleAssignmentService_CountsDeadAssignments(t *testing.T) {
+	t.Parallel()
+
+	rbac := &recordingRBAC{err: errors.New("rbac down")}
+	roles := newMemoryRoleAssignments()
+	_, err := roles.Enqueue(context.Background(), "u1", "teacher", time.Now())
+	require.NoError(t, err)
+	worker := service.NewRoleAssignmentService(roles, rbac, nil, nil, service.RoleAssignmentConfig{MaxAttempts: 2})
+
+	for i := 0; i < 3; i++ {
+		_, err := worker.ProcessPending(context.Background())
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, roles.items[0].Attempts)
+	dead, err := worker.CountDead(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, dead)
+}
+
+// memoryRoleAssignments ignores due times and leases so tests can retry
+// right away. Later items are newer.
+type memoryRoleAssignments struct {
+	items  []*domain.RoleAssignment
+	nextID int
+}
+
+func newMemoryRoleAssignments() *memoryRoleAssignments {
+	return &memoryRoleAssignments{}
+}
+
+func (m *memoryRoleAssignments) Enqueue(ctx context.Context, userID, role string, notBefore time.Time) (*domain.RoleAssignment, error) {
+	kept := m.items[:0]
+	for _, a := range m.items {
+		if a.UserID != userID || a.AssignedAt != nil {
+			kept = append(kept, a)
+		}
+	}
+	m.nextID++
+	a := &domain.RoleAssignment{ID: "ra" + strconv.Itoa(m.nextID), UserID: userID, Role: role, NextAttemptAt: notBefore}
+	m.items = append(kept, a)
+	return a, nil
+}
+
+func (m *memoryRoleAssignments) Claim(ctx context.Context, limit, maxAttempts int, leaseUntil time.Time) ([]domain.RoleAssignment, error) {
+	var out []domain.RoleAssignment
+	for _, a := range m.items {
+		if a.AssignedAt == nil && m.latest(a) && (maxAttempts <= 0 || a.Attempts < maxAttempts) && len(out) < limit {
+			out = append(out, *a)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryRoleAssignments) ClaimOne(ctx context.Context, id string, leaseUntil time.Time) (bool, error) {
+	a := m.find(id)
+	return a != nil && a.AssignedAt == nil && m.latest(a), nil
+}
+
+func (m *memoryRoleAssignments) MarkAssigned(ctx context.Context, id string) (bool, error) {
+	a := m.find(id)
+	if a == nil || a.AssignedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	a.AssignedAt = &now
+	return true, nil
+}
+
+func (m *memoryRoleAssignments) MarkFailed(ctx context.Context, id string, cause error, nextAttemptAt time.Time) error {
+	a := m.find(id)
+	msg := cause.Error()
+	a.Attempts++
+	a.LastError = &msg
+	a.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (m *memoryRoleAssignments) RequeueLatest(ctx context.Context, userID string) error {
+	for i := len(m.items) - 1; i >= 0; i-- {
+		if a := m.items[i]; a.UserID == userID {
+			a.AssignedAt = nil
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryRoleAssignments) CountDead(ctx context.Context, maxAttempts int) (int64, error) {
+	var count int64
+	for _, a := range m.items {
+		if a.AssignedAt == nil && a.Attempts >= maxAttempts {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryRoleAssignments) latest(a *domain.RoleAssignment) bool {
+	for i := len(m.items) - 1; i >= 0; i-- {
+		if m.items[i].UserID == a.UserID {
+			return m.items[i] == a
+		}
+	}
+	return false
+}
+
+func (m *memoryRoleAssignments) find(id string) *domain.RoleAssignment {
+	for _, a := range m.items {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/usecase"
+)
+
+func TestServiceClientService_Issue(t *testing.T) {
+	t.Parallel()
+
+	clients := &serviceClientRepo{}
+	svc := service.NewServiceClientService(clients)
+	past := time.Now().Add(-time.Hour)
+
+	for name, req := range map[string]service.IssueServiceClientRequest{
+		"missing name":    {Scopes: []string{domain.ScopeUsersRead}},
+		"missing scopes":  {Name: "billing"},
+		"unknown scope":   {Name: "billing", Scopes: []string{"users:delete"}},
+		"already expired": {Name: "billing", Scopes: []string{domain.ScopeUsersRead}, ExpiresAt: &past},
+	} {
+		_, _, err := svc.Issue(context.Background(), req)
+		require.Error(t, err, name)
+	}
+
+	client, key, err := svc.Issue(context.Background(), service.IssueServiceClientRequest{
+		Name:    " billing ",
+		Scopes:  []string{"USERS:READ", domain.ScopeUsersRead},
+		ActorID: "admin-1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "billing", client.Name)
+	require.Equal(t, domain.StringList{domain.ScopeUsersRead}, client.Scopes)
+	require.True(t, strings.HasPrefix(key, "usk_"+client.KeyPrefix+"_"))
+	require.NotContains(t, client.KeyHash, key)
+	require.Equal(t, "admin-1", deref(client.CreatedBy))
+}
+
+func TestServiceClientService_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	clients := &serviceClientRepo{}
+	svc := service.NewServiceClientService(clients)
+	expires := time.Now().Add(time.Hour)
+	client, key, err := svc.Issue(context.Background(), service.IssueServiceClientRequest{Name: "crm", Scopes: []string{domain.ScopeUsersWrite}, ExpiresAt: &expires})
+	require.NoError(t, err)
+
+	authenticated, err := svc.Authenticate(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, client.ID, authenticated.ID)
+	require.True(t, authenticated.HasScope(domain.ScopeUsersWrite))
+	require.Equal(t, 1, clients.touches)
+
+	// Last use is only recorded once per minute.
+	_, err = svc.Authenticate(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, 1, clients.touches)
+
+	for _, bad := range []string{"", "usk_", "usk_" + client.KeyPrefix + "_wrong", "other_" + key} {
+		_, err := svc.Authenticate(context.Background(), bad)
+		require.ErrorIs(t, err, domain.ErrInvalidAPIKey, bad)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	clients.items[0].ExpiresAt = &expired
+	_, err = svc.Authenticate(context.Background(), key)
+	require.ErrorIs(t, err, domain.ErrInvalidAPIKey)
+}
+
+func TestServiceClientService_RevokedCannotRotate(t *testing.T) {
+	t.Parallel()
+
+	svc := service.NewServiceClientService(&serviceClientRepo{})
+	client, _, err := svc.Issue(context.Background(), service.IssueServiceClientRequest{Name: "crm", Scopes: []string{domain.ScopeUsersRead}})
+	require.NoError(t, err)
+
+	revoked, err := svc.Revoke(context.Background(), client.ID)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+	_, _, err = svc.Rotate(context.Background(), client.ID)
+	require.ErrorIs(t, err, domain.ErrServiceClientRevoked)
+	_, err = svc.Revoke(context.Background(), "missing")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+type serviceClientRepo struct {
+	items   []domain.ServiceClient
+	touches int
+}
+
+func (r *serviceClientRepo) Create(_ context.Context, client *domain.ServiceClient) error {
+	client.ID = "client-" + client.KeyPrefix
+	r.items = append(r.items, *client)
+	return nil
+}
+
+func (r *serviceClientRepo) Update(_ context.Context, client *domain.ServiceClient) error {
+	for idx := range r.items {
+		if r.items[idx].ID == client.ID {
+			r.items[idx] = *client
+		}
+	}
+	return nil
+}
+
+func (r *serviceClientRepo) FindByID(_ context.Context, id string) (*domain.ServiceClient, error) {
+	for _, client := range r.items {
+		if client.ID == id {
+			return &client, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *serviceClientRepo) FindByKeyPrefix(_ context.Context, prefix string) (*domain.ServiceClient, error) {
+	for _, client := range r.items {
+		if client.KeyPrefix == prefix {
+			return &client, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *serviceClientRepo) List(_ context.Context) ([]domain.ServiceClient, error) {
+	return r.items, nil
+}
+
+func (r *serviceClientRepo) TouchLastUsed(_ context.Context, id string, at time.Time) error {
+	r.touches++
+	for idx := range r.items {
+		if r.items[idx].ID == id {
+			r.items[idx].LastUsedAt = &at
+		}
+	}
+	return nil
+}
@@ -28,7 +28,7 @@ func TestUploadAvatar_Success(t *testing.T) {
 			return &domain.UserProfile{UserID: userID, AvatarFileID: &avatarFileID}, nil
 		},
 	}
-	handler := v1.NewHandler(us, fs, nil, "avatar", "USER_MEDIA")
+	handler := v1.NewHandler(us, nil, nil, fs, nil, "avatar", "USER_MEDIA")
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -63,7 +63,7 @@ func TestUploadAvatar_TooLarge(t *testing.T) {
 
 	fs := &stubFilestorage{}
 	us := &stubUserService{}
-	handler := v1.NewHandler(us, fs, nil, "avatar", "USER_MEDIA")
+	handler := v1.NewHandler(us, nil, nil, fs, nil, "avatar", "USER_MEDIA")
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -79,7 +79,7 @@ func TestUploadAvatar_TooLarge(t *testing.T) {
 	c := e.NewContext(req, rec)
 	c.Set("user_id", "user-1")
 
-	require.NoError(t, handler.UploadAvatar(c))
+	serve(c, handler.UploadAvatar)
 	require.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
@@ -89,7 +89,7 @@ func TestUploadAvatar_EagerTriggersImageProcessor(t *testing.T) {
 	fs := &stubFilestorage{}
 	proc := &stubImageProc{}
 	us := &stubUserService{}
-	handler := v1.NewHandler(us, fs, proc, "avatar", "USER_MEDIA")
+	handler := v1.NewHandler(us, nil, nil, fs, proc, "avatar", "USER_MEDIA")
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -147,7 +147,7 @@ func (s *stubImageProc) Generate(ctx context.Context, originalID, ownerID, fileK
 }
 
 type stubUserService struct {
-	updateProfileFn   func(ctx context.Context, userID string, displayName *string) (*domain.UserProfile, error)
+	updateProfileFn   func(ctx context.Context, userID string, displayName *string, ifMatch string) (*domain.User, error)
 	setAvatarFileIDFn func(ctx context.Context, userID, avatarFileID string) (*domain.UserProfile, error)
 }
 
@@ -157,11 +157,11 @@ func (s *stubUserService) GetMe(ctx context.Context, userID string) (*domain.Use
 func (s *stubUserService) GetByID(ctx context.Context, requesterID, targetID string) (*domain.User, error) {
 	return nil, nil
 }
-func (s *stubUserService) UpdateProfile(ctx context.Context, userID string, displayName *string) (*domain.UserProfile, error) {
+func (s *stubUserService) UpdateProfile(ctx context.Context, userID string, displayName *string, ifMatch string) (*domain.User, error) {
 	if s.updateProfileFn != nil {
-		return s.updateProfileFn(ctx, userID, displayName)
+		return s.updateProfileFn(ctx, userID, displayName, ifMatch)
 	}
-	return &domain.UserProfile{UserID: userID}, nil
+	return &domain.User{ID: userID, Profile: &domain.UserProfile{UserID: userID}}, nil
 }
 func (s *stubUserService) SetAvatarFileID(ctx context.Context, userID, avatarFileID string) (*domain.UserProfile, error) {
 	if s.setAvatarFileIDFn != nil {
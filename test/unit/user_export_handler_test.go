@@ -0,0 +1,91 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/example/user-service/internal/adapters/http/api/v1"
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/usecase"
+)
+
+func TestRequestExport_Accepted(t *testing.T) {
+	t.Parallel()
+
+	exports := &stubExportService{
+		requestFn: func(ctx context.Context, userID string, format domain.ExportFormat) (*domain.ExportJob, error) {
+			require.Equal(t, "user-1", userID)
+			require.Equal(t, domain.ExportFormatZIP, format)
+			return &domain.ExportJob{ID: "job-1", UserID: userID, Status: domain.ExportStatusPending, Format: format}, nil
+		},
+	}
+	handler := v1.NewHandler(&stubUserService{}, nil, exports, nil, nil, "avatar", "USER_MEDIA")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/users/me/export", strings.NewReader(`{"format":"ZIP"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "user-1")
+
+	require.NoError(t, handler.RequestExport(c))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Contains(t, rec.Body.String(), `"status":"PENDING"`)
+}
+
+func TestGetExport_ReturnsDownloadURL(t *testing.T) {
+	t.Parallel()
+
+	exports := &stubExportService{
+		getFn: func(ctx context.Context, userID, jobID string) (*service.ExportResult, error) {
+			if jobID != "job-1" {
+				return nil, gorm.ErrRecordNotFound
+			}
+			return &service.ExportResult{
+				Job:         &domain.ExportJob{ID: jobID, Status: domain.ExportStatusCompleted, Format: domain.ExportFormatJSON},
+				DownloadURL: "http://filestorage/files/file-1/signed",
+			}, nil
+		},
+	}
+	handler := v1.NewHandler(&stubUserService{}, nil, exports, nil, nil, "avatar", "USER_MEDIA")
+	e := echo.New()
+
+	for jobID, code := range map[string]int{"job-1": http.StatusOK, "job-2": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodGet, "/users/me/export/"+jobID, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("job_id")
+		c.SetParamValues(jobID)
+		c.Set("user_id", "user-1")
+
+		serve(c, handler.GetExport)
+		require.Equal(t, code, rec.Code)
+		if code == http.StatusOK {
+			require.Contains(t, rec.Body.String(), "file-1/signed")
+		}
+	}
+}
+
+type stubExportService struct {
+	requestFn func(ctx context.Context, userID string, format domain.ExportFormat) (*domain.ExportJob, error)
+	getFn     func(ctx context.Context, userID, jobID string) (*service.ExportResult, error)
+}
+
+func (s *stubExportService) RequestExport(ctx context.Context, userID string, format domain.ExportFormat) (*domain.ExportJob, error) {
+	return s.requestFn(ctx, userID, format)
+}
+
+func (s *stubExportService) GetExport(ctx context.Context, userID, jobID string) (*service.ExportResult, error) {
+	return s.getFn(ctx, userID, jobID)
+}
+
+func (s *stubExportService) ProcessPending(ctx context.Context) (int, error) {
+	return 0, nil
+}
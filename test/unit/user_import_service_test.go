@@ -0,0 +1,206 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/usecase"
+)
+
+const importCSV = `email,display_name,role,status
+existing@example.com,Existing,teacher,
+Existing@example.com,Twice,teacher,
+new@example.com,New Student,student,inactive
+broken"@example.com,x
+bad-email,,student,
+ghost@example.com,,,
+`
+
+func TestUserImportService_CSVUpsert(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	profiles := newManageProfileRepo()
+	rbac := &roleRBAC{roles: map[string]string{"user-1": "TEACHER"}}
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: "user-1", Email: "existing@example.com", Status: domain.UserStatusActive, IsActive: true}))
+	require.NoError(t, profiles.Create(context.Background(), &domain.UserProfile{UserID: "user-1", DisplayName: stringPtr("Old")}))
+	svc := service.NewUserImportService(users, profiles, &statusHistoryRepo{}, rbac, &countingTx{}, nil, nil, nil)
+
+	results := runImport(t, svc, importCSV, domain.ImportFormatCSV, true)
+	require.Equal(t, []domain.ImportOutcome{domain.ImportUpdated, domain.ImportFailed, domain.ImportCreated, domain.ImportFailed, domain.ImportFailed, domain.ImportFailed}, outcomes(results))
+	require.Contains(t, results[1].Error, "first seen on line 2")
+	require.Equal(t, 5, results[3].Line)
+	require.Equal(t, "role is required", results[5].Error)
+	require.Equal(t, "Old", deref(profiles.profiles["user-1"].DisplayName))
+	require.Len(t, users.users, 1)
+
+	results = runImport(t, svc, importCSV, domain.ImportFormatCSV, false)
+	require.Equal(t, []domain.ImportOutcome{domain.ImportUpdated, domain.ImportFailed, domain.ImportCreated, domain.ImportFailed, domain.ImportFailed, domain.ImportFailed}, outcomes(results))
+	require.Equal(t, "Existing", deref(profiles.profiles["user-1"].DisplayName))
+	created, err := users.FindByEmail(context.Background(), "new@example.com")
+	require.NoError(t, err)
+	require.Equal(t, created.ID, results[2].UserID)
+	require.Equal(t, domain.UserStatusInactive, created.Status)
+	require.Equal(t, "student", rbac.roles[created.ID])
+
+	results = runImport(t, svc, importCSV, domain.ImportFormatCSV, false)
+	require.Equal(t, []domain.ImportOutcome{domain.ImportUnchanged, domain.ImportFailed, domain.ImportUnchanged, domain.ImportFailed, domain.ImportFailed, domain.ImportFailed}, outcomes(results))
+}
+
+func TestUserImportService_NDJSONRowErrors(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	svc := service.NewUserImportService(users, newManageProfileRepo(), nil, &roleRBAC{roles: map[string]string{}}, nil, nil, nil, nil)
+
+	input := `{"email":"a@example.com","role":"student"}
+
+{"email":"b@example.com","role":"student","status":"SUSPENDED"}
+{"email":"c@example.com","role":"student","password":"secret"}
+not json
+{"email":"d@example.com","role":"admin","status":"blocked"}
+`
+	results := runImport(t, svc, input, domain.ImportFormatNDJSON, false)
+	require.Equal(t, []domain.ImportOutcome{domain.ImportCreated, domain.ImportFailed, domain.ImportFailed, domain.ImportFailed, domain.ImportCreated}, outcomes(results))
+	require.Equal(t, []int{1, 3, 4, 5, 6}, []int{results[0].Line, results[1].Line, results[2].Line, results[3].Line, results[4].Line})
+	require.Equal(t, "invalid status", results[1].Error)
+	require.Len(t, users.users, 2)
+}
+
+func TestNewImportReader_RejectsBadHeader(t *testing.T) {
+	t.Parallel()
+
+	_, err := service.NewImportReader(strings.NewReader("mail,role\n"), domain.ImportFormatCSV)
+	require.Error(t, err)
+	_, err = service.NewImportReader(strings.NewReader("display_name,role\n"), domain.ImportFormatCSV)
+	require.Error(t, err)
+	_, err = service.NewImportReader(strings.NewReader(""), "xlsx")
+	require.ErrorIs(t, err, domain.ErrInvalidImportFormat)
+}
+
+func TestUserImportService_UpdatesUserCreatedConcurrently(t *testing.T) {
+	t.Parallel()
+
+	users := &racingUserRepo{manageUserRepo: newManageUserRepo()}
+	rbac := &roleRBAC{roles: map[string]string{"rival": "student"}}
+	svc := service.NewUserImportService(users, newManageProfileRepo(), &statusHistoryRepo{}, rbac, &countingTx{}, nil, nil, nil)
+
+	results := runImport(t, svc, "email,display_name,role,status\nrace@example.com,,teacher,\n", domain.ImportFormatCSV, false)
+	require.Equal(t, []domain.ImportOutcome{domain.ImportUpdated}, outcomes(results))
+	require.Equal(t, "rival", results[0].UserID)
+	require.Equal(t, "teacher", rbac.roles["rival"])
+	require.Len(t, users.users, 1)
+}
+
+func TestUserImportService_LooksUpRolesOutsideTransactions(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	require.NoError(t, users.Create(context.Background(), &domain.User{ID: "user-1", Email: "existing@example.com", Status: domain.UserStatusActive, IsActive: true}))
+	profiles := newManageProfileRepo()
+	require.NoError(t, profiles.Create(context.Background(), &domain.UserProfile{UserID: "user-1"}))
+	tx := &activeTx{}
+	rbac := &txRoleRBAC{roleRBAC: roleRBAC{roles: map[string]string{"user-1": "student"}}, tx: tx}
+	audit := &auditLogRepo{}
+	svc := service.NewUserImportService(users, profiles, &statusHistoryRepo{}, rbac, tx, nil, audit, nil)
+
+	results := runImport(t, svc, "email,display_name,role,status\nexisting@example.com,Renamed,teacher,\n", domain.ImportFormatCSV, false)
+	require.Equal(t, []domain.ImportOutcome{domain.ImportUpdated}, outcomes(results))
+	require.Equal(t, "teacher", rbac.roles["user-1"])
+	require.Zero(t, rbac.lookupsInTx)
+
+	var roleChange *domain.AuditEntry
+	for idx := range audit.entries {
+		if audit.entries[idx].Action == domain.AuditUserRoleChange {
+			roleChange = &audit.entries[idx]
+		}
+	}
+	require.NotNil(t, roleChange)
+	require.Contains(t, string(roleChange.Changes), `"before":"student"`)
+}
+
+// activeTx reports whether a transaction is running.
+type activeTx struct {
+	active bool
+}
+
+func (t *activeTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t.active {
+		return fn(ctx)
+	}
+	t.active = true
+	defer func() { t.active = false }()
+	return fn(ctx)
+}
+
+// txRoleRBAC counts role lookups made while a transaction is running.
+type txRoleRBAC struct {
+	roleRBAC
+	tx          *activeTx
+	lookupsInTx int
+}
+
+func (r *txRoleRBAC) GetRoleByUserID(ctx context.Context, userID string) (string, error) {
+	if r.tx.active {
+		r.lookupsInTx++
+	}
+	return r.roleRBAC.GetRoleByUserID(ctx, userID)
+}
+
+// racingUserRepo lets another request create the same email right before
+// the import inserts it, the way the unique email index reports it.
+type racingUserRepo struct {
+	*manageUserRepo
+}
+
+func (r *racingUserRepo) Create(ctx context.Context, user *domain.User) error {
+	if _, err := r.FindByEmail(ctx, user.Email); err != nil {
+		rival := &domain.User{ID: "rival", Email: user.Email, Status: domain.UserStatusActive, IsActive: true}
+		if err := r.manageUserRepo.Create(ctx, rival); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: duplicate key", domain.ErrEmailInUse)
+	}
+	return r.manageUserRepo.Create(ctx, user)
+}
+
+func runImport(t *testing.T, svc service.UserImportService, input string, format domain.ImportFormat, dryRun bool) []domain.ImportResult {
+	t.Helper()
+	rows, err := service.NewImportReader(strings.NewReader(input), format)
+	require.NoError(t, err)
+	var results []domain.ImportResult
+	err = svc.Import(context.Background(), rows, service.ImportOptions{DryRun: dryRun, ActorID: "admin-1"}, func(result domain.ImportResult) error {
+		results = append(results, result)
+		return nil
+	})
+	require.NoError(t, err)
+	return results
+}
+
+func outcomes(results []domain.ImportResult) []domain.ImportOutcome {
+	out := make([]domain.ImportOutcome, 0, len(results))
+	for _, r := range results {
+		out = append(out, r.Outcome)
+	}
+	return out
+}
+
+// roleRBAC remembers assigned roles so imports can compare against them.
+type roleRBAC struct {
+	recordingRBAC
+	roles map[string]string
+}
+
+func (r *roleRBAC) GetRoleByUserID(_ context.Context, userID string) (string, error) {
+	return r.roles[userID], nil
+}
+
+func (r *roleRBAC) AssignRole(_ context.Context, userID, role string) error {
+	r.roles[userID] = role
+	return nil
+}
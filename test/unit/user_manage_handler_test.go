@@ -1,23 +1,37 @@
 package unit
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 
 	adminv1 "github.com/example/user-service/internal/adapters/http/admin/v1"
+	"github.com/example/user-service/internal/adapters/http/middleware"
 	"github.com/example/user-service/internal/domain"
 	"github.com/example/user-service/internal/usecase"
+	"github.com/example/user-service/pkg/cursor"
 )
 
+// serve runs handler on c and renders a returned error the way the router's
+// error handler does.
+func serve(c echo.Context, handler echo.HandlerFunc) {
+	if err := handler(c); err != nil {
+		middleware.ErrorHandler(err, c)
+	}
+}
+
 func TestUserManageHandler_CreateUser(t *testing.T) {
 	t.Parallel()
 
@@ -34,7 +48,7 @@ func TestUserManageHandler_CreateUser(t *testing.T) {
 			}, nil
 		},
 	}
-	handler := adminv1.NewHandler(mockSvc, nil)
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
 
 	e := echo.New()
 	body := `{"email":"Admin@example.com","password":"Password1","role":"admin","status":"blocked"}`
@@ -60,13 +74,13 @@ func TestUserManageHandler_ListUsers_Default(t *testing.T) {
 	expected := []domain.User{{ID: "user-1"}, {ID: "user-2"}}
 	var gotOffset, gotLimit int
 	mockSvc := &mockManageService{
-		listUsersFn: func(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
-			gotOffset = offset
-			gotLimit = limit
+		listUsersFn: func(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
+			gotOffset = query.Offset
+			gotLimit = query.Limit
 			return expected, 120, nil
 		},
 	}
-	handler := adminv1.NewHandler(mockSvc, nil)
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
 	rec := httptest.NewRecorder()
@@ -94,22 +108,266 @@ func TestUserManageHandler_ListUsers_InvalidPer(t *testing.T) {
 
 	called := false
 	mockSvc := &mockManageService{
-		listUsersFn: func(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
+		listUsersFn: func(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
 			called = true
 			return nil, 0, nil
 		},
 	}
-	handler := adminv1.NewHandler(mockSvc, nil)
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/admin/users?per=5", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	require.NoError(t, handler.ListUsers(c))
+	serve(c, handler.ListUsers)
 	require.Equal(t, http.StatusBadRequest, rec.Code)
 	require.False(t, called)
 }
 
+func TestUserManageHandler_ListUsers_FiltersAndSort(t *testing.T) {
+	t.Parallel()
+
+	var got domain.UserQuery
+	mockSvc := &mockManageService{
+		listUsersFn: func(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
+			got = query
+			return nil, 0, nil
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/users?status=active,blocked&is_active=false&created_from=2024-01-01T00:00:00Z&provider=GitHub&q=%20smith%20&sort=email&order=desc", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	require.NoError(t, handler.ListUsers(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, []domain.UserStatus{domain.UserStatusActive, domain.UserStatusBlocked}, got.Statuses)
+	require.NotNil(t, got.IsActive)
+	require.False(t, *got.IsActive)
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), got.CreatedFrom.UTC())
+	require.Nil(t, got.CreatedTo)
+	require.Equal(t, domain.ProviderGitHub, got.Provider)
+	require.Equal(t, "smith", got.Search)
+	require.Equal(t, domain.UserSortEmail, got.SortBy)
+	require.True(t, got.SortDesc)
+}
+
+func TestUserManageHandler_ListUsers_InvalidParams(t *testing.T) {
+	t.Parallel()
+
+	mockSvc := &mockManageService{
+		listUsersFn: func(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
+			t.Fatal("service must not be called")
+			return nil, 0, nil
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+
+	for _, query := range []string{
+		"sort=password_hash",
+		"order=sideways",
+		"status=GONE",
+		"is_active=maybe",
+		"created_from=yesterday",
+		"provider=myspace",
+		"created_from=2024-02-01T00:00:00Z&created_to=2024-01-01T00:00:00Z",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		serve(c, handler.ListUsers)
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestUserManageHandler_ListUsers_CursorMode(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var got domain.UserQuery
+	mockSvc := &mockManageService{
+		listByCursorFn: func(ctx context.Context, query domain.UserQuery) (*domain.UserPage, error) {
+			got = query
+			return &domain.UserPage{
+				Users: []domain.User{{ID: "user-2", CreatedAt: created}},
+				Next:  &domain.UserCursor{CreatedAt: created, ID: "user-2"},
+			}, nil
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, cursor.NewRandomSigner())
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users?cursor=&per=10&count=estimated", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.ListUsers(e.NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Nil(t, got.Cursor)
+	require.Equal(t, domain.UserCountEstimated, got.Count)
+	require.Equal(t, 10, got.Limit)
+
+	var resp struct {
+		Data struct {
+			NextCursor string  `json:"next_cursor"`
+			PrevCursor *string `json:"prev_cursor"`
+			TotalCount *int64  `json:"totalCount"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.Data.NextCursor)
+	require.Nil(t, resp.Data.PrevCursor)
+	require.Nil(t, resp.Data.TotalCount)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/users?cursor="+resp.Data.NextCursor, nil)
+	rec = httptest.NewRecorder()
+	require.NoError(t, handler.ListUsers(e.NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, got.Cursor)
+	require.Equal(t, "user-2", got.Cursor.ID)
+	require.True(t, created.Equal(got.Cursor.CreatedAt))
+
+	for _, query := range []string{
+		"cursor=" + resp.Data.NextCursor + "x",
+		"cursor=" + resp.Data.NextCursor + "&order=asc",
+		"cursor=&sort=email",
+		"cursor=&count=all",
+	} {
+		req = httptest.NewRequest(http.MethodGet, "/admin/users?"+query, nil)
+		rec = httptest.NewRecorder()
+		serve(e.NewContext(req, rec), handler.ListUsers)
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestUserManageHandler_SubmitBulk(t *testing.T) {
+	t.Parallel()
+
+	bulk := &mockBulkService{
+		submitFn: func(ctx context.Context, req service.BulkRequest) (*domain.BulkJob, error) {
+			require.Equal(t, domain.BulkActionChangeStatus, req.Action)
+			require.Equal(t, "admin-1", req.ActorID)
+			require.NotNil(t, req.Filter)
+			require.Equal(t, []domain.UserStatus{domain.UserStatusActive}, req.Filter.Statuses)
+			return &domain.BulkJob{ID: "bulk-1", Action: req.Action, Status: domain.BulkJobCompleted, DryRun: req.DryRun, Total: 2, Succeeded: 2}, nil
+		},
+	}
+	handler := adminv1.NewHandler(&mockManageService{}, nil, bulk, nil, nil, nil)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/bulk", strings.NewReader(`{"action":"change_status","filter":{"status":["active"]},"status":"BLOCKED","reason":"spam","dry_run":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "admin-1")
+	require.NoError(t, handler.SubmitBulk(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"succeeded":2`)
+
+	for _, body := range []string{
+		`{"action":"explode","user_ids":["user-1"]}`,
+		`{"action":"delete","filter":{}}`,
+		`{"action":"delete","filter":{"status":["GONE"]}}`,
+	} {
+		req = httptest.NewRequest(http.MethodPost, "/admin/users/bulk", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec = httptest.NewRecorder()
+		serve(e.NewContext(req, rec), handler.SubmitBulk)
+		require.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestUserManageHandler_ImportUsers(t *testing.T) {
+	t.Parallel()
+
+	imports := &stubImportService{}
+	handler := adminv1.NewHandler(&mockManageService{}, nil, nil, imports, nil, nil)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/import?dry_run=true", strings.NewReader("email,role\na@example.com,student\nb@example.com,student\n"))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "admin-1")
+	require.NoError(t, handler.ImportUsers(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, imports.opts.DryRun)
+	require.Equal(t, "admin-1", imports.opts.ActorID)
+	require.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment")
+	require.Equal(t, "line,email,result,user_id,error\n2,a@example.com,created,,\n3,b@example.com,created,,\n", rec.Body.String())
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	require.NoError(t, writer.WriteField("note", "ignored"))
+	part, err := writer.CreateFormFile("file", "teachers.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("email,role\nc@example.com,teacher\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	req = httptest.NewRequest(http.MethodPost, "/admin/users/import", &form)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	rec = httptest.NewRecorder()
+	require.NoError(t, handler.ImportUsers(e.NewContext(req, rec)))
+	require.Equal(t, "line,email,result,user_id,error\n2,c@example.com,created,,\n", rec.Body.String())
+
+	// A failure after the report started ends it without internal details.
+	imports.err = errors.New("read tcp 10.0.0.7:5432: connection reset by peer")
+	req = httptest.NewRequest(http.MethodPost, "/admin/users/import", strings.NewReader("email,role\nd@example.com,student\n"))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	rec = httptest.NewRecorder()
+	require.ErrorIs(t, handler.ImportUsers(e.NewContext(req, rec)), imports.err)
+	require.Equal(t, "line,email,result,user_id,error\n2,d@example.com,created,,\n,,aborted,,internal error\n", rec.Body.String())
+	imports.err = nil
+
+	for _, target := range []string{"/admin/users/import", "/admin/users/import?format=xlsx", "/admin/users/import?dry_run=maybe"} {
+		req = httptest.NewRequest(http.MethodPost, target, strings.NewReader("email\n"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMETextPlain)
+		rec = httptest.NewRecorder()
+		serve(e.NewContext(req, rec), handler.ImportUsers)
+		require.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestUserManageHandler_ExportUsers(t *testing.T) {
+	t.Parallel()
+
+	display := "Jane"
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mockSvc := &mockManageService{
+		streamUsersFn: func(ctx context.Context, query domain.UserQuery, fn func(*domain.User) error) error {
+			require.Equal(t, domain.UserSortEmail, query.SortBy)
+			for _, u := range []*domain.User{
+				{ID: "user-1", Email: "jane@example.com", Status: domain.UserStatusActive, IsActive: true, CreatedAt: created, Profile: &domain.UserProfile{DisplayName: &display}},
+				{ID: "user-2", Email: "bob@example.org", Status: domain.UserStatusBlocked, CreatedAt: created},
+			} {
+				if err := fn(u); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/export?sort=email", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.ExportUsers(e.NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `attachment; filename="users.csv"`, rec.Header().Get(echo.HeaderContentDisposition))
+	require.Equal(t, "id,email,display_name,status,is_active,created_at\n"+
+		"user-1,j****@***e.com,Jane,ACTIVE,true,2024-05-01T12:00:00Z\n"+
+		"user-2,b****@***e.org,,BLOCKED,false,2024-05-01T12:00:00Z\n", rec.Body.String())
+
+	for _, target := range []string{"/admin/users/export?format=xml", "/admin/users/export?columns=id,password", "/admin/users/export?status=gone"} {
+		req = httptest.NewRequest(http.MethodGet, target, nil)
+		rec = httptest.NewRecorder()
+		serve(e.NewContext(req, rec), handler.ExportUsers)
+		require.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
 func TestUserManageHandler_UpdateUser_NotFound(t *testing.T) {
 	t.Parallel()
 
@@ -118,7 +376,7 @@ func TestUserManageHandler_UpdateUser_NotFound(t *testing.T) {
 			return nil, gorm.ErrRecordNotFound
 		},
 	}
-	handler := adminv1.NewHandler(mockSvc, nil)
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/admin/users/123", strings.NewReader(`{"email":"x@example.com"}`))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -127,26 +385,67 @@ func TestUserManageHandler_UpdateUser_NotFound(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues("123")
 
-	require.NoError(t, handler.UpdateUser(c))
+	serve(c, handler.UpdateUser)
 	require.Equal(t, http.StatusNotFound, rec.Code)
 }
 
+func TestUserManageHandler_UpdateUser_IfMatch(t *testing.T) {
+	t.Parallel()
+
+	mockSvc := &mockManageService{
+		updateUserFn: func(ctx context.Context, userID string, req service.UpdateUserRequest) (*domain.User, error) {
+			if req.IfMatch != `"2.1"` {
+				return nil, domain.ErrVersionConflict
+			}
+			return &domain.User{ID: userID, Version: 3, Profile: &domain.UserProfile{Version: 1}}, nil
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+	for _, tc := range []struct {
+		ifMatch  string
+		wantCode int
+		wantETag string
+	}{
+		{ifMatch: `"1.1"`, wantCode: http.StatusPreconditionFailed},
+		{ifMatch: `"2.1"`, wantCode: http.StatusOK, wantETag: `"3.1"`},
+	} {
+		req := httptest.NewRequest(http.MethodPatch, "/admin/users/123", strings.NewReader(`{"email":"x@example.com"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(middleware.HeaderIfMatch, tc.ifMatch)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("123")
+
+		serve(c, handler.UpdateUser)
+		require.Equal(t, tc.wantCode, rec.Code)
+		require.Equal(t, tc.wantETag, rec.Header().Get(middleware.HeaderETag))
+		if tc.wantCode == http.StatusPreconditionFailed {
+			require.Contains(t, rec.Body.String(), "precondition_failed")
+		}
+	}
+}
+
 func TestUserManageHandler_ChangeStatus(t *testing.T) {
 	t.Parallel()
 
 	mockSvc := &mockManageService{
-		changeStatusFn: func(ctx context.Context, userID string, status domain.UserStatus) (*domain.User, error) {
-			return &domain.User{ID: userID, Status: status, Profile: &domain.UserProfile{UserID: userID}}, nil
+		changeStatusFn: func(ctx context.Context, userID string, req service.ChangeStatusRequest) (*domain.User, error) {
+			require.Equal(t, "support ticket 17", req.Reason)
+			require.Equal(t, "admin-1", req.ActorID)
+			return &domain.User{ID: userID, Status: req.Status, Profile: &domain.UserProfile{UserID: userID}}, nil
 		},
 	}
-	handler := adminv1.NewHandler(mockSvc, nil)
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
 	e := echo.New()
-	req := httptest.NewRequest(http.MethodPatch, "/admin/users/42/status", strings.NewReader(`{"status":"active"}`))
+	req := httptest.NewRequest(http.MethodPatch, "/admin/users/42/status", strings.NewReader(`{"status":"active","reason":"support ticket 17"}`))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("id")
 	c.SetParamValues("42")
+	c.Set("user_id", "admin-1")
 
 	require.NoError(t, handler.ChangeStatus(c))
 	require.Equal(t, http.StatusOK, rec.Code)
@@ -158,15 +457,183 @@ func TestUserManageHandler_ChangeStatus(t *testing.T) {
 	require.Equal(t, domain.UserStatusActive, resp.Data.Status)
 }
 
+func TestUserManageHandler_ChangeStatus_RequiresReason(t *testing.T) {
+	t.Parallel()
+
+	called := false
+	mockSvc := &mockManageService{
+		changeStatusFn: func(ctx context.Context, userID string, req service.ChangeStatusRequest) (*domain.User, error) {
+			called = true
+			return nil, nil
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/admin/users/42/status", strings.NewReader(`{"status":"blocked"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("42")
+
+	serve(c, handler.ChangeStatus)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.False(t, called)
+}
+
+func TestUserManageHandler_ChangeStatus_IllegalTransition(t *testing.T) {
+	t.Parallel()
+
+	mockSvc := &mockManageService{
+		changeStatusFn: func(ctx context.Context, userID string, req service.ChangeStatusRequest) (*domain.User, error) {
+			return nil, domain.CanTransition(domain.UserStatusBlocked, domain.UserStatusNew, req.Reason)
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/admin/users/42/status", strings.NewReader(`{"status":"new_user","reason":"oops"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("42")
+
+	serve(c, handler.ChangeStatus)
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestUserManageHandler_StatusHistory(t *testing.T) {
+	t.Parallel()
+
+	mockSvc := &mockManageService{
+		statusHistoryFn: func(ctx context.Context, userID string) ([]domain.UserStatusHistory, error) {
+			return []domain.UserStatusHistory{{UserID: userID, FromStatus: domain.UserStatusActive, ToStatus: domain.UserStatusBlocked, Reason: "spam"}}, nil
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/42/status-history", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("42")
+
+	require.NoError(t, handler.StatusHistory(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data struct {
+			History []domain.UserStatusHistory `json:"history"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.History, 1)
+	require.Equal(t, "spam", resp.Data.History[0].Reason)
+}
+
+func TestUserManageHandler_Suspend(t *testing.T) {
+	t.Parallel()
+
+	var got service.SuspendRequest
+	mockSvc := &mockManageService{
+		suspendFn: func(ctx context.Context, userID string, req service.SuspendRequest) (*domain.User, error) {
+			got = req
+			return &domain.User{ID: userID, Status: domain.UserStatusSuspended, Suspension: domain.Suspension{SuspendedUntil: &req.Until, SuspensionReason: &req.Reason}}, nil
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/42/suspend", strings.NewReader(`{"duration":"72h","reason_code":"spam","note":"bulk messages"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("42")
+	c.Set("user_id", "admin-1")
+
+	require.NoError(t, handler.Suspend(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, domain.SuspensionReasonSpam, got.Reason)
+	require.Equal(t, "admin-1", got.ActorID)
+	require.WithinDuration(t, time.Now().Add(72*time.Hour), got.Until, time.Minute)
+
+	var resp struct {
+		Data struct {
+			Status         domain.UserStatus `json:"status"`
+			SuspendedUntil *time.Time        `json:"suspended_until"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, domain.UserStatusSuspended, resp.Data.Status)
+	require.NotNil(t, resp.Data.SuspendedUntil)
+}
+
+func TestUserManageHandler_LiftSuspension_NotSuspended(t *testing.T) {
+	t.Parallel()
+
+	mockSvc := &mockManageService{
+		liftSuspensionFn: func(ctx context.Context, userID string, req service.LiftSuspensionRequest) (*domain.User, error) {
+			return nil, domain.ErrNotSuspended
+		},
+	}
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/admin/users/42/suspend", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("42")
+
+	serve(c, handler.LiftSuspension)
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestUserManageHandler_DeleteAndRestore(t *testing.T) {
+	t.Parallel()
+
+	purgeAfter := time.Now().Add(time.Hour)
+	deletion := &mockDeletionService{
+		deleteFn: func(ctx context.Context, userID, actorID string) (*domain.User, error) {
+			require.Equal(t, "admin-1", actorID)
+			now := time.Now()
+			return &domain.User{ID: userID, Status: domain.UserStatusDeleted, Deletion: domain.Deletion{DeletedAt: &now, PurgeAfter: &purgeAfter}}, nil
+		},
+		restoreFn: func(ctx context.Context, userID, actorID string) (*domain.User, error) {
+			return nil, domain.ErrRestoreWindowClosed
+		},
+	}
+	handler := adminv1.NewHandler(&mockManageService{}, deletion, nil, nil, nil, nil)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/users/42", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("42")
+	c.Set("user_id", "admin-1")
+	require.NoError(t, handler.DeleteUser(c))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Contains(t, rec.Body.String(), `"status":"DELETED"`)
+	require.Contains(t, rec.Body.String(), `"purge_after"`)
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/users/42/restore", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("42")
+	serve(c, handler.RestoreUser)
+	require.Equal(t, http.StatusGone, rec.Code)
+}
+
 func TestUserManageHandler_ChangeRole_Error(t *testing.T) {
 	t.Parallel()
 
 	mockSvc := &mockManageService{
 		changeRoleFn: func(ctx context.Context, userID, role string) error {
-			return errors.New("bad role")
+			return domain.Invalid("role", "role is required")
 		},
 	}
-	handler := adminv1.NewHandler(mockSvc, nil)
+	handler := adminv1.NewHandler(mockSvc, nil, nil, nil, nil, nil)
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/admin/users/99/role", strings.NewReader(`{"role":""}`))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -175,17 +642,22 @@ func TestUserManageHandler_ChangeRole_Error(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues("99")
 
-	require.NoError(t, handler.ChangeRole(c))
+	serve(c, handler.ChangeRole)
 	require.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
 type mockManageService struct {
-	getUserFn      func(ctx context.Context, userID string) (*domain.User, error)
-	createUserFn   func(ctx context.Context, req service.CreateUserRequest) (*domain.User, error)
-	updateUserFn   func(ctx context.Context, userID string, req service.UpdateUserRequest) (*domain.User, error)
-	changeStatusFn func(ctx context.Context, userID string, status domain.UserStatus) (*domain.User, error)
-	changeRoleFn   func(ctx context.Context, userID, role string) error
-	listUsersFn    func(ctx context.Context, offset, limit int) ([]domain.User, int64, error)
+	getUserFn        func(ctx context.Context, userID string) (*domain.User, error)
+	createUserFn     func(ctx context.Context, req service.CreateUserRequest) (*domain.User, error)
+	updateUserFn     func(ctx context.Context, userID string, req service.UpdateUserRequest) (*domain.User, error)
+	changeStatusFn   func(ctx context.Context, userID string, req service.ChangeStatusRequest) (*domain.User, error)
+	statusHistoryFn  func(ctx context.Context, userID string) ([]domain.UserStatusHistory, error)
+	suspendFn        func(ctx context.Context, userID string, req service.SuspendRequest) (*domain.User, error)
+	liftSuspensionFn func(ctx context.Context, userID string, req service.LiftSuspensionRequest) (*domain.User, error)
+	changeRoleFn     func(ctx context.Context, userID, role string) error
+	listUsersFn      func(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error)
+	listByCursorFn   func(ctx context.Context, query domain.UserQuery) (*domain.UserPage, error)
+	streamUsersFn    func(ctx context.Context, query domain.UserQuery, fn func(*domain.User) error) error
 }
 
 func (m *mockManageService) GetUser(ctx context.Context, userID string) (*domain.User, error) {
@@ -209,13 +681,42 @@ func (m *mockManageService) UpdateUser(ctx context.Context, userID string, req s
 	return nil, nil
 }
 
-func (m *mockManageService) ChangeStatus(ctx context.Context, userID string, status domain.UserStatus) (*domain.User, error) {
+func (m *mockManageService) UpdateUserForService(ctx context.Context, userID string, req service.UpdateUserRequest) (*domain.User, error) {
+	return m.UpdateUser(ctx, userID, req)
+}
+
+func (m *mockManageService) ChangeStatus(ctx context.Context, userID string, req service.ChangeStatusRequest) (*domain.User, error) {
 	if m.changeStatusFn != nil {
-		return m.changeStatusFn(ctx, userID, status)
+		return m.changeStatusFn(ctx, userID, req)
+	}
+	return nil, nil
+}
+
+func (m *mockManageService) StatusHistory(ctx context.Context, userID string) ([]domain.UserStatusHistory, error) {
+	if m.statusHistoryFn != nil {
+		return m.statusHistoryFn(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockManageService) Suspend(ctx context.Context, userID string, req service.SuspendRequest) (*domain.User, error) {
+	if m.suspendFn != nil {
+		return m.suspendFn(ctx, userID, req)
+	}
+	return nil, nil
+}
+
+func (m *mockManageService) LiftSuspension(ctx context.Context, userID string, req service.LiftSuspensionRequest) (*domain.User, error) {
+	if m.liftSuspensionFn != nil {
+		return m.liftSuspensionFn(ctx, userID, req)
 	}
 	return nil, nil
 }
 
+func (m *mockManageService) ReactivateExpiredSuspensions(ctx context.Context, now time.Time) (int, error) {
+	return 0, nil
+}
+
 func (m *mockManageService) ChangeRole(ctx context.Context, userID, role string) error {
 	if m.changeRoleFn != nil {
 		return m.changeRoleFn(ctx, userID, role)
@@ -223,9 +724,77 @@ func (m *mockManageService) ChangeRole(ctx context.Context, userID, role string)
 	return nil
 }
 
-func (m *mockManageService) ListUsers(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
+func (m *mockManageService) ListUsersByCursor(ctx context.Context, query domain.UserQuery) (*domain.UserPage, error) {
+	if m.listByCursorFn != nil {
+		return m.listByCursorFn(ctx, query)
+	}
+	return &domain.UserPage{}, nil
+}
+
+func (m *mockManageService) StreamUsers(ctx context.Context, query domain.UserQuery, fn func(*domain.User) error) error {
+	if m.streamUsersFn != nil {
+		return m.streamUsersFn(ctx, query, fn)
+	}
+	return nil
+}
+
+func (m *mockManageService) ListUsers(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
 	if m.listUsersFn != nil {
-		return m.listUsersFn(ctx, offset, limit)
+		return m.listUsersFn(ctx, query)
 	}
 	return nil, 0, nil
 }
+
+type mockDeletionService struct {
+	deleteFn  func(ctx context.Context, userID, actorID string) (*domain.User, error)
+	restoreFn func(ctx context.Context, userID, actorID string) (*domain.User, error)
+}
+
+func (m *mockDeletionService) Delete(ctx context.Context, userID, actorID string) (*domain.User, error) {
+	return m.deleteFn(ctx, userID, actorID)
+}
+
+func (m *mockDeletionService) Restore(ctx context.Context, userID, actorID string) (*domain.User, error) {
+	return m.restoreFn(ctx, userID, actorID)
+}
+
+func (m *mockDeletionService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	return 0, nil
+}
+
+type mockBulkService struct {
+	submitFn func(ctx context.Context, req service.BulkRequest) (*domain.BulkJob, error)
+}
+
+func (m *mockBulkService) Submit(ctx context.Context, req service.BulkRequest) (*domain.BulkJob, error) {
+	return m.submitFn(ctx, req)
+}
+
+func (m *mockBulkService) Get(ctx context.Context, jobID string) (*domain.BulkJob, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *mockBulkService) ProcessPending(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+type stubImportService struct {
+	opts service.ImportOptions
+	err  error
+}
+
+func (s *stubImportService) Import(ctx context.Context, rows service.ImportReader, opts service.ImportOptions, report func(domain.ImportResult) error) error {
+	s.opts = opts
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			return s.err
+		}
+		if err != nil {
+			return err
+		}
+		if err := report(domain.ImportResult{Line: row.Line, Email: row.Email, Outcome: domain.ImportCreated}); err != nil {
+			return err
+		}
+	}
+}
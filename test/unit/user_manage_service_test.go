@@ -2,15 +2,20 @@ package unit
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"gorm.io/gorm"
 
 	"github.com/example/user-service/internal/domain"
+	"github.com/example/user-service/internal/events"
 	"github.com/example/user-service/internal/usecase"
 )
 
@@ -21,7 +26,7 @@ func TestUserManageService_CreateUser(t *testing.T) {
 	profiles := newManageProfileRepo()
 	rbac := &recordingRBAC{}
 	display := "Admin User"
-	svc := service.NewUserManageService(users, profiles, rbac)
+	svc := service.NewUserManageService(users, profiles, nil, rbac, nil, nil, nil, nil)
 
 	user, err := svc.CreateUser(context.Background(), service.CreateUserRequest{
 		Email:       "Admin@example.com",
@@ -46,7 +51,7 @@ func TestUserManageService_UpdateUser(t *testing.T) {
 	users := newManageUserRepo()
 	profiles := newManageProfileRepo()
 	rbac := &recordingRBAC{}
-	svc := service.NewUserManageService(users, profiles, rbac)
+	svc := service.NewUserManageService(users, profiles, nil, rbac, nil, nil, nil, nil)
 
 	original := &domain.User{ID: "user-1", Email: "old@example.com", Status: domain.UserStatusActive, IsActive: true}
 	require.NoError(t, users.Create(context.Background(), original))
@@ -64,30 +69,92 @@ func TestUserManageService_UpdateUser(t *testing.T) {
 	require.Equal(t, newDisplay, deref(user.Profile.DisplayName))
 }
 
+func TestUserManageService_UpdateUserForService(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	profiles := newManageProfileRepo()
+	rbac := &recordingRBAC{roles: map[string]string{"admin-1": "admin", "user-1": "student"}}
+	svc := service.NewUserManageService(users, profiles, nil, rbac, nil, nil, nil, nil)
+	for _, id := range []string{"admin-1", "user-1"} {
+		require.NoError(t, users.Create(context.Background(), &domain.User{ID: id, Email: id + "@example.com", Status: domain.UserStatusActive, IsActive: true}))
+		require.NoError(t, profiles.Create(context.Background(), &domain.UserProfile{UserID: id}))
+	}
+
+	display := "Renamed"
+	_, err := svc.UpdateUserForService(context.Background(), "admin-1", service.UpdateUserRequest{DisplayName: &display})
+	var domainErr *domain.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, "user_not_allowed", domainErr.Code)
+
+	email := "takeover@example.com"
+	password := "Password1"
+	for _, req := range []service.UpdateUserRequest{{Email: &email}, {Password: &password}} {
+		_, err = svc.UpdateUserForService(context.Background(), "user-1", req)
+		require.ErrorAs(t, err, &domainErr)
+		require.Equal(t, "field_not_allowed", domainErr.Code)
+	}
+
+	user, err := svc.UpdateUserForService(context.Background(), "user-1", service.UpdateUserRequest{DisplayName: &display})
+	require.NoError(t, err)
+	require.Equal(t, "user-1@example.com", user.Email)
+	require.Equal(t, display, deref(user.Profile.DisplayName))
+}
+
 func TestUserManageService_ChangeStatus(t *testing.T) {
 	t.Parallel()
 
 	users := newManageUserRepo()
 	profiles := newManageProfileRepo()
 	rbac := &recordingRBAC{}
-	svc := service.NewUserManageService(users, profiles, rbac)
+	svc := service.NewUserManageService(users, profiles, nil, rbac, nil, nil, nil, nil)
 
 	u := &domain.User{ID: "user-2", Email: "status@example.com", Status: domain.UserStatusActive, IsActive: true}
 	require.NoError(t, users.Create(context.Background(), u))
 
-	updated, err := svc.ChangeStatus(context.Background(), u.ID, domain.UserStatusInactive)
+	updated, err := svc.ChangeStatus(context.Background(), u.ID, service.ChangeStatusRequest{Status: domain.UserStatusInactive, Reason: "on leave"})
 	require.NoError(t, err)
 	require.Equal(t, domain.UserStatusInactive, updated.Status)
 	require.False(t, updated.IsActive)
 }
 
+func TestUserManageService_ChangeStatus_RecordsHistory(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	history := &statusHistoryRepo{}
+	svc := service.NewUserManageService(users, newManageProfileRepo(), history, &recordingRBAC{}, nil, nil, nil, nil)
+
+	u := &domain.User{ID: "user-4", Email: "blocked@example.com", Status: domain.UserStatusBlocked}
+	require.NoError(t, users.Create(context.Background(), u))
+
+	_, err := svc.ChangeStatus(context.Background(), u.ID, service.ChangeStatusRequest{Status: domain.UserStatusActive})
+	require.ErrorIs(t, err, domain.ErrStatusReasonRequired)
+
+	_, err = svc.ChangeStatus(context.Background(), u.ID, service.ChangeStatusRequest{Status: domain.UserStatusNew, Reason: "reset"})
+	require.ErrorIs(t, err, domain.ErrInvalidStatusTransition)
+	require.Empty(t, history.entries)
+
+	updated, err := svc.ChangeStatus(context.Background(), u.ID, service.ChangeStatusRequest{Status: domain.UserStatusActive, Reason: "appeal accepted", ActorID: "admin-1"})
+	require.NoError(t, err)
+	require.Equal(t, domain.UserStatusActive, updated.Status)
+
+	entries, err := svc.StatusHistory(context.Background(), u.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, domain.UserStatusBlocked, entries[0].FromStatus)
+	require.Equal(t, domain.UserStatusActive, entries[0].ToStatus)
+	require.Equal(t, "appeal accepted", entries[0].Reason)
+	require.Equal(t, "admin-1", deref(entries[0].ChangedBy))
+}
+
 func TestUserManageService_ChangeRole(t *testing.T) {
 	t.Parallel()
 
 	users := newManageUserRepo()
 	profiles := newManageProfileRepo()
 	rbac := &recordingRBAC{}
-	svc := service.NewUserManageService(users, profiles, rbac)
+	svc := service.NewUserManageService(users, profiles, nil, rbac, nil, nil, nil, nil)
 
 	u := &domain.User{ID: "user-3", Email: "role@example.com", Status: domain.UserStatusActive, IsActive: true}
 	require.NoError(t, users.Create(context.Background(), u))
@@ -97,9 +164,217 @@ func TestUserManageService_ChangeRole(t *testing.T) {
 	require.Equal(t, u.ID, rbac.assignedUserID)
 }
 
+func TestUserManageService_RecordsOutboxEvents(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	profiles := newManageProfileRepo()
+	outbox := &recordingOutbox{}
+	tx := &countingTx{}
+	svc := service.NewUserManageService(users, profiles, nil, &recordingRBAC{}, tx, outbox, nil, nil)
+
+	user, err := svc.CreateUser(context.Background(), service.CreateUserRequest{
+		Email:    "events@example.com",
+		Password: "Password1",
+		Role:     "student",
+	})
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(context.Background(), user.ID, service.ChangeStatusRequest{Status: domain.UserStatusInactive, Reason: "graduated"})
+	require.NoError(t, err)
+
+	require.Equal(t, 2, tx.calls)
+	require.Len(t, outbox.events, 2)
+	require.Equal(t, events.UserCreated, outbox.events[0].Event)
+	require.Equal(t, user.ID, outbox.events[0].UserID)
+	require.Equal(t, events.UserStatusChanged, outbox.events[1].Event)
+}
+
+func TestUserManageService_CreateUser_RBACFailureAbortsTx(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	profiles := newManageProfileRepo()
+	tx := &countingTx{}
+	svc := service.NewUserManageService(users, profiles, nil, &recordingRBAC{err: errors.New("rbac down")}, tx, &recordingOutbox{}, nil, nil)
+
+	_, err := svc.CreateUser(context.Background(), service.CreateUserRequest{
+		Email:    "rbac@example.com",
+		Password: "Password1",
+		Role:     "student",
+	})
+	require.EqualError(t, err, "rbac down")
+	require.Equal(t, 1, tx.calls)
+	require.ErrorContains(t, tx.lastErr, "rbac down")
+}
+
+func TestUserManageService_RecordsAuditLog(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	profiles := newManageProfileRepo()
+	audit := &auditLogRepo{}
+	svc := service.NewUserManageService(users, profiles, nil, &recordingRBAC{}, nil, nil, audit, nil)
+	ctx := service.WithAuditActor(context.Background(), domain.AuditActor{ID: "admin-1", RequestID: "req-1", IP: "10.0.0.1", UserAgent: "curl/8"})
+
+	user, err := svc.CreateUser(ctx, service.CreateUserRequest{
+		Email:    "audited@example.com",
+		Password: "Password1",
+		Role:     "student",
+	})
+	require.NoError(t, err)
+	display := "Audited"
+	_, err = svc.UpdateUser(ctx, user.ID, service.UpdateUserRequest{DisplayName: &display})
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, user.ID, service.ChangeStatusRequest{Status: domain.UserStatusBlocked, Reason: "spam", ActorID: "admin-1"})
+	require.NoError(t, err)
+
+	require.Len(t, audit.entries, 3)
+	require.Equal(t, domain.AuditUserCreate, audit.entries[0].Action)
+	require.Equal(t, domain.AuditUserUpdate, audit.entries[1].Action)
+	require.Equal(t, domain.AuditUserStatusChange, audit.entries[2].Action)
+	for _, entry := range audit.entries {
+		require.Equal(t, "admin-1", deref(entry.ActorID))
+		require.Equal(t, user.ID, deref(entry.TargetUserID))
+		require.Equal(t, "req-1", deref(entry.RequestID))
+		require.Equal(t, "10.0.0.1", deref(entry.IP))
+		require.Equal(t, "curl/8", deref(entry.UserAgent))
+	}
+	require.JSONEq(t, `{"display_name":{"before":null,"after":"Audited"}}`, string(audit.entries[1].Changes))
+	require.Contains(t, string(audit.entries[2].Changes), `"status":{"before":"ACTIVE","after":"BLOCKED"}`)
+
+	verifier := service.NewAuditService(audit)
+	checked, err := verifier.Verify(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, checked)
+
+	audit.entries[1].Changes = []byte(`{"display_name":{"before":null,"after":"Forged"}}`)
+	checked, err = verifier.Verify(context.Background())
+	require.ErrorIs(t, err, domain.ErrAuditChainBroken)
+	require.Equal(t, 1, checked)
+}
+
+func TestUserManageService_SuspendAndSweep(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	history := &statusHistoryRepo{}
+	svc := service.NewUserManageService(users, newManageProfileRepo(), history, &recordingRBAC{}, nil, nil, nil, nil)
+
+	u := &domain.User{ID: "user-5", Email: "suspend@example.com", Status: domain.UserStatusActive, IsActive: true}
+	require.NoError(t, users.Create(context.Background(), u))
+
+	until := time.Now().Add(time.Hour)
+	suspended, err := svc.Suspend(context.Background(), u.ID, service.SuspendRequest{Until: until, Reason: domain.SuspensionReasonAbuse, ActorID: "admin-1"})
+	require.NoError(t, err)
+	require.Equal(t, domain.UserStatusSuspended, suspended.Status)
+	require.False(t, suspended.IsActive)
+	require.Equal(t, "admin-1", deref(suspended.SuspendedBy))
+
+	count, err := svc.ReactivateExpiredSuspensions(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	count, err = svc.ReactivateExpiredSuspensions(context.Background(), until.Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	reactivated, err := users.FindByID(context.Background(), u.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.UserStatusActive, reactivated.Status)
+	require.Nil(t, reactivated.SuspendedUntil)
+	require.Len(t, history.entries, 2)
+	require.Equal(t, "suspension expired", history.entries[1].Reason)
+	require.Nil(t, history.entries[1].ChangedBy)
+}
+
+func TestUserManageService_SweepSkipsFailingUsers(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	svc := service.NewUserManageService(users, newManageProfileRepo(), &statusHistoryRepo{}, &recordingRBAC{}, nil, nil, nil, nil)
+
+	until := time.Now().Add(-time.Minute)
+	for _, id := range []string{"user-1", "user-2", "user-3"} {
+		require.NoError(t, users.Create(context.Background(), &domain.User{
+			ID: id, Email: id + "@example.com", Status: domain.UserStatusSuspended, Suspension: domain.Suspension{SuspendedUntil: &until},
+		}))
+	}
+	users.updateErr = map[string]error{"user-2": errors.New("write failed")}
+
+	count, err := svc.ReactivateExpiredSuspensions(context.Background(), time.Now())
+	require.Error(t, err)
+	require.Equal(t, 2, count)
+
+	for id, want := range map[string]domain.UserStatus{"user-1": domain.UserStatusActive, "user-2": domain.UserStatusSuspended, "user-3": domain.UserStatusActive} {
+		user, err := users.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		require.Equal(t, want, user.Status, id)
+	}
+}
+
+func TestUserManageService_Suspend_RejectsPastDate(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	svc := service.NewUserManageService(users, newManageProfileRepo(), nil, &recordingRBAC{}, nil, nil, nil, nil)
+	u := &domain.User{ID: "user-6", Status: domain.UserStatusActive, IsActive: true}
+	require.NoError(t, users.Create(context.Background(), u))
+
+	_, err := svc.Suspend(context.Background(), u.ID, service.SuspendRequest{Until: time.Now().Add(-time.Hour), Reason: domain.SuspensionReasonSpam})
+	require.Error(t, err)
+	require.Equal(t, domain.UserStatusActive, u.Status)
+}
+
+func TestUserManageService_ListUsersByCursor(t *testing.T) {
+	t.Parallel()
+
+	users := newManageUserRepo()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		u := &domain.User{ID: fmt.Sprintf("user-%d", i), CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		require.NoError(t, users.Create(context.Background(), u))
+	}
+	svc := service.NewUserManageService(users, newManageProfileRepo(), nil, &recordingRBAC{}, nil, nil, nil, nil)
+	ids := func(page *domain.UserPage) []string {
+		out := make([]string, 0, len(page.Users))
+		for _, u := range page.Users {
+			out = append(out, u.ID)
+		}
+		return out
+	}
+
+	first, err := svc.ListUsersByCursor(context.Background(), domain.UserQuery{Limit: 2, Count: domain.UserCountExact})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user-5", "user-4"}, ids(first))
+	require.Nil(t, first.Prev)
+	require.NotNil(t, first.Next)
+	require.Equal(t, int64(5), *first.TotalCount)
+
+	second, err := svc.ListUsersByCursor(context.Background(), domain.UserQuery{Limit: 2, Cursor: first.Next})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user-3", "user-2"}, ids(second))
+	require.NotNil(t, second.Prev)
+	require.Nil(t, second.TotalCount)
+
+	last, err := svc.ListUsersByCursor(context.Background(), domain.UserQuery{Limit: 2, Cursor: second.Next})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user-1"}, ids(last))
+	require.Nil(t, last.Next)
+
+	back, err := svc.ListUsersByCursor(context.Background(), domain.UserQuery{Limit: 2, Cursor: second.Prev})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user-5", "user-4"}, ids(back))
+	require.Nil(t, back.Prev)
+	require.NotNil(t, back.Next)
+
+	_, err = svc.ListUsersByCursor(context.Background(), domain.UserQuery{Limit: 2, SortBy: domain.UserSortEmail})
+	require.Error(t, err)
+}
+
 type manageUserRepo struct {
-	users  map[string]*domain.User
-	lastID int
+	users     map[string]*domain.User
+	lastID    int
+	updateErr map[string]error
 }
 
 func newManageUserRepo() *manageUserRepo {
@@ -116,13 +391,16 @@ func (r *manageUserRepo) Create(_ context.Context, user *domain.User) error {
 }
 
 func (r *manageUserRepo) Update(_ context.Context, user *domain.User) error {
+	if err := r.updateErr[user.ID]; err != nil {
+		return err
+	}
 	r.users[user.ID] = user
 	return nil
 }
 
 func (r *manageUserRepo) FindByEmail(_ context.Context, email string) (*domain.User, error) {
 	for _, u := range r.users {
-		if strings.EqualFold(u.Email, email) {
+		if !u.IsDeleted() && strings.EqualFold(u.Email, email) {
 			return u, nil
 		}
 	}
@@ -130,6 +408,13 @@ func (r *manageUserRepo) FindByEmail(_ context.Context, email string) (*domain.U
 }
 
 func (r *manageUserRepo) FindByID(_ context.Context, id string) (*domain.User, error) {
+	if user, ok := r.users[id]; ok && !user.IsDeleted() {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *manageUserRepo) FindByIDIncludingDeleted(_ context.Context, id string) (*domain.User, error) {
 	if user, ok := r.users[id]; ok {
 		return user, nil
 	}
@@ -141,10 +426,94 @@ func (r *manageUserRepo) Delete(_ context.Context, id string) error {
 	return nil
 }
 
-func (r *manageUserRepo) List(_ context.Context, offset, limit int) ([]domain.User, int64, error) {
+func (r *manageUserRepo) List(_ context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
 	return nil, 0, nil
 }
 
+func (r *manageUserRepo) ListByCursor(_ context.Context, query domain.UserQuery) ([]domain.User, error) {
+	desc := query.SortDesc
+	if query.Cursor != nil && query.Cursor.Backward {
+		desc = !desc
+	}
+	less := func(a, b domain.User) bool {
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return a.ID < b.ID
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	var out []domain.User
+	for _, u := range r.users {
+		if u.IsDeleted() {
+			continue
+		}
+		if c := query.Cursor; c != nil {
+			pivot := domain.User{ID: c.ID, CreatedAt: c.CreatedAt}
+			if (desc && !less(*u, pivot)) || (!desc && !less(pivot, *u)) {
+				continue
+			}
+		}
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if desc {
+			return less(out[j], out[i])
+		}
+		return less(out[i], out[j])
+	})
+	if len(out) > query.Limit {
+		out = out[:query.Limit]
+	}
+	return out, nil
+}
+
+func (r *manageUserRepo) Count(_ context.Context, query domain.UserQuery) (int64, error) {
+	var count int64
+	for _, u := range r.users {
+		if !u.IsDeleted() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *manageUserRepo) Stream(ctx context.Context, query domain.UserQuery, batchSize int, fn func([]domain.User) error) error {
+	users, err := r.ListByCursor(ctx, domain.UserQuery{Limit: len(r.users), SortDesc: query.SortDesc})
+	if err != nil {
+		return err
+	}
+	for len(users) > 0 {
+		n := min(batchSize, len(users))
+		if err := fn(users[:n]); err != nil {
+			return err
+		}
+		users = users[n:]
+	}
+	return nil
+}
+
+func (r *manageUserRepo) ListExpiredSuspensions(_ context.Context, now time.Time, limit int, exclude []string) ([]domain.User, error) {
+	var out []domain.User
+	for _, u := range r.users {
+		if u.Status == domain.UserStatusSuspended && u.SuspendedUntil != nil && !u.SuspendedUntil.After(now) && !slices.Contains(exclude, u.ID) {
+			out = append(out, *u)
+		}
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *manageUserRepo) ListPurgeable(_ context.Context, now time.Time, limit int) ([]domain.User, error) {
+	var out []domain.User
+	for _, u := range r.users {
+		if u.IsDeleted() && u.PurgedAt == nil && !u.PurgeAfter.After(now) {
+			out = append(out, *u)
+		}
+	}
+	return out, nil
+}
+
 type manageProfileRepo struct {
 	profiles map[string]*domain.UserProfile
 }
@@ -176,10 +545,13 @@ func (r *manageProfileRepo) FindByUserID(_ context.Context, userID string) (*dom
 type recordingRBAC struct {
 	assignedUserID string
 	assignedRole   string
+	err            error
+	onAssign       func(userID, role string)
+	roles          map[string]string
 }
 
 func (r *recordingRBAC) GetRoleByUserID(ctx context.Context, userID string) (string, error) {
-	return "", nil
+	return r.roles[userID], nil
 }
 
 func (r *recordingRBAC) GetPermissionsByUserID(ctx context.Context, userID string) ([]string, error) {
@@ -195,11 +567,80 @@ func (r *recordingRBAC) CheckRole(ctx context.Context, userID, role string) (boo
 }
 
 func (r *recordingRBAC) AssignRole(ctx context.Context, userID, role string) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.onAssign != nil {
+		r.onAssign(userID, role)
+	}
 	r.assignedUserID = userID
 	r.assignedRole = role
 	return nil
 }
 
+type statusHistoryRepo struct {
+	entries []domain.UserStatusHistory
+}
+
+func (r *statusHistoryRepo) Create(_ context.Context, entry *domain.UserStatusHistory) error {
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *statusHistoryRepo) ListByUser(_ context.Context, userID string) ([]domain.UserStatusHistory, error) {
+	var out []domain.UserStatusHistory
+	for _, e := range r.entries {
+		if e.UserID == userID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+type recordingOutbox struct {
+	events []events.UserEvent
+}
+
+func (o *recordingOutbox) Enqueue(_ context.Context, event events.UserEvent) error {
+	o.events = append(o.events, event)
+	return nil
+}
+
+func (o *recordingOutbox) LockPending(_ context.Context, limit, maxAttempts int) ([]domain.OutboxMessage, error) {
+	return nil, nil
+}
+
+func (o *recordingOutbox) MarkPublished(_ context.Context, id string) error {
+	return nil
+}
+
+func (o *recordingOutbox) MarkFailed(_ context.Context, id string, cause error, nextAttemptAt time.Time) error {
+	return nil
+}
+
+func (o *recordingOutbox) DeletePublishedBefore(_ context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (o *recordingOutbox) CountDead(_ context.Context, maxAttempts int) (int64, error) {
+	return 0, nil
+}
+
+func (o *recordingOutbox) DeleteDeadBefore(_ context.Context, maxAttempts int, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+type countingTx struct {
+	calls   int
+	lastErr error
+}
+
+func (t *countingTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.calls++
+	t.lastErr = fn(ctx)
+	return t.lastErr
+}
+
 func stringPtr(value string) *string {
 	return &value
 }
@@ -210,3 +651,38 @@ func deref(value *string) string {
 	}
 	return *value
 }
+
+type auditLogRepo struct {
+	entries []domain.AuditEntry
+}
+
+func (r *auditLogRepo) Append(_ context.Context, entry *domain.AuditEntry) error {
+	prevHash := ""
+	if len(r.entries) > 0 {
+		prevHash = r.entries[len(r.entries)-1].Hash
+	}
+	entry.ID = int64(len(r.entries) + 1)
+	entry.Seal(prevHash, time.Now())
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *auditLogRepo) List(_ context.Context, query domain.AuditQuery) ([]domain.AuditEntry, int64, error) {
+	var out []domain.AuditEntry
+	for _, entry := range r.entries {
+		if query.Action == "" || entry.Action == query.Action {
+			out = append(out, entry)
+		}
+	}
+	return out, int64(len(out)), nil
+}
+
+func (r *auditLogRepo) ListAfter(_ context.Context, afterID int64, limit int) ([]domain.AuditEntry, error) {
+	var out []domain.AuditEntry
+	for _, entry := range r.entries {
+		if entry.ID > afterID && len(out) < limit {
+			out = append(out, entry)
+		}
+	}
+	return out, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -35,9 +36,27 @@ func (r *userRepoStub) FindByID(ctx context.Context, id string) (*domain.User, e
 	return nil, errors.New("not found")
 }
 func (r *userRepoStub) Delete(ctx context.Context, id string) error { return nil }
-func (r *userRepoStub) List(ctx context.Context, offset, limit int) ([]domain.User, int64, error) {
+func (r *userRepoStub) List(ctx context.Context, query domain.UserQuery) ([]domain.User, int64, error) {
 	return nil, 0, nil
 }
+func (r *userRepoStub) FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error) {
+	return r.FindByID(ctx, id)
+}
+func (r *userRepoStub) ListPurgeable(ctx context.Context, now time.Time, limit int) ([]domain.User, error) {
+	return nil, nil
+}
+func (r *userRepoStub) Stream(ctx context.Context, query domain.UserQuery, batchSize int, fn func([]domain.User) error) error {
+	return nil
+}
+func (r *userRepoStub) ListByCursor(ctx context.Context, query domain.UserQuery) ([]domain.User, error) {
+	return nil, nil
+}
+func (r *userRepoStub) Count(ctx context.Context, query domain.UserQuery) (int64, error) {
+	return 0, nil
+}
+func (r *userRepoStub) ListExpiredSuspensions(ctx context.Context, now time.Time, limit int, exclude []string) ([]domain.User, error) {
+	return nil, nil
+}
 
 type profileRepoStub struct {
 	profiles map[string]*domain.UserProfile
@@ -72,23 +91,39 @@ func (identityRepoStub) ListByUser(ctx context.Context, userID string) ([]domain
 	return nil, nil
 }
 func (identityRepoStub) Delete(ctx context.Context, identity *domain.UserIdentity) error { return nil }
+func (identityRepoStub) DeleteByUser(ctx context.Context, userID string) error           { return nil }
 
 func TestUserService_UpdateProfile(t *testing.T) {
 	users := newUserRepoStub()
 	profiles := newProfileRepoStub()
-	svc := service.NewUserService(users, profiles, identityRepoStub{})
+	svc := service.NewUserService(users, profiles, identityRepoStub{}, nil, nil)
 	display := "New Name"
 
-	profile, err := svc.UpdateProfile(context.Background(), "user-1", &display)
+	user, err := svc.UpdateProfile(context.Background(), "user-1", &display, "")
+	require.NoError(t, err)
+	assert.Equal(t, &display, user.Profile.DisplayName)
+	assert.Nil(t, user.Profile.AvatarFileID)
+}
+
+func TestUserService_UpdateProfileIfMatch(t *testing.T) {
+	users := newUserRepoStub()
+	users.users["user-1"].Version = 4
+	profiles := newProfileRepoStub()
+	svc := service.NewUserService(users, profiles, identityRepoStub{}, nil, nil)
+	display := "New Name"
+
+	_, err := svc.UpdateProfile(context.Background(), "user-1", &display, `"3.0"`)
+	require.ErrorIs(t, err, domain.ErrVersionConflict)
+
+	user, err := svc.UpdateProfile(context.Background(), "user-1", &display, `"3.0", "4.0"`)
 	require.NoError(t, err)
-	assert.Equal(t, &display, profile.DisplayName)
-	assert.Nil(t, profile.AvatarFileID)
+	assert.Equal(t, &display, user.Profile.DisplayName)
 }
 
 func TestUserService_SetAvatarFileID(t *testing.T) {
 	users := newUserRepoStub()
 	profiles := newProfileRepoStub()
-	svc := service.NewUserService(users, profiles, identityRepoStub{})
+	svc := service.NewUserService(users, profiles, identityRepoStub{}, nil, nil)
 
 	profile, err := svc.SetAvatarFileID(context.Background(), "user-1", "file-123")
 	require.NoError(t, err)